@@ -0,0 +1,175 @@
+// Command calibrate fits, for each source of result confidence, a map from its raw scores to the probability that
+// a result is relevant, and writes a calibration file the matcher loads at startup (CALIBRATION_FILE).
+//
+//	go run ./cmd/calibrate -dataset eval/dataset.jsonl -feedback data/feedback.jsonl -out data/calibration.json
+//
+// Scoring strategies are fitted to the labelled dataset, scored as matcheval scores it, and to relevance feedback
+// recorded with their results. The cascade is fitted per stage, since each stage scores on its own scale. Extraction and the CSS fallback only score live searches, so they are fitted to
+// feedback alone. Sources without enough labelled results keep the calibrator already in the output file.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"price-comparison-tool/internal/config"
+	"price-comparison-tool/internal/evaluation"
+	"price-comparison-tool/internal/feedback"
+	"price-comparison-tool/internal/matcher"
+	"sort"
+	"strings"
+	"time"
+)
+
+// labelled is the raw scores of one confidence source with whether each result was relevant
+type labelled struct {
+	scores   []float64
+	relevant []bool
+}
+
+func (l *labelled) add(score float64, relevant bool) {
+	l.scores = append(l.scores, score)
+	l.relevant = append(l.relevant, relevant)
+}
+
+func main() {
+	datasetPath := flag.String("dataset", "eval/dataset.jsonl", "labelled JSONL dataset; empty skips it")
+	feedbackPath := flag.String("feedback", "data/feedback.jsonl", "relevance feedback recorded by the API; empty skips it")
+	strategyList := flag.String("strategies", "fuzzy,basic,bm25", "comma-separated strategies to score the dataset with")
+	relevantMin := flag.Int("relevant-min", evaluation.Exact, "minimum dataset relevance grade counted as relevant")
+	method := flag.String("method", "auto", "platt, isotonic, or auto: isotonic from -isotonic-min examples, Platt below")
+	isotonicMin := flag.Int("isotonic-min", 200, "examples a source needs for auto to choose isotonic regression")
+	minExamples := flag.Int("min-examples", 20, "skip sources with fewer labelled results")
+	outPath := flag.String("out", "data/calibration.json", "calibration file to write")
+	dryRun := flag.Bool("dry-run", false, "print the fit without writing it")
+	flag.Parse()
+
+	if *method != "auto" && *method != matcher.CalibrationPlatt && *method != matcher.CalibrationIsotonic {
+		log.Fatalf("Unknown method %q", *method)
+	}
+
+	sources := make(map[string]*labelled)
+	source := func(name string) *labelled {
+		if sources[name] == nil {
+			sources[name] = &labelled{}
+		}
+		return sources[name]
+	}
+
+	if *datasetPath != "" {
+		examples, err := evaluation.LoadDataset(*datasetPath)
+		if err != nil {
+			log.Fatalf("Failed to load dataset: %v", err)
+		}
+		service := matcher.NewService(config.Load())
+		for _, name := range strings.Split(*strategyList, ",") {
+			name = strings.TrimSpace(name)
+			score, err := evaluation.ScorerFor(service, name, examples)
+			if err != nil {
+				log.Fatal(err)
+			}
+			errors := 0
+			for _, example := range examples {
+				value, scoredBy, err := score(example.Query, example.Title)
+				if err != nil {
+					errors++
+					continue
+				}
+				source(scoredBy).add(value, example.Relevance >= *relevantMin)
+			}
+			if errors > 0 {
+				log.Printf("%s failed to score %d of %d examples", name, errors, len(examples))
+			}
+		}
+	}
+
+	if *feedbackPath != "" {
+		entries, err := feedback.Load(*feedbackPath)
+		if os.IsNotExist(err) {
+			log.Printf("No feedback at %s, fitting to the dataset alone", *feedbackPath)
+		} else if err != nil {
+			log.Fatalf("Failed to load feedback: %v", err)
+		}
+		skipped := 0
+		for _, entry := range entries {
+			if entry.Source == "" {
+				skipped++
+				continue
+			}
+			source(entry.Source).add(entry.RawConfidence, entry.Relevant())
+		}
+		if skipped > 0 {
+			log.Printf("Skipped %d feedback entries recorded without a confidence source", skipped)
+		}
+	}
+
+	calibration, err := matcher.LoadCalibration(*outPath)
+	if err != nil {
+		log.Fatalf("Failed to load existing calibration: %v", err)
+	}
+
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Printf("%-24s %8s %8s %9s %10s %9s\n", "source", "examples", "relevant", "method", "ece before", "ece after")
+	fitted := 0
+	for _, name := range names {
+		data := sources[name]
+		positives := 0
+		for _, relevant := range data.relevant {
+			if relevant {
+				positives++
+			}
+		}
+		if len(data.scores) < *minExamples || positives == 0 || positives == len(data.scores) {
+			fmt.Printf("%-24s %8d %8d %9s\n", name, len(data.scores), positives, "skipped")
+			continue
+		}
+
+		var calibrator matcher.Calibrator
+		switch {
+		case *method == matcher.CalibrationIsotonic, *method == "auto" && len(data.scores) >= *isotonicMin:
+			calibrator = matcher.FitIsotonic(data.scores, data.relevant)
+		default:
+			calibrator = matcher.FitPlatt(data.scores, data.relevant)
+		}
+		calibrated := make([]float64, len(data.scores))
+		for i, score := range data.scores {
+			calibrated[i] = calibrator.Apply(score)
+		}
+		fmt.Printf("%-24s %8d %8d %9s %10.3f %9.3f\n", name, len(data.scores), positives, calibrator.Method,
+			ece(data.scores, data.relevant), ece(calibrated, data.relevant))
+
+		calibration.Sources[name] = calibrator
+		fitted++
+	}
+
+	if *dryRun || fitted == 0 {
+		if fitted == 0 {
+			log.Printf("No source had enough labelled results to fit")
+		}
+		return
+	}
+	now := time.Now().UTC()
+	calibration.TrainedAt = &now
+	if err := matcher.SaveCalibration(*outPath, calibration); err != nil {
+		log.Fatalf("Failed to write calibration: %v", err)
+	}
+	log.Printf("Calibration written to %s; run cmd/matcheval -calibrated to check it", *outPath)
+}
+
+// ece is the expected calibration error of scores against labels, binned as matcheval bins them
+func ece(scores []float64, relevant []bool) float64 {
+	scored := make([]evaluation.Scored, len(scores))
+	for i, score := range scores {
+		scored[i].Score = score
+		if relevant[i] {
+			scored[i].Relevance = evaluation.Exact
+		}
+	}
+	return evaluation.Evaluate("", scored, 0.5, evaluation.Exact).ECE
+}
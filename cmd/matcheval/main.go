@@ -0,0 +1,146 @@
+// Command matcheval scores a labelled dataset with the product matchers and reports relevance metrics.
+// It exits non-zero when any metric regresses beyond the tolerance against a stored baseline.
+//
+//	go run ./cmd/matcheval -dataset eval/dataset.jsonl -baseline eval/baseline.json
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"price-comparison-tool/internal/config"
+	"price-comparison-tool/internal/evaluation"
+	"price-comparison-tool/internal/matcher"
+	"strings"
+)
+
+func main() {
+	datasetPath := flag.String("dataset", "eval/dataset.jsonl", "labelled JSONL dataset")
+	baselinePath := flag.String("baseline", "eval/baseline.json", "baseline metrics to compare against")
+	scorerList := flag.String("scorers", "fuzzy,basic,bm25", "comma-separated scorers: fuzzy, basic, bm25, llm, embedding, cascade; "+
+		"the model-backed ones are gated once a baseline is recorded for them with Ollama running")
+	threshold := flag.Float64("threshold", 0.3, "confidence threshold for precision and recall")
+	relevantMin := flag.Int("relevant-min", evaluation.Exact, "minimum relevance grade counted as relevant")
+	tolerance := flag.Float64("tolerance", 0.02, "allowed drop in any metric before failing")
+	calibrated := flag.Bool("calibrated", false, "map scores through the calibration file (CALIBRATION_FILE)")
+	allowUnavailable := flag.Bool("allow-unavailable", false, "pass when model-backed scorers cannot run (no Ollama)")
+	writeBaseline := flag.Bool("write-baseline", false, "record these results in the baseline")
+	reason := flag.String("reason", "", "why the baseline changes; required with -write-baseline")
+	jsonOutput := flag.Bool("json", false, "print full reports as JSON")
+	flag.Parse()
+
+	if *writeBaseline && strings.TrimSpace(*reason) == "" {
+		log.Fatal("-write-baseline needs -reason explaining why the accepted metrics change")
+	}
+
+	examples, err := evaluation.LoadDataset(*datasetPath)
+	if err != nil {
+		log.Fatalf("Failed to load dataset: %v", err)
+	}
+	dataset, err := evaluation.DatasetHash(*datasetPath)
+	if err != nil {
+		log.Fatalf("Failed to hash dataset: %v", err)
+	}
+
+	service := matcher.NewService(config.Load())
+
+	var reports []evaluation.Report
+	var unavailable []string
+	for _, name := range strings.Split(*scorerList, ",") {
+		name = strings.TrimSpace(name)
+		score, err := evaluation.ScorerFor(service, name, examples)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := evaluation.Available(service, name); err != nil {
+			unavailable = append(unavailable, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+
+		scored := make([]evaluation.Scored, 0, len(examples))
+		errors := 0
+		for _, example := range examples {
+			value, source, err := score(example.Query, example.Title)
+			if err != nil {
+				errors++
+				continue
+			}
+			if *calibrated {
+				value, _ = service.Calibrate(source, value)
+			}
+			scored = append(scored, evaluation.Scored{Example: example, Score: value})
+		}
+
+		report := evaluation.Evaluate(name, scored, *threshold, *relevantMin)
+		report.Errors = errors
+		reports = append(reports, report)
+	}
+
+	if *jsonOutput {
+		data, _ := json.MarshalIndent(reports, "", "  ")
+		fmt.Println(string(data))
+	} else {
+		printReports(reports)
+	}
+	if len(unavailable) > 0 {
+		fmt.Println("\nUnavailable scorers:")
+		for _, scorer := range unavailable {
+			fmt.Println("  - " + scorer)
+		}
+	}
+
+	if *writeBaseline {
+		if err := evaluation.SaveBaseline(*baselinePath, reports, dataset, *reason); err != nil {
+			log.Fatalf("Failed to write baseline: %v", err)
+		}
+		log.Printf("Baseline written to %s for dataset %s", *baselinePath, dataset)
+		return
+	}
+
+	baseline, err := evaluation.LoadBaseline(*baselinePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Printf("No baseline at %s, skipping regression check", *baselinePath)
+			return
+		}
+		log.Fatalf("Failed to load baseline: %v", err)
+	}
+
+	var regressions []string
+	for _, report := range reports {
+		regressions = append(regressions, baseline.Regressions(report, dataset, *tolerance)...)
+	}
+	if !*allowUnavailable {
+		for _, scorer := range unavailable {
+			regressions = append(regressions, scorer+" (pass -allow-unavailable to skip it)")
+		}
+	}
+	if len(regressions) > 0 {
+		fmt.Println("\nRegressions beyond tolerance:")
+		for _, regression := range regressions {
+			fmt.Println("  - " + regression)
+		}
+		os.Exit(1)
+	}
+	fmt.Println("\nNo regressions against baseline")
+}
+
+func printReports(reports []evaluation.Report) {
+	fmt.Printf("%-8s %8s %6s %9s %7s %7s %7s %7s\n", "scorer", "examples", "errors", "precision", "recall", "f1", "ndcg", "ece")
+	for _, r := range reports {
+		fmt.Printf("%-8s %8d %6d %9.3f %7.3f %7.3f %7.3f %7.3f\n", r.Scorer, r.Examples, r.Errors, r.Precision, r.Recall, r.F1, r.NDCG, r.ECE)
+	}
+
+	for _, r := range reports {
+		fmt.Printf("\n%s threshold curve:\n", r.Scorer)
+		for _, p := range r.Curve {
+			fmt.Printf("  >= %.1f  precision %.3f  recall %.3f  f1 %.3f\n", p.Threshold, p.Precision, p.Recall, p.F1)
+		}
+		fmt.Printf("%s calibration:\n", r.Scorer)
+		for _, b := range r.Calibration {
+			fmt.Printf("  [%.1f, %.1f)  n=%-4d mean score %.3f  relevant %.3f\n", b.Lower, b.Upper, b.Count, b.MeanScore, b.RelevantRate)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+// Command trainweights fits the fuzzy and embedding scoring weights to relevance feedback with logistic
+// regression and writes a weights file the matcher loads at startup (WEIGHTS_FILE).
+//
+//	go run ./cmd/trainweights -feedback data/feedback.jsonl -out data/weights.json
+//
+// The model is p(relevant) = sigmoid(a*score + b), where score is the score the matcher serves (Weights.Score): the
+// base similarity plus the weighted bonuses, clamped to [0, 1], minus the relevance and variant penalties, floored
+// at 0. The fitted weights stay in score units. A weight only learns from entries its term moves: bonuses not from
+// entries the clamp saturates, and nothing from entries floored at 0. Weights are kept non-negative, so a penalty
+// never becomes a bonus, and L2 regularisation pulls them towards the defaults, so features the feedback rarely
+// exercises keep them.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"price-comparison-tool/internal/feedback"
+	"price-comparison-tool/internal/matcher"
+	"sort"
+	"time"
+)
+
+func main() {
+	feedbackPath := flag.String("feedback", "data/feedback.jsonl", "relevance feedback recorded by the API")
+	outPath := flag.String("out", "data/weights.json", "weights file to write")
+	epochs := flag.Int("epochs", 3000, "gradient descent passes over the feedback")
+	rate := flag.Float64("rate", 0.5, "learning rate")
+	l2 := flag.Float64("l2", 0.05, "strength of the pull towards the default weights")
+	minExamples := flag.Int("min-examples", 30, "refuse to train on fewer feedback entries")
+	dryRun := flag.Bool("dry-run", false, "print the fitted weights without writing them")
+	flag.Parse()
+
+	entries, err := feedback.Load(*feedbackPath)
+	if err != nil {
+		log.Fatalf("Failed to load feedback: %v", err)
+	}
+	if len(entries) < *minExamples {
+		log.Fatalf("Only %d feedback entries, need at least %d", len(entries), *minExamples)
+	}
+
+	positives := 0
+	for _, entry := range entries {
+		if entry.Relevant() {
+			positives++
+		}
+	}
+	if positives == 0 || positives == len(entries) {
+		log.Fatalf("Feedback needs both relevant and irrelevant entries, have %d relevant of %d", positives, len(entries))
+	}
+
+	defaults := matcher.DefaultWeights()
+	weights := matcher.DefaultWeights()
+	terms := weights.Terms()
+	prior := make([]float64, len(terms))
+	for i, term := range terms {
+		prior[i] = term.Get()
+	}
+
+	afterClamp := make([]bool, len(terms))
+	for i, term := range terms {
+		afterClamp[i] = term.AfterClamp
+	}
+
+	// Each entry becomes its base similarity, signed feature values and label
+	base := make([]float64, len(entries))
+	features := make([][]float64, len(entries))
+	labels := make([]float64, len(entries))
+	for e, entry := range entries {
+		base[e] = entry.Features.BaseSimilarity
+		features[e] = make([]float64, len(terms))
+		for i, term := range terms {
+			features[e][i] = term.Sign * term.Feature(entry.Features)
+		}
+		if entry.Relevant() {
+			labels[e] = 1
+		}
+	}
+
+	// served mirrors Weights.Score for the weights w, reporting whether the bonuses are inside the clamp and
+	// whether the score is above the floor, which is when their terms move it
+	served := func(w []float64, e int) (score float64, bonusesLive, live bool) {
+		linear := base[e]
+		for i, value := range features[e] {
+			if !afterClamp[i] {
+				linear += w[i] * value
+			}
+		}
+		score = math.Max(0, math.Min(1, linear))
+		for i, value := range features[e] {
+			if afterClamp[i] {
+				score += w[i] * value
+			}
+		}
+		live = score > 0
+		return math.Max(0, score), live && linear > 0 && linear < 1, live
+	}
+	scores := func(w []float64) []float64 {
+		result := make([]float64, len(entries))
+		for e := range entries {
+			result[e], _, _ = served(w, e)
+		}
+		return result
+	}
+	aucBefore := auc(scores(prior), labels)
+
+	// Batch gradient descent on the log loss
+	w := append([]float64(nil), prior...)
+	scale, bias := 10.0, -5.0
+	n := float64(len(entries))
+	for epoch := 0; epoch < *epochs; epoch++ {
+		gradient := make([]float64, len(w))
+		gradientScale, gradientBias := 0.0, 0.0
+		for e := range entries {
+			score, bonusesLive, live := served(w, e)
+			residual := sigmoid(scale*score+bias) - labels[e]
+			gradientBias += residual
+			gradientScale += residual * score
+			for i, value := range features[e] {
+				if (afterClamp[i] && live) || (!afterClamp[i] && bonusesLive) {
+					gradient[i] += residual * scale * value
+				}
+			}
+		}
+
+		bias -= *rate * gradientBias / n
+		scale = math.Max(1, scale-*rate*gradientScale/n)
+		for i := range w {
+			w[i] -= *rate * (gradient[i]/n + *l2*(w[i]-prior[i]))
+			w[i] = math.Max(0, w[i])
+		}
+	}
+
+	final := scores(w)
+	loss, correct := 0.0, 0
+	for e, score := range final {
+		p := math.Min(math.Max(sigmoid(scale*score+bias), 1e-12), 1-1e-12)
+		loss -= labels[e]*math.Log(p) + (1-labels[e])*math.Log(1-p)
+		if (p >= 0.5) == (labels[e] == 1) {
+			correct++
+		}
+	}
+
+	fmt.Printf("%d feedback entries (%d relevant)\n", len(entries), positives)
+	fmt.Printf("ranking AUC %.3f -> %.3f, log loss %.3f, accuracy %.3f\n\n", aucBefore, auc(final, labels), loss/n, float64(correct)/n)
+	fmt.Printf("%-22s %8s %8s\n", "weight", "default", "trained")
+	defaultTerms := defaults.Terms()
+	for i, term := range terms {
+		term.Set(math.Round(w[i]*1000) / 1000)
+		fmt.Printf("%-22s %8.3f %8.3f\n", term.Name, defaultTerms[i].Get(), term.Get())
+	}
+
+	if *dryRun {
+		return
+	}
+	now := time.Now().UTC()
+	weights.TrainedAt = &now
+	weights.Examples = len(entries)
+	if err := matcher.SaveWeights(*outPath, weights); err != nil {
+		log.Fatalf("Failed to write weights: %v", err)
+	}
+	log.Printf("Weights written to %s; run cmd/matcheval to compare them with the baseline", *outPath)
+}
+
+func sigmoid(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
+
+// auc is the probability that a random relevant entry scores above a random irrelevant one, ties counting half
+func auc(scores, labels []float64) float64 {
+	order := make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return scores[order[i]] < scores[order[j]] })
+
+	// Sum the ranks of relevant entries, averaging the ranks of tied scores
+	rankSum, positives := 0.0, 0.0
+	for start := 0; start < len(order); {
+		end := start
+		for end < len(order) && scores[order[end]] == scores[order[start]] {
+			end++
+		}
+		rank := float64(start+end+1) / 2
+		for _, index := range order[start:end] {
+			if labels[index] == 1 {
+				rankSum += rank
+				positives++
+			}
+		}
+		start = end
+	}
+	negatives := float64(len(scores)) - positives
+	if positives == 0 || negatives == 0 {
+		return 0
+	}
+	return (rankSum - positives*(positives+1)/2) / (positives * negatives)
+}
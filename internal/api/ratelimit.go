@@ -0,0 +1,44 @@
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter allows each client a number of requests per fixed window. Counts are dropped when a window ends,
+// so memory is bounded by the clients seen in one window.
+type rateLimiter struct {
+	limit  int
+	window time.Duration
+
+	mutex  sync.Mutex
+	start  time.Time
+	counts map[string]int
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{limit: limit, window: window, counts: make(map[string]int)}
+}
+
+// allow counts a request from client at now, reporting false once the client has used the window's requests
+func (l *rateLimiter) allow(client string, now time.Time) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if now.Sub(l.start) >= l.window {
+		l.start = now
+		l.counts = make(map[string]int)
+	}
+	if l.counts[client] >= l.limit {
+		return false
+	}
+	l.counts[client]++
+	return true
+}
+
+// retryAfter is how long until the current window ends
+func (l *rateLimiter) retryAfter(now time.Time) time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.start.Add(l.window).Sub(now)
+}
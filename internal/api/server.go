@@ -2,23 +2,38 @@ package api
 
 import (
 	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"price-comparison-tool/internal/brands"
 	"price-comparison-tool/internal/config"
+	"price-comparison-tool/internal/feedback"
+	"price-comparison-tool/internal/identifiers"
+	"price-comparison-tool/internal/llm"
+	"price-comparison-tool/internal/matcher"
 	"price-comparison-tool/internal/models"
 	"price-comparison-tool/internal/scraper"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
+// maxFeedbackBody caps a feedback request; a result with a few dozen others shown fits well within it
+const maxFeedbackBody = 256 << 10
+
 type Server struct {
 	config  *config.Config
 	scraper *scraper.Service
 	router  *gin.Engine
+
+	feedbackLimiter *rateLimiter // nil when FEEDBACK_RATE_LIMIT is 0
 }
 
 func NewServer(cfg *config.Config) *Server {
@@ -29,6 +44,9 @@ func NewServer(cfg *config.Config) *Server {
 		scraper: scraper.NewService(cfg),
 		router:  gin.Default(),
 	}
+	if cfg.FeedbackRateLimit > 0 {
+		s.feedbackLimiter = newRateLimiter(cfg.FeedbackRateLimit, time.Minute)
+	}
 	
 	s.setupRoutes()
 	return s
@@ -50,6 +68,17 @@ func (s *Server) setupRoutes() {
 		api.POST("/prices", s.getPrices)
 		api.GET("/prices/stream", s.getPricesStream)
 		api.GET("/sites", s.getSupportedSites)
+		api.GET("/brands", s.listBrands)
+		api.GET("/brands/:name", s.getBrand)
+		api.POST("/feedback", s.guardFeedback, s.recordFeedback)
+	}
+
+	admin := s.router.Group("/api/v1/admin", s.requireAdmin)
+	{
+		admin.GET("/selectors", s.listSelectorRevisions)
+		admin.POST("/selectors/:id/approve", s.approveSelectorRevision)
+		admin.POST("/selectors/:id/reject", s.rejectSelectorRevision)
+		admin.POST("/brands", s.addBrand)
 	}
 
 	s.router.Static("/static", "./web/static")
@@ -66,6 +95,9 @@ func (s *Server) healthCheck(c *gin.Context) {
 		"status":    "ok",
 		"timestamp": time.Now().Unix(),
 		"service":   "price-comparison-tool",
+		"llm":       s.scraper.LLMStats(),
+		"cascade":   s.scraper.CascadeStats(),
+		"rates":     s.scraper.ExchangeRates(c.Request.Context()),
 	})
 }
 
@@ -75,24 +107,39 @@ func (s *Server) getPrices(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !matcher.IsValidStrategy(req.Strategy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown strategy: " + req.Strategy})
+		return
+	}
+	if err := validateSearch(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.DisplayCurrency = strings.ToUpper(req.DisplayCurrency); req.DisplayCurrency != "" &&
+		!s.scraper.ExchangeRates(c.Request.Context()).Supports(req.DisplayCurrency) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported displayCurrency: " + req.DisplayCurrency})
+		return
+	}
 
-	// Add timeout context
-	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	// Add timeout context, tagged so the LLM scheduler can share capacity fairly between searches
+	ctx, cancel := context.WithTimeout(llm.WithRequestID(c.Request.Context(), llm.NewRequestID()), 30*time.Second)
 	defer cancel()
 
-	results, err := s.scraper.FetchPrices(ctx, req.Country, req.Query)
+	response, err := s.scraper.FetchPrices(ctx, req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	sortResultsByConfidenceAndPrice(results)
+	response.Query, response.Country = req.Query, req.Country
+	sortResultsByConfidenceAndPrice(response.Results, rankByPrice(req))
 
-	response := models.PriceResponse{
-		Results: results,
-		Query:   req.Query,
-		Country: req.Country,
-		Count:   len(results),
+	if req.GroupVariants {
+		response.Results, response.OtherVariants = models.SplitVariants(response.Results)
+		response.Count = len(response.Results)
+	}
+	if req.Grouped {
+		response.Groups = s.scraper.ClusterProducts(response.Results)
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -103,14 +150,44 @@ func (s *Server) getPricesStream(c *gin.Context) {
 	country := c.Query("country")
 	query := c.Query("query")
 	
-	if country == "" || query == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "country and query parameters are required"})
+	if country == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "country parameter is required"})
 		return
 	}
 	
 	req := models.PriceRequest{
-		Country: country,
-		Query:   query,
+		Country:         country,
+		Query:           query,
+		GTIN:            c.Query("gtin"),
+		Strategy:        c.Query("strategy"),
+		GroupVariants:   c.Query("groupVariants") == "true",
+		Grouped:         c.Query("grouped") == "true",
+		Condition:       c.Query("condition"),
+		Explain:         c.Query("explain") == "true",
+		DisplayCurrency: c.Query("displayCurrency"),
+		LandedCost:      c.Query("landedCost") == "true",
+		PostalCode:      c.Query("postalCode"),
+	}
+	if value := c.Query("minConfidence"); value != "" {
+		minConfidence, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid minConfidence: " + value})
+			return
+		}
+		req.MinConfidence = &minConfidence
+	}
+	if !matcher.IsValidStrategy(req.Strategy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown strategy: " + req.Strategy})
+		return
+	}
+	if err := validateSearch(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.DisplayCurrency = strings.ToUpper(req.DisplayCurrency); req.DisplayCurrency != "" &&
+		!s.scraper.ExchangeRates(c.Request.Context()).Supports(req.DisplayCurrency) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported displayCurrency: " + req.DisplayCurrency})
+		return
 	}
 
 	// Set headers for SSE (Server-Sent Events)
@@ -123,13 +200,13 @@ func (s *Server) getPricesStream(c *gin.Context) {
 	resultsChan := make(chan models.StreamingResult, 10)
 	
 	// Add timeout context
-	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	ctx, cancel := context.WithTimeout(llm.WithRequestID(c.Request.Context(), llm.NewRequestID()), 60*time.Second)
 	defer cancel()
 
 	// Start price fetching in goroutine
 	go func() {
 		defer close(resultsChan)
-		s.scraper.FetchPricesStreaming(ctx, req.Country, req.Query, resultsChan)
+		s.scraper.FetchPricesStreaming(ctx, req, resultsChan)
 	}()
 
 	// Stream results as they come in
@@ -164,37 +241,210 @@ func (s *Server) getSupportedSites(c *gin.Context) {
 	})
 }
 
+func (s *Server) listBrands(c *gin.Context) {
+	list := s.scraper.Brands(c.Query("category"))
+	c.JSON(http.StatusOK, gin.H{
+		"brands": list,
+		"count":  len(list),
+	})
+}
+
+func (s *Server) getBrand(c *gin.Context) {
+	brand, exists := s.scraper.Brand(c.Param("name"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown brand: " + c.Param("name")})
+		return
+	}
+	c.JSON(http.StatusOK, brand)
+}
+
+func (s *Server) addBrand(c *gin.Context) {
+	var brand brands.Brand
+	if err := c.ShouldBindJSON(&brand); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	added, err := s.scraper.AddBrand(brand)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, added)
+}
+
+func (s *Server) recordFeedback(c *gin.Context) {
+	var req models.FeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateFeedback(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entries, err := s.scraper.RecordFeedback(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"recorded": entries,
+		"count":    len(entries),
+	})
+}
+
+// guardFeedback checks the bearer token against FEEDBACK_TOKEN when one is set, limits each client to
+// FEEDBACK_RATE_LIMIT requests a minute and caps the request body, since feedback is written to disk and trains
+// the scoring weights
+func (s *Server) guardFeedback(c *gin.Context) {
+	if s.config.FeedbackToken != "" {
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.config.FeedbackToken)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid feedback token"})
+			return
+		}
+	}
+	if s.feedbackLimiter != nil {
+		now := time.Now()
+		if !s.feedbackLimiter.allow(c.RemoteIP(), now) {
+			seconds := int(s.feedbackLimiter.retryAfter(now).Seconds()) + 1
+			c.Header("Retry-After", strconv.Itoa(seconds))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "too many feedback requests, retry later"})
+			return
+		}
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxFeedbackBody)
+	c.Next()
+}
+
+// requireAdmin checks the bearer token against ADMIN_TOKEN; without a configured token the admin API is off
+func (s *Server) requireAdmin(c *gin.Context) {
+	if s.config.AdminToken == "" {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin API disabled, set ADMIN_TOKEN to enable it"})
+		return
+	}
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.config.AdminToken)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid admin token"})
+		return
+	}
+	c.Next()
+}
+
+func (s *Server) listSelectorRevisions(c *gin.Context) {
+	revisions := s.scraper.SelectorRevisions(c.Query("status"))
+	c.JSON(http.StatusOK, gin.H{
+		"revisions": revisions,
+		"count":     len(revisions),
+	})
+}
+
+func (s *Server) approveSelectorRevision(c *gin.Context) {
+	s.reviewSelectorRevision(c, true)
+}
+
+func (s *Server) rejectSelectorRevision(c *gin.Context) {
+	s.reviewSelectorRevision(c, false)
+}
+
+func (s *Server) reviewSelectorRevision(c *gin.Context, approve bool) {
+	revision, err := s.scraper.ReviewSelectorRevision(c.Param("id"), approve)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, revision)
+}
+
 func (s *Server) indexHandler(c *gin.Context) {
 	c.HTML(http.StatusOK, "index.html", gin.H{
 		"title": "Price Comparison Tool",
 	})
 }
 
-func sortResultsByConfidenceAndPrice(results []models.ProductResult) {
+// validateSearch requires a query or a barcode, a barcode to have a valid check digit and a known condition
+func validateSearch(req models.PriceRequest) error {
+	if strings.TrimSpace(req.Query) == "" && req.GTIN == "" {
+		return fmt.Errorf("query or gtin is required")
+	}
+	if req.GTIN != "" && identifiers.NormalizeGTIN(req.GTIN) == "" {
+		return fmt.Errorf("invalid gtin: %s", req.GTIN)
+	}
+	if !matcher.IsValidCondition(req.Condition) {
+		return fmt.Errorf("unknown condition: %s", req.Condition)
+	}
+	if req.MinConfidence != nil && (*req.MinConfidence < 0 || *req.MinConfidence > 1) {
+		return fmt.Errorf("minConfidence must be between 0 and 1")
+	}
+	return nil
+}
+
+// validateFeedback requires a known label and a judged title; other results only accompany a correct match
+func validateFeedback(req models.FeedbackRequest) error {
+	if !feedback.IsValidLabel(req.Label) {
+		return fmt.Errorf("unknown label: %s", req.Label)
+	}
+	if strings.TrimSpace(req.Result.ProductName) == "" {
+		return fmt.Errorf("result.productName is required")
+	}
+	if len(req.Others) > 0 && req.Label != feedback.LabelCorrect {
+		return fmt.Errorf("others are only accepted with label %q", feedback.LabelCorrect)
+	}
+	return nil
+}
+
+// priceBandWidth is the width of the confidence bands within which results are ranked by price when the request
+// asks for prices in one currency
+const priceBandWidth = 0.1
+
+// sortResultsByConfidenceAndPrice orders results by confidence, cheapest first among equals. With byPrice, results
+// whose confidence falls in the same band are ranked cheapest first, so a cheaper offer that is about as likely
+// to be the product asked for ranks above a dearer one.
+func sortResultsByConfidenceAndPrice(results []models.ProductResult, byPrice bool) {
 	sort.Slice(results, func(i, j int) bool {
+		if byPrice {
+			bandI, bandJ := confidenceBand(results[i].Confidence), confidenceBand(results[j].Confidence)
+			if bandI != bandJ {
+				return bandI > bandJ
+			}
+			priceI, errI := results[i].RankAmount()
+			priceJ, errJ := results[j].RankAmount()
+			if errI == nil && errJ == nil && priceI.Currency == priceJ.Currency && priceI.Minor != priceJ.Minor {
+				return priceI.Minor < priceJ.Minor
+			}
+		}
+		
 		// First, sort by confidence score (descending - higher confidence first)
 		if results[i].Confidence != results[j].Confidence {
 			return results[i].Confidence > results[j].Confidence
 		}
 		
-		// If confidence is the same, sort by price (ascending - lower price first)
-		priceI, errI := parsePrice(results[i].Price)
-		priceJ, errJ := parsePrice(results[j].Price)
+		// If confidence is the same, sort by price or landed total (ascending - lower price first)
+		priceI, errI := results[i].RankAmount()
+		priceJ, errJ := results[j].RankAmount()
 		
 		if errI != nil || errJ != nil {
 			return false
 		}
 		
-		return priceI < priceJ
+		return priceI.Float() < priceJ.Float()
 	})
 }
 
-func parsePrice(priceStr string) (float64, error) {
-	cleaned := ""
-	for _, char := range priceStr {
-		if (char >= '0' && char <= '9') || char == '.' {
-			cleaned += string(char)
-		}
-	}
-	return strconv.ParseFloat(cleaned, 64)
-}
\ No newline at end of file
+// rankByPrice reports whether the request asks for prices comparable across sites, converted into its display
+// currency or totalled with shipping and tax, so results are ranked by them within confidence bands
+func rankByPrice(req models.PriceRequest) bool {
+	return req.DisplayCurrency != "" || req.LandedCost
+}
+
+// confidenceBand is the band of width priceBandWidth a confidence falls in
+func confidenceBand(confidence float64) int {
+	return int(math.Floor(confidence/priceBandWidth + 1e-9))
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"price-comparison-tool/internal/config"
+	"price-comparison-tool/internal/models"
+	"price-comparison-tool/internal/money"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimiterResetsEachWindow(t *testing.T) {
+	limiter := newRateLimiter(2, time.Minute)
+	start := time.Now()
+
+	for i, want := range []bool{true, true, false} {
+		if got := limiter.allow("10.0.0.1", start); got != want {
+			t.Errorf("request %d: allow() = %v, want %v", i+1, got, want)
+		}
+	}
+	if !limiter.allow("10.0.0.2", start) {
+		t.Error("another client was limited by the first client's requests")
+	}
+	if retry := limiter.retryAfter(start.Add(20 * time.Second)); retry != 40*time.Second {
+		t.Errorf("retryAfter() = %v, want 40s", retry)
+	}
+	if !limiter.allow("10.0.0.1", start.Add(time.Minute)) {
+		t.Error("client still limited in the next window")
+	}
+}
+
+// feedbackRouter serves the feedback guard in front of a handler reading the whole body
+func feedbackRouter(cfg *config.Config) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	s := &Server{config: cfg}
+	if cfg.FeedbackRateLimit > 0 {
+		s.feedbackLimiter = newRateLimiter(cfg.FeedbackRateLimit, time.Minute)
+	}
+	router := gin.New()
+	router.POST("/feedback", s.guardFeedback, func(c *gin.Context) {
+		var tooLarge *http.MaxBytesError
+		if _, err := io.ReadAll(c.Request.Body); errors.As(err, &tooLarge) {
+			c.Status(http.StatusRequestEntityTooLarge)
+			return
+		}
+		c.Status(http.StatusCreated)
+	})
+	return router
+}
+
+func postFeedback(router *gin.Engine, token, body string) int {
+	req := httptest.NewRequest(http.MethodPost, "/feedback", strings.NewReader(body))
+	req.RemoteAddr = "10.0.0.1:1234"
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder.Code
+}
+
+func TestGuardFeedback(t *testing.T) {
+	router := feedbackRouter(&config.Config{FeedbackToken: "secret", FeedbackRateLimit: 2})
+	if code := postFeedback(router, "", "{}"); code != http.StatusUnauthorized {
+		t.Errorf("without the token: status %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code := postFeedback(router, "secret", "{}"); code != http.StatusCreated {
+		t.Errorf("with the token: status %d, want %d", code, http.StatusCreated)
+	}
+	if code := postFeedback(router, "secret", strings.Repeat("x", maxFeedbackBody+1)); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized body: status %d, want %d", code, http.StatusRequestEntityTooLarge)
+	}
+	if code := postFeedback(router, "secret", "{}"); code != http.StatusTooManyRequests {
+		t.Errorf("third request in a minute: status %d, want %d", code, http.StatusTooManyRequests)
+	}
+
+	open := feedbackRouter(&config.Config{})
+	for i := 0; i < 5; i++ {
+		if code := postFeedback(open, "", "{}"); code != http.StatusCreated {
+			t.Fatalf("without a token or limit configured: status %d, want %d", code, http.StatusCreated)
+		}
+	}
+}
+
+// offer is a result on site with a confidence and a price converted into USD
+func offer(site string, confidence float64, usdCents int64) models.ProductResult {
+	return models.ProductResult{
+		Site:       site,
+		Price:      "1.00",
+		Currency:   "EUR",
+		Confidence: confidence,
+		Converted:  &models.ConvertedPrice{Value: money.Money{Minor: usdCents, Currency: "USD"}},
+	}
+}
+
+func TestSortResultsRanksByDisplayCurrencyWithinConfidenceBands(t *testing.T) {
+	results := []models.ProductResult{
+		offer("a", 0.97, 120000),
+		offer("b", 0.91, 110000),
+		offer("c", 0.85, 90000),
+		offer("d", 0.99, 130000),
+		offer("e", 0.88, 95000),
+	}
+	order := func() string {
+		var sites []string
+		for _, result := range results {
+			sites = append(sites, result.Site)
+		}
+		return strings.Join(sites, "")
+	}
+
+	sortResultsByConfidenceAndPrice(results, rankByPrice(models.PriceRequest{DisplayCurrency: "USD"}))
+	if got := order(); got != "badce" {
+		t.Errorf("ranked by converted price within bands: %s, want badce", got)
+	}
+
+	sortResultsByConfidenceAndPrice(results, rankByPrice(models.PriceRequest{}))
+	if got := order(); got != "dabec" {
+		t.Errorf("ranked by confidence: %s, want dabec", got)
+	}
+}
+
+func TestSortResultsRanksByLandedTotalWithinConfidenceBands(t *testing.T) {
+	// b has the lowest price but the highest total once shipping is added
+	landed := func(site string, confidence float64, priceCents, shippingCents int64) models.ProductResult {
+		return models.ProductResult{
+			Site:       site,
+			Price:      money.Money{Minor: priceCents, Currency: "USD"}.Decimal(),
+			Currency:   "USD",
+			Confidence: confidence,
+			Landed: &models.LandedCost{
+				Price:    money.Money{Minor: priceCents, Currency: "USD"},
+				Shipping: money.Money{Minor: shippingCents, Currency: "USD"},
+				Total:    money.Money{Minor: priceCents + shippingCents, Currency: "USD"},
+			},
+		}
+	}
+	results := []models.ProductResult{
+		landed("a", 0.95, 50000, 0),
+		landed("b", 0.93, 48000, 4000),
+		landed("c", 0.91, 49000, 500),
+		landed("d", 0.72, 30000, 0),
+	}
+
+	sortResultsByConfidenceAndPrice(results, rankByPrice(models.PriceRequest{LandedCost: true}))
+	var sites []string
+	for _, result := range results {
+		sites = append(sites, result.Site)
+	}
+	if got := strings.Join(sites, ""); got != "cabd" {
+		t.Errorf("ranked by landed total within bands: %s, want cabd", got)
+	}
+}
@@ -0,0 +1,419 @@
+// Package brands is the brand knowledge base shared by every matcher: canonical brand names with their
+// aliases, product lines, transliterations and category hints. The embedded list covers the common brands
+// of each category we scrape; entries added at runtime are persisted to a JSON file and merged on load.
+package brands
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"price-comparison-tool/internal/lang"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+//go:embed brands.json
+var embeddedBrands []byte
+
+// Brand describes one canonical brand and every name that refers to it
+type Brand struct {
+	Name             string        `json:"name"`
+	Aliases          []string      `json:"aliases,omitempty"`
+	Lines            []ProductLine `json:"lines,omitempty"`
+	Transliterations []string      `json:"transliterations,omitempty"`
+	Categories       []string      `json:"categories,omitempty"`
+}
+
+// ProductLine is a sub-brand or product line that implies its brand, e.g. "galaxy" for samsung
+type ProductLine struct {
+	Name     string `json:"name"`
+	Category string `json:"category,omitempty"`
+}
+
+// UnmarshalJSON accepts a bare string as shorthand for a line without a category
+func (l *ProductLine) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		l.Name = name
+		l.Category = ""
+		return nil
+	}
+	type plain ProductLine
+	return json.Unmarshal(data, (*plain)(l))
+}
+
+// Match is a brand found in a piece of text
+type Match struct {
+	Brand    string // canonical brand name
+	Line     string // product line, when one was named
+	Category string // category hint from the line, or the brand's only category
+	Term     string // the text that matched, normalized
+}
+
+// term is one searchable name: a brand name, alias, transliteration or line
+type term struct {
+	text  string
+	brand string
+	line  *ProductLine
+}
+
+// KnowledgeBase resolves brand mentions in queries and product titles
+type KnowledgeBase struct {
+	mutex  sync.RWMutex
+	path   string
+	brands map[string]*Brand
+	custom map[string]*Brand // runtime additions, persisted to path
+	terms  []term            // longest first so "galaxy tab" wins over "galaxy"
+}
+
+// Load builds the knowledge base from the embedded list and then merges the brands stored at path.
+// A missing file is not an error.
+func Load(path string) (*KnowledgeBase, error) {
+	kb := &KnowledgeBase{
+		path:   path,
+		brands: make(map[string]*Brand),
+		custom: make(map[string]*Brand),
+	}
+
+	var builtIn []Brand
+	if err := json.Unmarshal(embeddedBrands, &builtIn); err != nil {
+		return nil, fmt.Errorf("embedded brands: %v", err)
+	}
+	for _, brand := range builtIn {
+		kb.merge(kb.brands, brand)
+	}
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			kb.index()
+			return kb, err
+		}
+		if err == nil {
+			var custom []Brand
+			if err := json.Unmarshal(data, &custom); err != nil {
+				kb.index()
+				return kb, fmt.Errorf("parse %s: %v", path, err)
+			}
+			for _, brand := range custom {
+				kb.merge(kb.custom, brand)
+				kb.merge(kb.brands, brand)
+			}
+		}
+	}
+
+	kb.index()
+	return kb, nil
+}
+
+// Detect returns the brand a text refers to. The longest matching name wins, and when the brand is
+// named directly its longest product line present in the text is reported too.
+func (kb *KnowledgeBase) Detect(text string) (Match, bool) {
+	padded := " " + Normalize(text) + " "
+
+	kb.mutex.RLock()
+	defer kb.mutex.RUnlock()
+
+	for _, t := range kb.terms {
+		if !containsWord(padded, t.text) {
+			continue
+		}
+
+		match := Match{Brand: t.brand, Term: t.text}
+		line := t.line
+		if line == nil {
+			line = kb.longestLine(padded, t.brand)
+		}
+		if line != nil {
+			match.Line = line.Name
+			match.Category = line.Category
+		}
+		if match.Category == "" {
+			if brand := kb.brands[t.brand]; len(brand.Categories) == 1 {
+				match.Category = brand.Categories[0]
+			}
+		}
+		return match, true
+	}
+	return Match{}, false
+}
+
+// Mentions reports whether text names the brand through any of its names or product lines
+func (kb *KnowledgeBase) Mentions(text, brand string) bool {
+	padded := " " + Normalize(text) + " "
+
+	kb.mutex.RLock()
+	defer kb.mutex.RUnlock()
+
+	for _, t := range kb.terms {
+		if t.brand == brand && containsWord(padded, t.text) {
+			return true
+		}
+	}
+	return false
+}
+
+// MentionedBrands lists every distinct brand named in text, in order of the longest matching name
+func (kb *KnowledgeBase) MentionedBrands(text string) []string {
+	padded := " " + Normalize(text) + " "
+
+	kb.mutex.RLock()
+	defer kb.mutex.RUnlock()
+
+	var found []string
+	seen := make(map[string]bool)
+	for _, t := range kb.terms {
+		if !seen[t.brand] && containsWord(padded, t.text) {
+			seen[t.brand] = true
+			found = append(found, t.brand)
+		}
+	}
+	return found
+}
+
+// Lines lists the product lines of brand named in text, longest first
+func (kb *KnowledgeBase) Lines(text, brand string) []string {
+	padded := " " + Normalize(text) + " "
+
+	kb.mutex.RLock()
+	defer kb.mutex.RUnlock()
+
+	var found []string
+	for _, t := range kb.terms {
+		if t.brand == brand && t.line != nil && containsWord(padded, t.text) {
+			found = append(found, t.line.Name)
+		}
+	}
+	return found
+}
+
+// Get looks a brand up by its canonical name or any alias
+func (kb *KnowledgeBase) Get(name string) (Brand, bool) {
+	key := Normalize(name)
+
+	kb.mutex.RLock()
+	defer kb.mutex.RUnlock()
+
+	if brand, exists := kb.brands[key]; exists {
+		return *brand, true
+	}
+	for _, t := range kb.terms {
+		if t.text == key && t.line == nil {
+			return *kb.brands[t.brand], true
+		}
+	}
+	return Brand{}, false
+}
+
+// List returns all brands sorted by name, optionally only those with the given category hint
+func (kb *KnowledgeBase) List(category string) []Brand {
+	kb.mutex.RLock()
+	defer kb.mutex.RUnlock()
+
+	var result []Brand
+	for _, brand := range kb.brands {
+		if category == "" || hasCategory(brand, category) {
+			result = append(result, *brand)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// Add merges a brand into the knowledge base and persists it. Names already known are extended with
+// the new aliases, lines, transliterations and categories rather than replaced.
+func (kb *KnowledgeBase) Add(brand Brand) (Brand, error) {
+	brand.Name = Normalize(brand.Name)
+	if brand.Name == "" {
+		return Brand{}, fmt.Errorf("brand name is required")
+	}
+
+	kb.mutex.Lock()
+	defer kb.mutex.Unlock()
+
+	// An alias of an existing brand extends that brand
+	for _, t := range kb.terms {
+		if t.text == brand.Name && t.line == nil {
+			brand.Name = t.brand
+			break
+		}
+	}
+
+	kb.merge(kb.custom, brand)
+	kb.merge(kb.brands, brand)
+	kb.index()
+
+	return *kb.brands[brand.Name], kb.save()
+}
+
+// merge adds brand into set, unioning its names with an existing entry of the same canonical name
+func (kb *KnowledgeBase) merge(set map[string]*Brand, brand Brand) {
+	name := Normalize(brand.Name)
+	if name == "" {
+		return
+	}
+
+	existing, exists := set[name]
+	if !exists {
+		existing = &Brand{Name: name}
+		set[name] = existing
+	}
+
+	existing.Aliases = union(existing.Aliases, normalizeAll(brand.Aliases))
+	existing.Transliterations = union(existing.Transliterations, normalizeAll(brand.Transliterations))
+	existing.Categories = union(existing.Categories, normalizeAll(brand.Categories))
+	for _, line := range brand.Lines {
+		line.Name = Normalize(line.Name)
+		line.Category = Normalize(line.Category)
+		if line.Name == "" {
+			continue
+		}
+		replaced := false
+		for i := range existing.Lines {
+			if existing.Lines[i].Name == line.Name {
+				if line.Category != "" {
+					existing.Lines[i].Category = line.Category
+				}
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			existing.Lines = append(existing.Lines, line)
+		}
+	}
+}
+
+// index rebuilds the term list. Must be called with the mutex held (or before the knowledge base is shared).
+func (kb *KnowledgeBase) index() {
+	var terms []term
+	for _, brand := range kb.brands {
+		terms = append(terms, term{text: brand.Name, brand: brand.Name})
+		for _, name := range brand.Aliases {
+			terms = append(terms, term{text: name, brand: brand.Name})
+		}
+		for _, name := range brand.Transliterations {
+			terms = append(terms, term{text: name, brand: brand.Name})
+		}
+		for i := range brand.Lines {
+			terms = append(terms, term{text: brand.Lines[i].Name, brand: brand.Name, line: &brand.Lines[i]})
+		}
+	}
+
+	sort.SliceStable(terms, func(i, j int) bool {
+		if len(terms[i].text) != len(terms[j].text) {
+			return len(terms[i].text) > len(terms[j].text)
+		}
+		return terms[i].text < terms[j].text
+	})
+	kb.terms = terms
+}
+
+// longestLine finds the longest product line of brand present in padded text. Must be called with the mutex held.
+func (kb *KnowledgeBase) longestLine(padded, brand string) *ProductLine {
+	var best *ProductLine
+	for i, line := range kb.brands[brand].Lines {
+		if containsWord(padded, line.Name) && (best == nil || len(line.Name) > len(best.Name)) {
+			best = &kb.brands[brand].Lines[i]
+		}
+	}
+	return best
+}
+
+// save writes the runtime additions to disk. Must be called with the mutex held.
+func (kb *KnowledgeBase) save() error {
+	if kb.path == "" {
+		return nil
+	}
+
+	custom := make([]*Brand, 0, len(kb.custom))
+	for _, brand := range kb.custom {
+		custom = append(custom, brand)
+	}
+	sort.Slice(custom, func(i, j int) bool { return custom[i].Name < custom[j].Name })
+
+	data, err := json.MarshalIndent(custom, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(kb.path), 0755); err != nil {
+		return err
+	}
+
+	tmp := kb.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, kb.path)
+}
+
+// Normalize lowercases text, drops apostrophes ("levi's" -> "levis") and turns hyphens and other separators
+// into single spaces, so "ray-ban" and "ray ban" compare equal. '&' is kept since brand names use it ("h&m").
+func Normalize(text string) string {
+	text = strings.NewReplacer("'", "").Replace(lang.Fold(text))
+	return strings.Join(strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && !unicode.IsMark(r) && r != '&'
+	}), " ")
+}
+
+// containsWord reports whether term occurs in padded text on word boundaries. Scripts written without
+// spaces (CJK, kana) cannot be split into words, so those terms match as substrings.
+func containsWord(padded, term string) bool {
+	if term == "" {
+		return false
+	}
+	if strings.Contains(padded, " "+term+" ") {
+		return true
+	}
+	for _, r := range term {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			return strings.Contains(padded, term)
+		}
+	}
+	return false
+}
+
+func hasCategory(brand *Brand, category string) bool {
+	category = Normalize(category)
+	for _, c := range brand.Categories {
+		if c == category {
+			return true
+		}
+	}
+	for _, line := range brand.Lines {
+		if line.Category == category {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeAll(values []string) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if normalized := Normalize(value); normalized != "" {
+			result = append(result, normalized)
+		}
+	}
+	return result
+}
+
+func union(existing, additions []string) []string {
+	for _, addition := range additions {
+		found := false
+		for _, value := range existing {
+			if value == addition {
+				found = true
+				break
+			}
+		}
+		if !found {
+			existing = append(existing, addition)
+		}
+	}
+	return existing
+}
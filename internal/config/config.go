@@ -3,24 +3,131 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
 )
 
 type Config struct {
 	Port           string
 	OllamaHost     string
+	OllamaModel    string
 	MaxConcurrency int
 	RequestTimeout int
+
+	// LLMContextWindow is the model context size in tokens; 0 derives it from OllamaModel
+	LLMContextWindow int
+	// LLMConcurrency is the process-wide limit on in-flight LLM calls
+	LLMConcurrency int
+	// MaxChunksPerPage caps how many extraction prompts one results page fans out to; 0 removes the cap
+	MaxChunksPerPage int
+
+	// MatchStrategy is the scoring strategy of requests that name none: fuzzy, basic, bm25, llm, embedding or cascade
+	MatchStrategy string
+	// MinConfidence drops results scoring below it unless a request sets its own. With a calibration file it is
+	// a probability of relevance, comparable across strategies, extraction and the CSS fallback.
+	MinConfidence float64
+
+	// CascadeAccept and CascadeReject are the fuzzy scores at or above and below which the cascade strategy
+	// settles a result without an LLM; an accepted result must also be free of accessory and variant penalties
+	CascadeAccept float64
+	CascadeReject float64
+	// CascadeSmallModel scores the results fuzzy matching leaves open; defaults to OllamaModel
+	CascadeSmallModel string
+	// CascadeLargeModel rescores results the small model scored within the uncertain band; empty disables it
+	CascadeLargeModel string
+	// CascadeUncertainLow and CascadeUncertainHigh bound the uncertain band of small model scores
+	CascadeUncertainLow  float64
+	CascadeUncertainHigh float64
+
+	// EmbeddingModel is the Ollama model used by the embedding scoring strategy
+	EmbeddingModel string
+	// EmbeddingCacheSize bounds how many text vectors are kept in memory
+	EmbeddingCacheSize int
+
+	// QueryLLM enables an LLM pass that fills query attributes the rule-based parser missed
+	QueryLLM bool
+
+	// PromptsDir holds *.tmpl files overriding the embedded prompt templates
+	PromptsDir string
+	// PromptVersions pins prompts to a version, e.g. "scoring=v1,extraction=v2"
+	PromptVersions string
+
+	// BrandsFile persists brands added through the API on top of the embedded brand knowledge base
+	BrandsFile string
+
+	// TaxonomyFile adds or replaces categories of the embedded accessory/consumable/part taxonomy
+	TaxonomyFile string
+
+	// IdentifierLookups is how many detail pages per site are fetched to read barcodes during a GTIN search
+	IdentifierLookups int
+
+	// FeedbackFile appends relevance feedback, with the match features of each judged result, as JSON lines
+	FeedbackFile string
+	// FeedbackToken, when set, must be sent as a bearer token to record feedback
+	FeedbackToken string
+	// FeedbackRateLimit is how many feedback requests a client may send per minute; 0 removes the limit
+	FeedbackRateLimit int
+	// WeightsFile holds scoring weights fitted to feedback by cmd/trainweights; defaults are used without it
+	WeightsFile string
+	// CalibrationFile maps each confidence source's raw scores to probabilities, fitted by cmd/calibrate
+	CalibrationFile string
+
+	// RatesSource is an ECB-style XML or JSON exchange rates file or URL; empty uses the built-in rates
+	RatesSource string
+	// RatesTTL is how many minutes loaded exchange rates are used before they are reloaded
+	RatesTTL int
+
+	// TaxRulesFile adds or replaces country tax rules used to estimate landed cost
+	TaxRulesFile string
+	// ShippingLookups is how many detail pages per site are fetched to read shipping when estimating landed cost
+	ShippingLookups int
+
+	// SelectorRevisionsFile persists LLM-proposed selector repairs and their review status
+	SelectorRevisionsFile string
+	// AdminToken guards the admin API; admin routes are disabled when it is empty
+	AdminToken string
 }
 
 func Load() *Config {
 	ollamaHost := getEnv("OLLAMA_HOST", "http://localhost:11434")
+	ollamaModel := getEnv("OLLAMA_MODEL", "phi3:mini")
 	log.Printf("🔧 Config loaded - OLLAMA_HOST: %s", ollamaHost)
-	
+
 	return &Config{
-		Port:           getEnv("PORT", "8080"),
-		OllamaHost:     ollamaHost,
-		MaxConcurrency: 50,
-		RequestTimeout: 30,
+		Port:                  getEnv("PORT", "8080"),
+		OllamaHost:            ollamaHost,
+		OllamaModel:           ollamaModel,
+		MaxConcurrency:        50,
+		RequestTimeout:        30,
+		LLMContextWindow:      getEnvInt("LLM_CONTEXT_WINDOW", 0),
+		LLMConcurrency:        getEnvInt("LLM_CONCURRENCY", 4),
+		MaxChunksPerPage:      getEnvInt("MAX_CHUNKS_PER_PAGE", 8),
+		MatchStrategy:         getEnv("MATCH_STRATEGY", "llm"),
+		MinConfidence:         getEnvFloat("MIN_CONFIDENCE", 0.3),
+		CascadeAccept:         getEnvFloat("CASCADE_ACCEPT", 0.95),
+		CascadeReject:         getEnvFloat("CASCADE_REJECT", 0.2),
+		CascadeSmallModel:     getEnv("CASCADE_SMALL_MODEL", ollamaModel),
+		CascadeLargeModel:     getEnv("CASCADE_LARGE_MODEL", ""),
+		CascadeUncertainLow:   getEnvFloat("CASCADE_UNCERTAIN_LOW", 0.35),
+		CascadeUncertainHigh:  getEnvFloat("CASCADE_UNCERTAIN_HIGH", 0.65),
+		EmbeddingModel:        getEnv("EMBEDDING_MODEL", "nomic-embed-text"),
+		EmbeddingCacheSize:    getEnvInt("EMBEDDING_CACHE_SIZE", 5000),
+		QueryLLM:              getEnv("QUERY_LLM", "false") == "true",
+		PromptsDir:            getEnv("PROMPTS_DIR", ""),
+		PromptVersions:        getEnv("PROMPT_VERSIONS", ""),
+		BrandsFile:            getEnv("BRANDS_FILE", "data/brands.json"),
+		TaxonomyFile:          getEnv("TAXONOMY_FILE", ""),
+		IdentifierLookups:     getEnvInt("IDENTIFIER_LOOKUPS", 3),
+		FeedbackFile:          getEnv("FEEDBACK_FILE", "data/feedback.jsonl"),
+		FeedbackToken:         getEnv("FEEDBACK_TOKEN", ""),
+		FeedbackRateLimit:     getEnvInt("FEEDBACK_RATE_LIMIT", 30),
+		WeightsFile:           getEnv("WEIGHTS_FILE", "data/weights.json"),
+		CalibrationFile:       getEnv("CALIBRATION_FILE", "data/calibration.json"),
+		RatesSource:           getEnv("RATES_SOURCE", ""),
+		RatesTTL:              getEnvInt("RATES_TTL_MINUTES", 360),
+		TaxRulesFile:          getEnv("TAX_RULES_FILE", ""),
+		ShippingLookups:       getEnvInt("SHIPPING_LOOKUPS", 3),
+		SelectorRevisionsFile: getEnv("SELECTOR_REVISIONS_FILE", "data/selector_revisions.json"),
+		AdminToken:            getEnv("ADMIN_TOKEN", ""),
 	}
 }
 
@@ -29,4 +136,30 @@ func getEnv(key, defaultValue string) string {
 		return value
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("⚠️ Invalid integer for %s: %q, using default %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("⚠️ Invalid number for %s: %q, using default %g", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
@@ -0,0 +1,75 @@
+package evaluation
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Relevance grades used in the labelled dataset
+const (
+	Irrelevant = 0 // Unrelated product
+	Related    = 1 // Same family but not what was asked for: accessories, other variants
+	Exact      = 2 // The product the query asks for
+)
+
+// Example is one labelled (query, product title) pair
+type Example struct {
+	Query     string `json:"query"`
+	Title     string `json:"title"`
+	Relevance int    `json:"relevance"`
+	Category  string `json:"category,omitempty"`
+	Note      string `json:"note,omitempty"`
+}
+
+// LoadDataset reads a JSONL file of examples, skipping blank lines and lines starting with "#"
+func LoadDataset(path string) ([]Example, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var examples []Example
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		var example Example
+		if err := json.Unmarshal([]byte(text), &example); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		if example.Query == "" || example.Title == "" {
+			return nil, fmt.Errorf("%s:%d: query and title are required", path, line)
+		}
+		if example.Relevance < Irrelevant || example.Relevance > Exact {
+			return nil, fmt.Errorf("%s:%d: relevance must be between %d and %d", path, line, Irrelevant, Exact)
+		}
+		examples = append(examples, example)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return examples, nil
+}
+
+// DatasetHash identifies the contents of a dataset file, so a baseline can be tied to the examples it was
+// measured on
+func DatasetHash(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:16], nil
+}
@@ -0,0 +1,258 @@
+package evaluation
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Scored pairs an example with the score a matcher gave it
+type Scored struct {
+	Example
+	Score float64
+}
+
+// ThresholdPoint is precision and recall when results below Threshold are dropped
+type ThresholdPoint struct {
+	Threshold float64 `json:"threshold"`
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	F1        float64 `json:"f1"`
+}
+
+// CalibrationBin compares the mean score in a score range with how often those results were relevant
+type CalibrationBin struct {
+	Lower        float64 `json:"lower"`
+	Upper        float64 `json:"upper"`
+	Count        int     `json:"count"`
+	MeanScore    float64 `json:"meanScore"`
+	RelevantRate float64 `json:"relevantRate"`
+}
+
+// Report holds the metrics for one scorer over the dataset
+type Report struct {
+	Scorer      string           `json:"scorer"`
+	Examples    int              `json:"examples"`
+	Errors      int              `json:"errors"`
+	Threshold   float64          `json:"threshold"`
+	Precision   float64          `json:"precision"`
+	Recall      float64          `json:"recall"`
+	F1          float64          `json:"f1"`
+	NDCG        float64          `json:"ndcg"`
+	ECE         float64          `json:"ece"` // Expected calibration error, lower is better
+	Curve       []ThresholdPoint `json:"curve,omitempty"`
+	Calibration []CalibrationBin `json:"calibration,omitempty"`
+}
+
+const calibrationBins = 10
+
+// Evaluate computes metrics for scored examples. Results with relevance >= relevantMin count as relevant
+// for precision, recall and calibration; NDCG uses the graded relevance directly.
+func Evaluate(scorer string, scored []Scored, threshold float64, relevantMin int) Report {
+	report := Report{
+		Scorer:    scorer,
+		Examples:  len(scored),
+		Threshold: threshold,
+	}
+
+	point := thresholdPoint(scored, threshold, relevantMin)
+	report.Precision, report.Recall, report.F1 = point.Precision, point.Recall, point.F1
+
+	for t := 0; t <= 10; t++ {
+		report.Curve = append(report.Curve, thresholdPoint(scored, float64(t)/10, relevantMin))
+	}
+
+	report.NDCG = meanNDCG(scored)
+	report.Calibration, report.ECE = calibration(scored, relevantMin)
+
+	return report
+}
+
+func thresholdPoint(scored []Scored, threshold float64, relevantMin int) ThresholdPoint {
+	truePositives, predicted, relevant := 0, 0, 0
+	for _, s := range scored {
+		isRelevant := s.Relevance >= relevantMin
+		if isRelevant {
+			relevant++
+		}
+		if s.Score >= threshold {
+			predicted++
+			if isRelevant {
+				truePositives++
+			}
+		}
+	}
+
+	point := ThresholdPoint{Threshold: threshold}
+	if predicted > 0 {
+		point.Precision = float64(truePositives) / float64(predicted)
+	}
+	if relevant > 0 {
+		point.Recall = float64(truePositives) / float64(relevant)
+	}
+	if point.Precision+point.Recall > 0 {
+		point.F1 = 2 * point.Precision * point.Recall / (point.Precision + point.Recall)
+	}
+	return point
+}
+
+// meanNDCG ranks each query's titles by score and averages NDCG over queries with at least one relevant title
+func meanNDCG(scored []Scored) float64 {
+	byQuery := make(map[string][]Scored)
+	for _, s := range scored {
+		byQuery[s.Query] = append(byQuery[s.Query], s)
+	}
+
+	total, queries := 0.0, 0
+	for _, group := range byQuery {
+		ranked := append([]Scored(nil), group...)
+		sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].Score > ranked[j].Score })
+
+		ideal := append([]Scored(nil), group...)
+		sort.SliceStable(ideal, func(i, j int) bool { return ideal[i].Relevance > ideal[j].Relevance })
+
+		idcg := dcg(ideal)
+		if idcg == 0 {
+			continue
+		}
+		total += dcg(ranked) / idcg
+		queries++
+	}
+
+	if queries == 0 {
+		return 0
+	}
+	return total / float64(queries)
+}
+
+func dcg(ranked []Scored) float64 {
+	sum := 0.0
+	for i, s := range ranked {
+		gain := math.Pow(2, float64(s.Relevance)) - 1
+		sum += gain / math.Log2(float64(i+2))
+	}
+	return sum
+}
+
+func calibration(scored []Scored, relevantMin int) ([]CalibrationBin, float64) {
+	bins := make([]CalibrationBin, calibrationBins)
+	for i := range bins {
+		bins[i].Lower = float64(i) / calibrationBins
+		bins[i].Upper = float64(i+1) / calibrationBins
+	}
+
+	for _, s := range scored {
+		idx := int(s.Score * calibrationBins)
+		if idx >= calibrationBins {
+			idx = calibrationBins - 1
+		} else if idx < 0 {
+			idx = 0
+		}
+		bins[idx].Count++
+		bins[idx].MeanScore += s.Score
+		if s.Relevance >= relevantMin {
+			bins[idx].RelevantRate++
+		}
+	}
+
+	ece := 0.0
+	var populated []CalibrationBin
+	for _, bin := range bins {
+		if bin.Count == 0 {
+			continue
+		}
+		bin.MeanScore /= float64(bin.Count)
+		bin.RelevantRate /= float64(bin.Count)
+		ece += float64(bin.Count) / float64(len(scored)) * math.Abs(bin.MeanScore-bin.RelevantRate)
+		populated = append(populated, bin)
+	}
+
+	return populated, ece
+}
+
+// BaselineEntry is a scorer's accepted metrics, the dataset they were measured on and why they were accepted
+type BaselineEntry struct {
+	Report
+	Dataset string `json:"dataset"` // DatasetHash of the dataset the metrics were measured on
+	Reason  string `json:"reason"`
+}
+
+// Baseline stores accepted metrics per scorer
+type Baseline map[string]BaselineEntry
+
+// LoadBaseline reads a baseline file written by SaveBaseline
+func LoadBaseline(path string) (Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var baseline Baseline
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return nil, fmt.Errorf("parse baseline %s: %v", path, err)
+	}
+	return baseline, nil
+}
+
+// SaveBaseline records the headline metrics of each report, omitting curves and bins, with the dataset they
+// were measured on and the reason for accepting them. Scorers not in reports keep their entries in the file.
+func SaveBaseline(path string, reports []Report, dataset, reason string) error {
+	if strings.TrimSpace(reason) == "" {
+		return fmt.Errorf("a reason is required to change the baseline")
+	}
+	baseline, err := LoadBaseline(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+		baseline = make(Baseline)
+	}
+	for _, report := range reports {
+		report.Curve = nil
+		report.Calibration = nil
+		baseline[report.Scorer] = BaselineEntry{Report: report, Dataset: dataset, Reason: reason}
+	}
+	data, err := json.MarshalIndent(baseline, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Regressions lists every metric that is worse than the baseline by more than tolerance. A scorer without a
+// baseline, or whose baseline was measured on another dataset or threshold, is a regression too: its metrics
+// cannot be compared.
+func (b Baseline) Regressions(report Report, dataset string, tolerance float64) []string {
+	base, exists := b[report.Scorer]
+	switch {
+	case !exists:
+		return []string{fmt.Sprintf("%s has no baseline", report.Scorer)}
+	case base.Dataset != dataset:
+		return []string{fmt.Sprintf("%s baseline was measured on dataset %s, not %s", report.Scorer, base.Dataset, dataset)}
+	case base.Threshold != report.Threshold:
+		return []string{fmt.Sprintf("%s baseline was measured at threshold %.2f, not %.2f", report.Scorer, base.Threshold, report.Threshold)}
+	}
+
+	var regressions []string
+	higherIsBetter := []struct {
+		name              string
+		current, accepted float64
+	}{
+		{"precision", report.Precision, base.Precision},
+		{"recall", report.Recall, base.Recall},
+		{"f1", report.F1, base.F1},
+		{"ndcg", report.NDCG, base.NDCG},
+	}
+	for _, m := range higherIsBetter {
+		if m.current < m.accepted-tolerance {
+			regressions = append(regressions, fmt.Sprintf("%s %s dropped from %.3f to %.3f", report.Scorer, m.name, m.accepted, m.current))
+		}
+	}
+	if report.ECE > base.ECE+tolerance {
+		regressions = append(regressions, fmt.Sprintf("%s ece rose from %.3f to %.3f", report.Scorer, base.ECE, report.ECE))
+	}
+
+	return regressions
+}
@@ -0,0 +1,91 @@
+package evaluation
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRegressions(t *testing.T) {
+	baseline := Baseline{
+		"fuzzy": {Report: Report{Scorer: "fuzzy", Threshold: 0.3, Precision: 0.8, Recall: 0.9, F1: 0.85, NDCG: 0.95, ECE: 0.1}, Dataset: "abc", Reason: "test"},
+	}
+
+	tests := []struct {
+		name    string
+		report  Report
+		dataset string
+		want    []string
+	}{
+		{"unchanged", Report{Scorer: "fuzzy", Threshold: 0.3, Precision: 0.8, Recall: 0.9, F1: 0.85, NDCG: 0.95, ECE: 0.1}, "abc", nil},
+		{"within tolerance", Report{Scorer: "fuzzy", Threshold: 0.3, Precision: 0.79, Recall: 0.9, F1: 0.85, NDCG: 0.95, ECE: 0.11}, "abc", nil},
+		{"precision dropped", Report{Scorer: "fuzzy", Threshold: 0.3, Precision: 0.7, Recall: 0.9, F1: 0.85, NDCG: 0.95, ECE: 0.1}, "abc", []string{"precision dropped"}},
+		{"ece rose", Report{Scorer: "fuzzy", Threshold: 0.3, Precision: 0.8, Recall: 0.9, F1: 0.85, NDCG: 0.95, ECE: 0.2}, "abc", []string{"ece rose"}},
+		{"no baseline", Report{Scorer: "llm", Threshold: 0.3}, "abc", []string{"no baseline"}},
+		{"other dataset", Report{Scorer: "fuzzy", Threshold: 0.3, Precision: 1, Recall: 1, F1: 1, NDCG: 1}, "def", []string{"measured on dataset abc"}},
+		{"other threshold", Report{Scorer: "fuzzy", Threshold: 0.5, Precision: 1, Recall: 1, F1: 1, NDCG: 1}, "abc", []string{"threshold"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := baseline.Regressions(test.report, test.dataset, 0.02)
+			if len(got) != len(test.want) {
+				t.Fatalf("Regressions() = %q, want %d matching %q", got, len(test.want), test.want)
+			}
+			for i, want := range test.want {
+				if !strings.Contains(got[i], want) {
+					t.Errorf("Regressions()[%d] = %q, want it to mention %q", i, got[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestSaveBaselineRequiresReasonAndKeepsOtherScorers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	if err := SaveBaseline(path, []Report{{Scorer: "llm", Precision: 0.9}}, "abc", ""); err == nil {
+		t.Fatal("SaveBaseline() without a reason succeeded")
+	}
+	if err := SaveBaseline(path, []Report{{Scorer: "llm", Precision: 0.9}}, "abc", "first"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveBaseline(path, []Report{{Scorer: "fuzzy", Precision: 0.8}}, "def", "second"); err != nil {
+		t.Fatal(err)
+	}
+
+	baseline, err := LoadBaseline(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if llm := baseline["llm"]; llm.Precision != 0.9 || llm.Dataset != "abc" || llm.Reason != "first" {
+		t.Errorf("llm entry = %+v, want it kept from the first save", llm)
+	}
+	if fuzzy := baseline["fuzzy"]; fuzzy.Dataset != "def" || fuzzy.Reason != "second" {
+		t.Errorf("fuzzy entry = %+v, want the second save", fuzzy)
+	}
+}
+
+// The checked-in baseline must be measured on the checked-in dataset: changing the dataset means rebaselining,
+// with a reason, in its own commit
+func TestBaselinePinnedToDataset(t *testing.T) {
+	dataset, err := DatasetHash("../../eval/dataset.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseline, err := LoadBaseline("../../eval/baseline.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, scorer := range []string{"fuzzy", "basic"} {
+		entry, exists := baseline[scorer]
+		if !exists {
+			t.Errorf("baseline has no %s entry", scorer)
+			continue
+		}
+		if entry.Dataset != dataset {
+			t.Errorf("%s baseline measured on dataset %s, the dataset is now %s", scorer, entry.Dataset, dataset)
+		}
+		if strings.TrimSpace(entry.Reason) == "" {
+			t.Errorf("%s baseline has no reason", scorer)
+		}
+	}
+}
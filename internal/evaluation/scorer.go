@@ -0,0 +1,54 @@
+package evaluation
+
+import (
+	"context"
+	"fmt"
+	"price-comparison-tool/internal/llm"
+	"price-comparison-tool/internal/matcher"
+	"price-comparison-tool/internal/models"
+	"time"
+)
+
+// ScorerFor scores with the named matching strategy, as a search selecting it would, and names each score's
+// confidence source (matcher.CalibrationSource). The titles labelled for a query stand in for that search's
+// results, for strategies that weigh titles against each other.
+func ScorerFor(service *matcher.Service, name string, examples []Example) (func(query, title string) (float64, string, error), error) {
+	if name == "" || !matcher.IsValidStrategy(name) {
+		return nil, fmt.Errorf("unknown scorer %q", name)
+	}
+
+	results := make(map[string][]models.ProductResult)
+	for _, example := range examples {
+		results[example.Query] = append(results[example.Query], models.ProductResult{ProductName: example.Title})
+	}
+	strategies := make(map[string]matcher.Matcher, len(results))
+	for query, products := range results {
+		strategies[query] = matcher.ForResults(service.Strategy(name), products)
+	}
+
+	return func(query, title string) (float64, string, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+		explanation, err := strategies[query].Match(ctx, service.ParseQueryRules(query), models.ProductResult{ProductName: title})
+		return explanation.Score, matcher.CalibrationSource(name, explanation), err
+	}, nil
+}
+
+// Available checks that a scorer can run here. The model-backed strategies need Ollama; without it every example
+// would fail, or the cascade would quietly measure its fuzzy fallback, so they are reported unavailable instead.
+func Available(service *matcher.Service, name string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	switch name {
+	case matcher.StrategyLLM, matcher.StrategyCascade:
+		if _, err := service.CallOllama(ctx, llm.PriorityBackground, "Reply with OK."); err != nil {
+			return fmt.Errorf("Ollama unreachable: %v", err)
+		}
+	case matcher.StrategyEmbedding:
+		if _, err := service.Embed(ctx, "probe"); err != nil {
+			return fmt.Errorf("embeddings unavailable: %v", err)
+		}
+	}
+	return nil
+}
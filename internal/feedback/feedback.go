@@ -0,0 +1,134 @@
+// Package feedback records users' relevance judgements on search results, each with the match features that
+// produced its score, so scoring weights can be fitted to them offline.
+package feedback
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"price-comparison-tool/internal/models"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Feedback labels
+const (
+	LabelRelevant   = "relevant"
+	LabelIrrelevant = "irrelevant"
+	LabelCorrect    = "correct" // The result picked as the right match among those shown
+)
+
+// IsValidLabel reports whether label can be submitted
+func IsValidLabel(label string) bool {
+	return label == LabelRelevant || label == LabelIrrelevant || label == LabelCorrect
+}
+
+// Entry is one judged result
+type Entry struct {
+	ID            string               `json:"id"`
+	Query         string               `json:"query"`
+	Country       string               `json:"country,omitempty"`
+	ProductName   string               `json:"productName"`
+	Site          string               `json:"site,omitempty"`
+	Link          string               `json:"link,omitempty"`
+	Label         string               `json:"label"`
+	Confidence    float64              `json:"confidence,omitempty"`    // Confidence the user was shown
+	RawConfidence float64              `json:"rawConfidence,omitempty"` // The same before calibration
+	Source        string               `json:"source,omitempty"`        // Strategy, extraction or css-fallback behind the confidence
+	Scorer        string               `json:"scorer,omitempty"`        // Scorer that produced it, when the result was explained
+	Features      models.MatchFeatures `json:"features"`
+	CreatedAt     time.Time            `json:"createdAt"`
+}
+
+// Relevant reports whether the entry is a positive example
+func (e Entry) Relevant() bool {
+	return e.Label == LabelRelevant || e.Label == LabelCorrect
+}
+
+// Store appends entries to a JSON lines file
+type Store struct {
+	mutex sync.Mutex
+	path  string
+}
+
+// NewStore appends to path; an empty path keeps nothing
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// Append assigns each entry an ID and timestamp and writes them to the file
+func (s *Store) Append(entries []Entry) ([]Entry, error) {
+	var lines []byte
+	for i := range entries {
+		entries[i].ID = newID()
+		entries[i].CreatedAt = time.Now()
+		line, err := json.Marshal(entries[i])
+		if err != nil {
+			return nil, err
+		}
+		lines = append(append(lines, line...), '\n')
+	}
+	if s.path == "" {
+		return entries, nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if _, err := file.Write(lines); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Load reads every entry from a feedback file, skipping blank lines
+func Load(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var entry Entry
+		if err := json.Unmarshal([]byte(text), &entry); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		if !IsValidLabel(entry.Label) {
+			return nil, fmt.Errorf("%s:%d: unknown label %q", path, line, entry.Label)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,279 @@
+// Package identifiers reads product identifiers (GTIN/EAN/UPC barcodes, Amazon ASINs, manufacturer part numbers
+// and retailer ids) from offer links, JSON-LD structured data and product detail pages, and compares them.
+package identifiers
+
+import (
+	"encoding/json"
+	"price-comparison-tool/internal/models"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Identifier kinds reported by Match
+const (
+	KindGTIN          = "gtin"
+	KindASIN          = "asin"
+	KindSiteProductID = "siteProductId"
+	KindMPN           = "mpn"
+)
+
+var (
+	asinLinkPattern      = regexp.MustCompile(`/(?:dp|gp/product|gp/aw/d)/([A-Z0-9]{10})(?:[/?#]|$)`)
+	flipkartLinkPattern  = regexp.MustCompile(`[?&]pid=([A-Z0-9]{8,20})(?:&|$)`)
+	gtinTextPattern      = regexp.MustCompile(`(?i)\b(?:gtin|ean|upc|barcode)(?:[- ]?(?:8|12|13|14))?(?:\s+(?:code|number|no\.?))?\s*[:#]?\s*(\d{8,14})\b`)
+	asinTextPattern      = regexp.MustCompile(`\bASIN\s*[:#]?\s*([A-Z0-9]{10})\b`)
+	mpnTextPattern       = regexp.MustCompile(`(?i)\b(?:mpn|manufacturer part number|part number|item model number|model number)\s*[:#]?\s*([A-Z0-9][A-Z0-9\-./]{2,30})`)
+	invisibleMarkPattern = regexp.MustCompile("[\u200e\u200f\u202a-\u202e]")
+)
+
+// gtinKeys are the schema.org properties holding a barcode, most specific first
+var gtinKeys = []string{"gtin13", "gtin12", "gtin14", "gtin8", "gtin", "ean", "upc"}
+
+// Listing is a product described in a page's structured data, with what is needed to pair it with an offer
+type Listing struct {
+	Name        string
+	URL         string
+	Condition   string // schema.org itemCondition as published, e.g. "https://schema.org/UsedCondition"
+	Shipping    string // The offer's shippingRate as amount and currency, e.g. "5.99 USD"; "" when not published
+	Identifiers models.Identifiers
+}
+
+// ValidGTIN reports whether code is an 8, 12, 13 or 14 digit barcode with a correct check digit
+func ValidGTIN(code string) bool {
+	switch len(code) {
+	case 8, 12, 13, 14:
+	default:
+		return false
+	}
+
+	sum := 0
+	for i := len(code) - 2; i >= 0; i-- {
+		digit := int(code[i] - '0')
+		if digit < 0 || digit > 9 {
+			return false
+		}
+		// Weights alternate 3, 1, 3, ... from the digit left of the check digit
+		if (len(code)-2-i)%2 == 0 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	check := int(code[len(code)-1] - '0')
+	return check >= 0 && check <= 9 && (10-sum%10)%10 == check
+}
+
+// NormalizeGTIN strips spaces and dashes from a barcode and pads it to GTIN-14, so a UPC-A and the
+// EAN-13 with a leading zero compare equal. It returns "" for anything that is not a valid barcode.
+func NormalizeGTIN(code string) string {
+	code = strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(code))
+	if !ValidGTIN(code) {
+		return ""
+	}
+	return strings.Repeat("0", 14-len(code)) + code
+}
+
+// Match returns the kind of identifier two offers share, or "" when none is known on both. An MPN alone is
+// only unique within a brand, so callers comparing offers of different brands should not rely on it.
+func Match(a, b models.Identifiers) string {
+	if gtin := NormalizeGTIN(a.GTIN); gtin != "" && gtin == NormalizeGTIN(b.GTIN) {
+		return KindGTIN
+	}
+	if a.ASIN != "" && strings.EqualFold(a.ASIN, b.ASIN) {
+		return KindASIN
+	}
+	if a.SiteProductID != "" && a.SiteProductID == b.SiteProductID {
+		return KindSiteProductID
+	}
+	if a.MPN != "" && strings.EqualFold(a.MPN, b.MPN) {
+		return KindMPN
+	}
+	return ""
+}
+
+// Conflict reports whether both offers carry a barcode and the barcodes differ
+func Conflict(a, b models.Identifiers) bool {
+	gtinA, gtinB := NormalizeGTIN(a.GTIN), NormalizeGTIN(b.GTIN)
+	return gtinA != "" && gtinB != "" && gtinA != gtinB
+}
+
+// Merge fills the identifiers missing from ids with those in other
+func Merge(ids, other models.Identifiers) models.Identifiers {
+	if ids.GTIN == "" {
+		ids.GTIN = other.GTIN
+	}
+	if ids.ASIN == "" {
+		ids.ASIN = other.ASIN
+	}
+	if ids.MPN == "" {
+		ids.MPN = other.MPN
+	}
+	if ids.SiteProductID == "" {
+		ids.SiteProductID = other.SiteProductID
+	}
+	return ids
+}
+
+// FromLink reads the identifiers encoded in an offer URL: an Amazon ASIN or a Flipkart product id
+func FromLink(link string) models.Identifiers {
+	var ids models.Identifiers
+	if match := asinLinkPattern.FindStringSubmatch(link); match != nil {
+		ids.ASIN = match[1]
+	}
+	if match := flipkartLinkPattern.FindStringSubmatch(link); match != nil {
+		ids.SiteProductID = match[1]
+	}
+	return ids
+}
+
+// FromStructuredData returns every product described by the page's JSON-LD blocks, including the items
+// of an ItemList on a search results page
+func FromStructuredData(doc *goquery.Document) []Listing {
+	var listings []Listing
+	doc.Find(`script[type="application/ld+json"]`).Each(func(_ int, script *goquery.Selection) {
+		var data interface{}
+		if err := json.Unmarshal([]byte(script.Text()), &data); err != nil {
+			return
+		}
+		collectListings(data, &listings)
+	})
+	return listings
+}
+
+// FromDetailPage reads a product detail page: JSON-LD first, then microdata, then labelled text such as
+// "EAN: 8901030865237" or "ASIN : B0DGJ65N8K" in specification tables
+func FromDetailPage(doc *goquery.Document) models.Identifiers {
+	var ids models.Identifiers
+	if listings := FromStructuredData(doc); len(listings) > 0 {
+		ids = listings[0].Identifiers
+	}
+
+	var microdata models.Identifiers
+	for _, key := range gtinKeys {
+		if value := itempropValue(doc, key); microdata.GTIN == "" && ValidGTIN(value) {
+			microdata.GTIN = value
+		}
+	}
+	microdata.MPN = itempropValue(doc, "mpn")
+	ids = Merge(ids, microdata)
+
+	return Merge(ids, FromText(doc.Find("body").Text()))
+}
+
+// FromText finds labelled identifiers in free text; barcodes are only accepted with a valid check digit
+func FromText(text string) models.Identifiers {
+	text = invisibleMarkPattern.ReplaceAllString(text, "")
+
+	var ids models.Identifiers
+	for _, match := range gtinTextPattern.FindAllStringSubmatch(text, -1) {
+		if ValidGTIN(match[1]) {
+			ids.GTIN = match[1]
+			break
+		}
+	}
+	if match := asinTextPattern.FindStringSubmatch(text); match != nil {
+		ids.ASIN = match[1]
+	}
+	if match := mpnTextPattern.FindStringSubmatch(text); match != nil {
+		ids.MPN = strings.TrimRight(match[1], ".-/")
+	}
+	return ids
+}
+
+// collectListings walks decoded JSON-LD, appending each object typed Product
+func collectListings(node interface{}, listings *[]Listing) {
+	switch value := node.(type) {
+	case []interface{}:
+		for _, item := range value {
+			collectListings(item, listings)
+		}
+	case map[string]interface{}:
+		if hasType(value["@type"], "Product") {
+			*listings = append(*listings, listingFrom(value))
+		}
+		for key, child := range value {
+			if key != "@type" && key != "@context" {
+				collectListings(child, listings)
+			}
+		}
+	}
+}
+
+func listingFrom(product map[string]interface{}) Listing {
+	listing := Listing{Name: stringValue(product["name"]), URL: stringValue(product["url"])}
+	listing.Condition = stringValue(product["itemCondition"])
+	if offers, ok := product["offers"].(map[string]interface{}); ok {
+		if listing.URL == "" {
+			listing.URL = stringValue(offers["url"])
+		}
+		if listing.Condition == "" {
+			listing.Condition = stringValue(offers["itemCondition"])
+		}
+		listing.Shipping = shippingRate(offers["shippingDetails"])
+	}
+
+	for _, key := range gtinKeys {
+		if code := stringValue(product[key]); ValidGTIN(code) {
+			listing.Identifiers.GTIN = code
+			break
+		}
+	}
+	listing.Identifiers.MPN = stringValue(product["mpn"])
+	listing.Identifiers = Merge(listing.Identifiers, FromLink(listing.URL))
+	return listing
+}
+
+// shippingRate reads the first shippingRate of an offer's shippingDetails, which may be one object or a list
+func shippingRate(node interface{}) string {
+	if list, ok := node.([]interface{}); ok && len(list) > 0 {
+		node = list[0]
+	}
+	details, ok := node.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	rate, ok := details["shippingRate"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	value := stringValue(rate["value"])
+	if value == "" {
+		return ""
+	}
+	return strings.TrimSpace(value + " " + stringValue(rate["currency"]))
+}
+
+func hasType(node interface{}, want string) bool {
+	switch value := node.(type) {
+	case string:
+		return value == want
+	case []interface{}:
+		for _, item := range value {
+			if item == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// stringValue reads a JSON-LD value that may be a string or a number, as barcodes sometimes are
+func stringValue(node interface{}) string {
+	switch value := node.(type) {
+	case string:
+		return strings.TrimSpace(value)
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return ""
+}
+
+func itempropValue(doc *goquery.Document, prop string) string {
+	selection := doc.Find(`[itemprop="` + prop + `"]`).First()
+	if content, exists := selection.Attr("content"); exists {
+		return strings.TrimSpace(content)
+	}
+	return strings.TrimSpace(selection.Text())
+}
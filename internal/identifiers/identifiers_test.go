@@ -0,0 +1,78 @@
+package identifiers
+
+import (
+	"strings"
+	"testing"
+
+	"price-comparison-tool/internal/models"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestValidGTIN(t *testing.T) {
+	tests := map[string]bool{
+		"8901030865237":  true,  // EAN-13
+		"036000291452":   true,  // UPC-A
+		"96385074":       true,  // EAN-8
+		"00036000291452": true,  // GTIN-14
+		"8901030865238":  false, // Wrong check digit
+		"036000291453":   false,
+		"890103086523":   false, // Check digit of the 13-digit code on 12 digits
+		"890103086523x":  false,
+		"1234567":        false,
+		"":               false,
+	}
+	for code, want := range tests {
+		if got := ValidGTIN(code); got != want {
+			t.Errorf("ValidGTIN(%q) = %v, want %v", code, got, want)
+		}
+	}
+}
+
+func TestMatchComparesBarcodesAcrossLengths(t *testing.T) {
+	upc := models.Identifiers{GTIN: "036000291452"}
+	ean := models.Identifiers{GTIN: "0036000291452"}
+	if got := Match(upc, ean); got != KindGTIN {
+		t.Errorf("Match(UPC-A, EAN-13 with a leading zero) = %q, want %q", got, KindGTIN)
+	}
+	if Conflict(upc, ean) {
+		t.Error("Conflict(UPC-A, same EAN-13) = true, want false")
+	}
+	if !Conflict(upc, models.Identifiers{GTIN: "8901030865237"}) {
+		t.Error("Conflict(different barcodes) = false, want true")
+	}
+	if Conflict(upc, models.Identifiers{GTIN: "8901030865238"}) {
+		t.Error("Conflict() = true for an invalid barcode, want only valid barcodes compared")
+	}
+}
+
+func TestFromLink(t *testing.T) {
+	tests := map[string]models.Identifiers{
+		"https://www.amazon.in/Apple-iPhone-16/dp/B0DGJ65N8K/ref=sr_1_1":                   {ASIN: "B0DGJ65N8K"},
+		"https://www.flipkart.com/apple-iphone-16/p/itm7c0281cd247be?pid=MOBH4DQFG8NKFRDY": {SiteProductID: "MOBH4DQFG8NKFRDY"},
+		"https://www.example.com/product/123":                                              {},
+	}
+	for link, want := range tests {
+		if got := FromLink(link); got != want {
+			t.Errorf("FromLink(%q) = %+v, want %+v", link, got, want)
+		}
+	}
+}
+
+func TestFromDetailPage(t *testing.T) {
+	page := `<html><head><script type="application/ld+json">
+		{"@context": "https://schema.org", "@type": "Product", "name": "Nescafe Classic 200g", "mpn": "NC200"}
+	</script></head><body><table>
+		<tr><td>EAN</td><td>: 8901030865238</td></tr>
+		<tr><td>EAN</td><td>: 8901030865237</td></tr>
+		<tr><td>ASIN</td><td>: B0DGJ65N8K</td></tr>
+	</table></body></html>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := models.Identifiers{GTIN: "8901030865237", ASIN: "B0DGJ65N8K", MPN: "NC200"}
+	if got := FromDetailPage(doc); got != want {
+		t.Errorf("FromDetailPage() = %+v, want %+v: the barcode with a bad check digit skipped", got, want)
+	}
+}
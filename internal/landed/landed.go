@@ -0,0 +1,167 @@
+// Package landed estimates what an offer costs the shopper once it arrives: the price, the delivery charge, and
+// any sales tax the price leaves out. US and Canadian prices exclude tax, which depends on the state or province;
+// UK, EU, Indian, Japanese and Australian prices include it. The embedded tax rules can be extended or
+// overridden with a JSON file of the same shape.
+package landed
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"price-comparison-tool/internal/models"
+	"price-comparison-tool/internal/money"
+	"strings"
+)
+
+//go:embed taxrules.json
+var embeddedRules []byte
+
+// Rule is how one country taxes retail prices
+type Rule struct {
+	Country  string   `json:"country"`
+	Tax      string   `json:"tax"`               // Name shown with estimates, e.g. "VAT" or "sales tax"
+	Included bool     `json:"included"`          // Listed prices include the tax
+	Rate     float64  `json:"rate"`              // National or typical rate, used when no region matches
+	Regions  []Region `json:"regions,omitempty"` // States or provinces with their own rate
+}
+
+// Region is a state or province rate and the postal codes it covers. A postal entry is a prefix ("055", "K") or
+// a range of prefixes of one length ("900-961").
+type Region struct {
+	Code   string   `json:"code"`
+	Rate   float64  `json:"rate"`
+	Postal []string `json:"postal"`
+}
+
+// Rules are the tax rules of every known country
+type Rules struct {
+	Updated   string `json:"updated"` // When the rates were last checked
+	Countries []Rule `json:"countries"`
+}
+
+// Load reads the embedded rules, then applies the rules in path (if set), which replace embedded rules for the
+// same country or add new ones
+func Load(path string) (Rules, error) {
+	var rules Rules
+	if err := json.Unmarshal(embeddedRules, &rules); err != nil {
+		return Rules{}, fmt.Errorf("embedded tax rules: %v", err)
+	}
+	if path == "" {
+		return rules, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return rules, err
+	}
+	var overrides Rules
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return rules, fmt.Errorf("parse %s: %v", path, err)
+	}
+	for _, override := range overrides.Countries {
+		replaced := false
+		for i := range rules.Countries {
+			if rules.Countries[i].Country == override.Country {
+				rules.Countries[i] = override
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			rules.Countries = append(rules.Countries, override)
+		}
+	}
+	if overrides.Updated != "" {
+		rules.Updated = overrides.Updated
+	}
+	return rules, nil
+}
+
+// For returns the rule for a country
+func (r Rules) For(country string) (Rule, bool) {
+	for _, rule := range r.Countries {
+		if rule.Country == country {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+// RateFor is the tax rate at a postal code and the region it falls in; without a matching region it is the
+// national rate and regional is false
+func (r Rule) RateFor(postalCode string) (rate float64, region string, regional bool) {
+	postalCode = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(postalCode), " ", ""))
+	if postalCode != "" {
+		for _, candidate := range r.Regions {
+			for _, postal := range candidate.Postal {
+				if postalMatches(postalCode, postal) {
+					return candidate.Rate, candidate.Code, true
+				}
+			}
+		}
+	}
+	return r.Rate, "", false
+}
+
+func postalMatches(postalCode, postal string) bool {
+	low, high, isRange := strings.Cut(postal, "-")
+	if !isRange {
+		return strings.HasPrefix(postalCode, postal)
+	}
+	if len(postalCode) < len(low) {
+		return false
+	}
+	prefix := postalCode[:len(low)]
+	return prefix >= low && prefix <= high
+}
+
+// Estimate totals an offer for a shopper in country. A price without a tax label follows the country's rule, and
+// tax is charged on the price alone. Shipping that is not shown counts as free; what the estimate assumed is
+// listed with it.
+func (r Rules) Estimate(product models.ProductResult, country, postalCode string) (models.LandedCost, error) {
+	price, err := product.Amount()
+	if err != nil {
+		return models.LandedCost{}, err
+	}
+	cost := models.LandedCost{
+		Price:    price,
+		Shipping: money.Money{Currency: price.Currency},
+		Tax:      money.Money{Currency: price.Currency},
+	}
+
+	switch {
+	case product.Shipping == nil:
+		cost.Assumptions = append(cost.Assumptions, "shipping not shown, counted as free")
+	case product.Shipping.Currency != price.Currency:
+		cost.Assumptions = append(cost.Assumptions, fmt.Sprintf("shipping in %s left out", product.Shipping.Currency))
+	default:
+		cost.Shipping = *product.Shipping
+	}
+
+	rule, known := r.For(country)
+	if !known {
+		cost.Assumptions = append(cost.Assumptions, "no tax rule for "+country+", tax counted as included")
+	} else {
+		rate, region, regional := rule.RateFor(postalCode)
+		cost.TaxName, cost.TaxRate, cost.TaxRegion = rule.Tax, rate, region
+		if len(rule.Regions) > 0 && !regional {
+			if strings.TrimSpace(postalCode) == "" {
+				cost.Assumptions = append(cost.Assumptions, "no postal code, using the national rate")
+			} else {
+				cost.Assumptions = append(cost.Assumptions, "postal code "+postalCode+" not recognised, using the national rate")
+			}
+		}
+		included := rule.Included
+		if product.TaxIncluded != nil {
+			included = *product.TaxIncluded
+		}
+		if !included {
+			cost.Tax.Minor = int64(math.Round(float64(price.Minor) * rate))
+		}
+	}
+
+	cost.Total = money.Money{Minor: price.Minor + cost.Shipping.Minor + cost.Tax.Minor, Currency: price.Currency}
+	return cost, nil
+}
@@ -0,0 +1,114 @@
+package landed
+
+import (
+	"strings"
+	"testing"
+
+	"price-comparison-tool/internal/models"
+	"price-comparison-tool/internal/money"
+)
+
+// offer is a US dollar result at price with the given delivery charge
+func offer(t *testing.T, price string, shipping *money.Money) models.ProductResult {
+	t.Helper()
+	amount, err := money.ParseIn(price, "US", "USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return models.ProductResult{Price: amount.Decimal(), Currency: amount.Currency, PriceValue: &amount, Shipping: shipping}
+}
+
+func TestEstimate(t *testing.T) {
+	rules, err := Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shipping := money.FromFloat(5.99, "USD")
+
+	tests := []struct {
+		name                string
+		product             models.ProductResult
+		country, postalCode string
+		tax, total          string
+		region              string
+		assumption          string // Substring of an expected assumption, "" for none
+	}{
+		{"California sales tax on the price alone", offer(t, "100.00", &shipping), "US", "94105", "8.85", "114.84", "CA", ""},
+		{"national rate without a postal code", offer(t, "100.00", &shipping), "US", "", "7.00", "112.99", "", "no postal code"},
+		{"unknown postal code", offer(t, "100.00", &shipping), "US", "ZZZ", "7.00", "112.99", "", "not recognised"},
+		{"Ontario by postal letter", offer(t, "100.00", nil), "CA", "M5V 2T6", "13.00", "113.00", "ON", "shipping not shown"},
+		{"VAT included in UK prices", offer(t, "100.00", &shipping), "UK", "", "0.00", "105.99", "", ""},
+		{"no rule for the country", offer(t, "100.00", &shipping), "BR", "", "0.00", "105.99", "", "no tax rule for BR"},
+	}
+	for _, test := range tests {
+		cost, err := rules.Estimate(test.product, test.country, test.postalCode)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if cost.Tax.Decimal() != test.tax || cost.Total.Decimal() != test.total || cost.TaxRegion != test.region {
+			t.Errorf("%s: tax %s, total %s, region %q; want %s, %s, %q",
+				test.name, cost.Tax.Decimal(), cost.Total.Decimal(), cost.TaxRegion, test.tax, test.total, test.region)
+		}
+		assumptions := strings.Join(cost.Assumptions, "; ")
+		if (test.assumption == "") != (assumptions == "") || !strings.Contains(assumptions, test.assumption) {
+			t.Errorf("%s: assumptions %q, want %q", test.name, assumptions, test.assumption)
+		}
+	}
+}
+
+func TestEstimateFollowsTaxLabel(t *testing.T) {
+	rules, err := Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	product := offer(t, "100.00", nil)
+	included := true
+	product.TaxIncluded = &included
+
+	cost, err := rules.Estimate(product, "US", "94105")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cost.Tax.Minor != 0 {
+		t.Errorf("tax %s on a price labelled tax-inclusive, want none", cost.Tax.Decimal())
+	}
+}
+
+func TestParseShipping(t *testing.T) {
+	tests := []struct {
+		text, country, currency string
+		want                    string // Decimal and currency, "" when not read
+	}{
+		{"+$5.99 shipping", "US", "USD", "5.99 USD"},
+		{"+5,99 € Versand", "DE", "EUR", "5.99 EUR"},
+		{"FREE delivery", "US", "USD", "0.00 USD"},
+		{"送料無料", "JP", "JPY", "0 JPY"},
+		{"Delivery Tue, Oct 21", "US", "USD", ""},
+		{"", "US", "USD", ""},
+	}
+	for _, test := range tests {
+		got := ""
+		if amount, ok := ParseShipping(test.text, test.country, test.currency); ok {
+			got = amount.Decimal() + " " + amount.Currency
+		}
+		if got != test.want {
+			t.Errorf("ParseShipping(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestTaxLabel(t *testing.T) {
+	tests := map[string][2]bool{ // included, labelled
+		"incl. VAT":           {true, true},
+		"inkl. MwSt.":         {true, true},
+		"税込":                  {true, true},
+		"+ tax":               {false, true},
+		"zzgl. MwSt.":         {false, true},
+		"Delivery by Tuesday": {false, false},
+	}
+	for text, want := range tests {
+		if included, labelled := TaxLabel(text); included != want[0] || labelled != want[1] {
+			t.Errorf("TaxLabel(%q) = %v, %v; want %v, %v", text, included, labelled, want[0], want[1])
+		}
+	}
+}
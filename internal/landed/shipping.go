@@ -0,0 +1,52 @@
+package landed
+
+import (
+	"price-comparison-tool/internal/money"
+	"regexp"
+	"strings"
+)
+
+// freeShippingPattern finds free delivery in the languages of the supported sites: "Free shipping",
+// "FREE delivery", "GRATIS-Lieferung", "Livraison GRATUITE", "送料無料"
+var freeShippingPattern = regexp.MustCompile(`(?i)\b(?:free|gratis|gratuite?|kostenlos\w*|versandkostenfrei)\b|送料無料|配送料無料`)
+
+// Tax labels shown next to prices: "incl. VAT", "inkl. MwSt.", "TTC", "税込" or "+ tax", "zzgl. MwSt.", "HT", "税抜"
+var (
+	taxIncludedPattern = regexp.MustCompile(`(?i)\bincl(?:\.|uding|usive of)?\s*(?:all\s*)?(?:vat|gst|tax(?:es)?)\b|\binkl\.?\s*mwst|\bttc\b|税込`)
+	taxExcludedPattern = regexp.MustCompile(`(?i)\bexcl(?:\.|uding)?\s*(?:vat|gst|tax(?:es)?)\b|\bzzgl\.?\s*mwst|\+\s*tax\b|\bplus\s+tax\b|\bbefore\s+tax\b|\bht\b|税抜|税別`)
+)
+
+// ParseShipping reads a listing's delivery charge, e.g. "+$5.99 shipping", "+5,99 € Versand" or "Free delivery".
+// Free delivery is zero in currency, the price's; a charge is in the currency it shows. Text without free delivery
+// or a currency is not read as a charge, so "Delivery Tue, Oct 21" gives false.
+func ParseShipping(text, country, currency string) (money.Money, bool) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return money.Money{}, false
+	}
+	if freeShippingPattern.MatchString(text) {
+		if currency == "" {
+			currency = money.DetectCurrency(text, country)
+		}
+		return money.Money{Currency: currency}, true
+	}
+	if _, shown := money.SymbolCurrency(text); !shown && !strings.Contains(text, "$") {
+		return money.Money{}, false
+	}
+	amount, err := money.Parse(text, country)
+	if err != nil {
+		return money.Money{}, false
+	}
+	return amount, true
+}
+
+// TaxLabel reads whether a price's label says it includes tax; labelled is false when the text says neither
+func TaxLabel(text string) (included, labelled bool) {
+	switch {
+	case taxIncludedPattern.MatchString(text):
+		return true, true
+	case taxExcludedPattern.MatchString(text):
+		return false, true
+	}
+	return false, false
+}
@@ -0,0 +1,313 @@
+// Package lang makes titles from non-English markets comparable with English queries and with each other. It
+// folds case, accents and full-width forms, splits CJK runs from Latin text and translates the accessory,
+// condition, colour and product vocabulary of the supported site languages (German, French and Japanese) into
+// English, so the rest of the matcher only ever sees English keywords.
+package lang
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/text/width"
+)
+
+//go:embed vocabulary.json
+var embeddedVocabulary []byte
+
+// vocabulary holds one language's terms, each mapped to its English equivalent
+type vocabulary struct {
+	Connectors  map[string]string `json:"connectors"`
+	Products    map[string]string `json:"products"`
+	Accessories map[string]string `json:"accessories"`
+	Conditions  map[string]string `json:"conditions"`
+	Colors      map[string]string `json:"colors"`
+	Units       map[string]string `json:"units"`
+	Suffixes    map[string]string `json:"suffixes"` // Compound endings, as German "-hülle" in "Silikonhülle"
+	Generic     []string          `json:"generic"`
+	PriceCues   []string          `json:"priceCues"`
+}
+
+// suffix is a compound ending and its translation
+type suffix struct{ ending, english string }
+
+var (
+	// phrases maps folded Latin-script terms of one or more words to English
+	phrases = map[string]string{}
+	// maxPhraseWords is the word count of the longest Latin-script term
+	maxPhraseWords = 1
+	// cjkTerms are the CJK-script terms, longest first so the most specific wins
+	cjkTerms []suffix
+	suffixes []suffix
+
+	genericPhrases []string
+	priceCues      []string
+)
+
+// foldReplacer spells out letters that do not decompose, and German umlauts the way German writes them without
+// the diacritic, so "für" becomes "fuer" rather than colliding with English "fur"
+var foldReplacer = strings.NewReplacer(
+	"ä", "ae", "ö", "oe", "ü", "ue", "Ä", "ae", "Ö", "oe", "Ü", "ue",
+	"ß", "ss", "æ", "ae", "Æ", "ae", "œ", "oe", "Œ", "oe", "ø", "o", "Ø", "o",
+	"’", "'", "‘", "'",
+)
+
+// forPattern finds the Japanese "A用B" (B for A) once its parts have been translated; trailingForPattern finds
+// the same with B before it, as in "ガラスフィルム iPad用"
+var (
+	forPattern         = regexp.MustCompile(`^(.*\S)\s+用\s+(\S.*)$`)
+	trailingForPattern = regexp.MustCompile(`^(.*\S)\s+(\S+)\s+用$`)
+)
+
+func init() {
+	var languages map[string]vocabulary
+	if err := json.Unmarshal(embeddedVocabulary, &languages); err != nil {
+		panic(fmt.Sprintf("lang: parse embedded vocabulary: %v", err))
+	}
+
+	codes := make([]string, 0, len(languages))
+	for code := range languages {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	for _, code := range codes {
+		v := languages[code]
+		for _, terms := range []map[string]string{v.Connectors, v.Products, v.Accessories, v.Conditions, v.Colors, v.Units} {
+			for term, english := range terms {
+				addTerm(Fold(term), english)
+			}
+		}
+		for ending, english := range v.Suffixes {
+			suffixes = append(suffixes, suffix{Fold(ending), english})
+		}
+		for _, phrase := range v.Generic {
+			genericPhrases = append(genericPhrases, Fold(phrase))
+		}
+		for _, cue := range v.PriceCues {
+			priceCues = append(priceCues, Fold(cue))
+		}
+	}
+
+	sort.Slice(cjkTerms, func(i, j int) bool { return len(cjkTerms[i].ending) > len(cjkTerms[j].ending) })
+	sort.Slice(suffixes, func(i, j int) bool { return len(suffixes[i].ending) > len(suffixes[j].ending) })
+}
+
+func addTerm(term, english string) {
+	if term == "" {
+		return
+	}
+	if hasCJK(term) {
+		cjkTerms = append(cjkTerms, suffix{term, english})
+		return
+	}
+	phrases[term] = english
+	if words := len(strings.Fields(term)); words > maxPhraseWords {
+		maxPhraseWords = words
+	}
+}
+
+// Fold lowercases text and removes what differs between spellings of the same word: accents on Latin letters,
+// full-width and half-width forms, and ligatures. Kana voicing marks are kept, since they change the word.
+func Fold(text string) string {
+	if isASCII(text) {
+		return strings.ToLower(text)
+	}
+
+	text = foldReplacer.Replace(norm.NFC.String(text))
+	decomposed := norm.NFKD.String(text)
+
+	var b strings.Builder
+	b.Grow(len(decomposed))
+	var base rune
+	for _, r := range decomposed {
+		if unicode.Is(unicode.Mn, r) {
+			if unicode.Is(unicode.Latin, base) {
+				continue
+			}
+		} else {
+			base = r
+		}
+		b.WriteRune(r)
+	}
+	return strings.ToLower(norm.NFC.String(b.String()))
+}
+
+// Segment puts a space wherever CJK text meets other text, as in "iPhone16用ケース", so the Latin parts become
+// words of their own. CJK runs stay whole; Canonical finds terms inside them.
+func Segment(text string) string {
+	if isASCII(text) {
+		return text
+	}
+
+	var b strings.Builder
+	b.Grow(len(text) + 8)
+	previous := ' '
+	for _, r := range text {
+		if !unicode.IsSpace(previous) && !unicode.IsSpace(r) && isCJK(previous) != isCJK(r) {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(r)
+		previous = r
+	}
+	return b.String()
+}
+
+// Canonical folds and segments text, then translates the known German, French and Japanese terms into English.
+// English text comes back folded and otherwise untouched.
+func Canonical(text string) string {
+	folded := Segment(Fold(text))
+	words := strings.Fields(folded)
+
+	changed := false
+	out := make([]string, 0, len(words))
+	for i := 0; i < len(words); {
+		if hasCJK(words[i]) {
+			translated := translateCJK(words[i])
+			changed = changed || translated != words[i]
+			out = append(out, translated)
+			i++
+			continue
+		}
+		if english, used := matchPhrase(words[i:]); used > 0 {
+			out = append(out, english)
+			changed = true
+			i += used
+			continue
+		}
+		if translated := splitCompound(words[i]); translated != words[i] {
+			out = append(out, translated)
+			changed = true
+			i++
+			continue
+		}
+		out = append(out, words[i])
+		i++
+	}
+	if !changed {
+		return folded
+	}
+
+	result := strings.Join(strings.Fields(strings.Join(out, " ")), " ")
+	if match := forPattern.FindStringSubmatch(result); match != nil {
+		result = match[2] + " for " + match[1]
+	} else if match := trailingForPattern.FindStringSubmatch(result); match != nil {
+		result = match[1] + " for " + match[2]
+	}
+	return result
+}
+
+// matchPhrase translates the longest known term starting at words[0], keeping the punctuation around it, and
+// returns how many words it used; 0 when none matches
+func matchPhrase(words []string) (string, int) {
+	for n := min(maxPhraseWords, len(words)); n > 0; n-- {
+		core, leading, trailing := trimPunctuation(strings.Join(words[:n], " "))
+		if english, known := phrases[core]; known {
+			return leading + english + trailing, n
+		}
+	}
+	return "", 0
+}
+
+// splitCompound translates a known compound ending, as "silikonhuelle" to "silikon case"
+func splitCompound(word string) string {
+	core, leading, trailing := trimPunctuation(word)
+	for _, s := range suffixes {
+		if len(core) > len(s.ending)+2 && strings.HasSuffix(core, s.ending) {
+			return leading + core[:len(core)-len(s.ending)] + " " + s.english + trailing
+		}
+	}
+	return word
+}
+
+// translateCJK replaces known terms inside a CJK run, longest first, and sets them apart with spaces
+func translateCJK(word string) string {
+	var b strings.Builder
+	for i := 0; i < len(word); {
+		matched := false
+		for _, term := range cjkTerms {
+			if strings.HasPrefix(word[i:], term.ending) {
+				b.WriteString(" " + term.english + " ")
+				i += len(term.ending)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			r, size := utf8.DecodeRuneInString(word[i:])
+			b.WriteRune(r)
+			i += size
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// trimPunctuation splits a word into its letters and digits and the punctuation before and after them
+func trimPunctuation(word string) (core, leading, trailing string) {
+	isWordRune := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsNumber(r) }
+	start := strings.IndexFunc(word, isWordRune)
+	if start < 0 {
+		return "", word, ""
+	}
+	end := strings.LastIndexFunc(word, isWordRune)
+	_, size := utf8.DecodeRuneInString(word[end:])
+	return word[start : end+size], word[:start], word[end+size:]
+}
+
+// Width is the display width of text: wide and full-width characters, as in CJK, count twice. Length limits
+// measured with it treat a five-character Japanese title like a ten-letter English one rather than as bytes.
+func Width(text string) int {
+	total := 0
+	for _, r := range strings.TrimSpace(text) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+		case width.LookupRune(r).Kind() == width.EastAsianWide || width.LookupRune(r).Kind() == width.EastAsianFullwidth:
+			total += 2
+		default:
+			total++
+		}
+	}
+	return total
+}
+
+// GenericPhrases lists the folded phrases of site chrome ("Sponsored", "See all results") in every supported
+// language other than English
+func GenericPhrases() []string {
+	return genericPhrases
+}
+
+// PriceCues lists folded words and symbols that mark text as an offer ("Preis", "税込", "€") in every supported
+// language other than English
+func PriceCues() []string {
+	return priceCues
+}
+
+func isASCII(text string) bool {
+	for i := 0; i < len(text); i++ {
+		if text[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// isCJK reports whether r is written without spaces between words: CJK ideographs, kana, Hangul and the
+// CJK punctuation around them, as the brackets of "【中古】"
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) || (r >= 0x3000 && r <= 0x303f) || r == 'ー'
+}
+
+func hasCJK(text string) bool {
+	for _, r := range text {
+		if isCJK(r) {
+			return true
+		}
+	}
+	return false
+}
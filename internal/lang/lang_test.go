@@ -0,0 +1,70 @@
+package lang
+
+import "testing"
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"Apple iPhone 15", "apple iphone 15"},
+		{"ＩＰＨＯＮＥ　１５ Ｐｒｏ", "iphone 15 pro"}, // Full-width letters, digits and space
+		{"ｶﾒﾗ", "カメラ"},                     // Half-width katakana
+		{"ガ", "ガ"},                         // Voicing mark kept
+		{"Café Crème", "cafe creme"},
+		{"ﬁlm", "film"}, // Ligature
+		{"Straße für", "strasse fuer"},
+		{"Œuvre", "oeuvre"},
+	}
+	for _, test := range tests {
+		if got := Fold(test.text); got != test.want {
+			t.Errorf("Fold(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestSegment(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"iPhone16用ケース", "iPhone16 用ケース"},
+		{"【中古】iPhone 15", "【中古】 iPhone 15"},
+		{"AirPods Pro 第2世代", "AirPods Pro 第 2 世代"},
+		{"iPhone 15 Pro", "iPhone 15 Pro"},
+	}
+	for _, test := range tests {
+		if got := Segment(test.text); got != test.want {
+			t.Errorf("Segment(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"Apple iPhone 15 128GB", "apple iphone 15 128gb"},
+		{"Hülle für iPhone 15", "case for iphone 15"},
+		{"Silikonhülle für iPhone 15, schwarz", "silikon case for iphone 15, black"}, // Compound ending
+		{"Kopfhörer wie neu", "headphones like new"},
+		{"Coque pour iPhone 15 noir", "case for iphone 15 black"},
+		{"Écouteurs reconditionné", "earbuds refurbished"},
+		{"スマートフォン", "smartphone"},
+		{"iPhone 15用 ケース ブラック", "case black for iphone 15"},
+		{"ガラスフィルム iPad用", "tempered glass for ipad"},
+		{"【中古】iPhone 15", "【 used 】 iphone 15"},
+	}
+	for _, test := range tests {
+		if got := Canonical(test.text); got != test.want {
+			t.Errorf("Canonical(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestWidth(t *testing.T) {
+	if got := Width("スマホ"); got != 6 {
+		t.Errorf("Width(スマホ) = %d, want 6", got)
+	}
+	if got := Width(" iPhone "); got != 6 {
+		t.Errorf("Width(iPhone) = %d, want 6", got)
+	}
+}
@@ -0,0 +1,292 @@
+package llm
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Priority orders queued LLM work; lower values are dispatched first
+type Priority int
+
+const (
+	// PriorityInteractive is page extraction a user is waiting on
+	PriorityInteractive Priority = iota
+	// PriorityBackground is relevance scoring that can fall back to fuzzy matching
+	PriorityBackground
+
+	numPriorities
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityInteractive:
+		return "interactive"
+	case PriorityBackground:
+		return "background"
+	}
+	return "unknown"
+}
+
+// ErrDeadlineTooSoon is returned when queued work could not finish before the caller's deadline
+var ErrDeadlineTooSoon = errors.New("llm scheduler: request deadline too close to admit work")
+
+// latencySmoothing is the weight of the newest sample in the moving average of call latency
+const latencySmoothing = 0.2
+
+type requestIDKey struct{}
+
+// WithRequestID tags ctx so the scheduler can share capacity fairly between concurrent searches
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the ID set by WithRequestID, or "" when none was set
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// NewRequestID returns a random identifier for a search request
+func NewRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(b)
+}
+
+type waiter struct {
+	requestID string
+	granted   chan struct{}
+}
+
+// fairQueue round-robins between requests so one search cannot starve the others
+type fairQueue struct {
+	order   []string
+	waiting map[string][]*waiter
+	size    int
+}
+
+func (q *fairQueue) push(w *waiter) {
+	if len(q.waiting[w.requestID]) == 0 {
+		q.order = append(q.order, w.requestID)
+	}
+	q.waiting[w.requestID] = append(q.waiting[w.requestID], w)
+	q.size++
+}
+
+func (q *fairQueue) pop() *waiter {
+	if q.size == 0 {
+		return nil
+	}
+
+	requestID := q.order[0]
+	q.order = q.order[1:]
+
+	pending := q.waiting[requestID]
+	w := pending[0]
+	if len(pending) > 1 {
+		q.waiting[requestID] = pending[1:]
+		q.order = append(q.order, requestID)
+	} else {
+		delete(q.waiting, requestID)
+	}
+	q.size--
+	return w
+}
+
+func (q *fairQueue) remove(w *waiter) bool {
+	pending := q.waiting[w.requestID]
+	for i, candidate := range pending {
+		if candidate != w {
+			continue
+		}
+		pending = append(pending[:i], pending[i+1:]...)
+		if len(pending) == 0 {
+			delete(q.waiting, w.requestID)
+			for j, id := range q.order {
+				if id == w.requestID {
+					q.order = append(q.order[:j], q.order[j+1:]...)
+					break
+				}
+			}
+		} else {
+			q.waiting[w.requestID] = pending
+		}
+		q.size--
+		return true
+	}
+	return false
+}
+
+// Scheduler is a process-wide governor for LLM calls
+type Scheduler struct {
+	mutex      sync.Mutex
+	limit      int
+	active     int
+	queues     [numPriorities]*fairQueue
+	avgLatency time.Duration
+	rejected   int64
+	completed  int64
+}
+
+// Stats is a snapshot of scheduler load for health reporting
+type Stats struct {
+	Limit        int            `json:"limit"`
+	Active       int            `json:"active"`
+	Queued       map[string]int `json:"queued"`
+	AvgLatencyMs int64          `json:"avgLatencyMs"`
+	Completed    int64          `json:"completed"`
+	Rejected     int64          `json:"rejected"`
+}
+
+// NewScheduler creates a scheduler that allows at most limit LLM calls in flight
+func NewScheduler(limit int) *Scheduler {
+	if limit <= 0 {
+		limit = 1
+	}
+	s := &Scheduler{limit: limit}
+	for i := range s.queues {
+		s.queues[i] = &fairQueue{waiting: make(map[string][]*waiter)}
+	}
+	return s
+}
+
+// Acquire blocks until a slot is free for work of the given priority and returns a release func that
+// must be called when the call finishes. Work that is not expected to finish before the ctx deadline
+// is rejected with ErrDeadlineTooSoon so callers can use a cheaper fallback immediately.
+func (s *Scheduler) Acquire(ctx context.Context, priority Priority) (func(), error) {
+	if priority < 0 || priority >= numPriorities {
+		priority = PriorityBackground
+	}
+
+	s.mutex.Lock()
+	if !s.admissible(ctx, priority) {
+		s.rejected++
+		s.mutex.Unlock()
+		return nil, ErrDeadlineTooSoon
+	}
+
+	if s.active < s.limit && s.queuedAhead(priority) == 0 {
+		s.active++
+		s.mutex.Unlock()
+		return s.releaser(), nil
+	}
+
+	w := &waiter{requestID: RequestID(ctx), granted: make(chan struct{})}
+	s.queues[priority].push(w)
+	s.mutex.Unlock()
+
+	select {
+	case <-w.granted:
+		// The deadline may have moved closer while we waited; give the slot back if it is now too late
+		s.mutex.Lock()
+		if deadline, ok := ctx.Deadline(); ok && s.avgLatency > 0 && time.Until(deadline) < s.avgLatency {
+			s.rejected++
+			s.active--
+			s.dispatch()
+			s.mutex.Unlock()
+			return nil, ErrDeadlineTooSoon
+		}
+		s.mutex.Unlock()
+		return s.releaser(), nil
+	case <-ctx.Done():
+		s.mutex.Lock()
+		if !s.queues[priority].remove(w) {
+			// Granted concurrently with cancellation, hand the slot to the next waiter
+			s.active--
+			s.dispatch()
+		}
+		s.mutex.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// admissible estimates when work queued now would finish and compares it with the ctx deadline.
+// Must be called with the mutex held.
+func (s *Scheduler) admissible(ctx context.Context, priority Priority) bool {
+	deadline, ok := ctx.Deadline()
+	if !ok || s.avgLatency == 0 {
+		return true
+	}
+
+	ahead := s.queuedAhead(priority) + s.active - s.limit + 1
+	rounds := 0
+	if ahead > 0 {
+		rounds = (ahead + s.limit - 1) / s.limit
+	}
+	expectedFinish := time.Now().Add(time.Duration(rounds+1) * s.avgLatency)
+
+	return expectedFinish.Before(deadline)
+}
+
+// queuedAhead counts waiters that would be dispatched before new work of this priority
+func (s *Scheduler) queuedAhead(priority Priority) int {
+	ahead := 0
+	for p := Priority(0); p <= priority; p++ {
+		ahead += s.queues[p].size
+	}
+	return ahead
+}
+
+func (s *Scheduler) releaser() func() {
+	start := time.Now()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			elapsed := time.Since(start)
+			s.mutex.Lock()
+			defer s.mutex.Unlock()
+
+			if s.avgLatency == 0 {
+				s.avgLatency = elapsed
+			} else {
+				s.avgLatency = time.Duration(latencySmoothing*float64(elapsed) + (1-latencySmoothing)*float64(s.avgLatency))
+			}
+			s.completed++
+			s.active--
+			s.dispatch()
+		})
+	}
+}
+
+// dispatch grants free slots to waiters in priority order. Must be called with the mutex held.
+func (s *Scheduler) dispatch() {
+	for s.active < s.limit {
+		var next *waiter
+		for _, queue := range s.queues {
+			if next = queue.pop(); next != nil {
+				break
+			}
+		}
+		if next == nil {
+			return
+		}
+		s.active++
+		close(next.granted)
+	}
+}
+
+// Stats returns a snapshot of the current load
+func (s *Scheduler) Stats() Stats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	queued := make(map[string]int, numPriorities)
+	for p, queue := range s.queues {
+		queued[Priority(p).String()] = queue.size
+	}
+
+	return Stats{
+		Limit:        s.limit,
+		Active:       s.active,
+		Queued:       queued,
+		AvgLatencyMs: s.avgLatency.Milliseconds(),
+		Completed:    s.completed,
+		Rejected:     s.rejected,
+	}
+}
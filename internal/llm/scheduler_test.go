@@ -0,0 +1,158 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitQueued polls until n waiters of priority are queued
+func waitQueued(t *testing.T, s *Scheduler, priority Priority, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if s.Stats().Queued[priority.String()] == n {
+			return
+		}
+	}
+	t.Fatalf("%d %s waiters never queued, stats %+v", n, priority, s.Stats())
+}
+
+// queuedWork is a call of priority made for the search requestID
+type queuedWork struct {
+	name, requestID string
+	priority        Priority
+}
+
+// grantOrder queues one waiter per name behind a held slot, one at a time so the queue order is known, then frees
+// the slot and returns the order the waiters were granted in
+func grantOrder(t *testing.T, s *Scheduler, waiters []queuedWork) []string {
+	t.Helper()
+	hold, err := s.Acquire(context.Background(), PriorityInteractive)
+	if err != nil {
+		t.Fatalf("Acquire() error: %v", err)
+	}
+
+	var mutex sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	queued := make(map[Priority]int)
+	for _, w := range waiters {
+		w := w
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := s.Acquire(WithRequestID(context.Background(), w.requestID), w.priority)
+			if err != nil {
+				t.Errorf("Acquire(%s) error: %v", w.name, err)
+				return
+			}
+			mutex.Lock()
+			order = append(order, w.name)
+			mutex.Unlock()
+			release()
+		}()
+		queued[w.priority]++
+		waitQueued(t, s, w.priority, queued[w.priority])
+	}
+
+	hold()
+	wg.Wait()
+	return order
+}
+
+func TestSchedulerLimitsCallsInFlight(t *testing.T) {
+	s := NewScheduler(2)
+	first, _ := s.Acquire(context.Background(), PriorityBackground)
+	second, _ := s.Acquire(context.Background(), PriorityBackground)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := s.Acquire(ctx, PriorityBackground); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("third Acquire() error = %v, want the context deadline while both slots are held", err)
+	}
+	if stats := s.Stats(); stats.Active != 2 || stats.Queued[PriorityBackground.String()] != 0 {
+		t.Errorf("Stats() = %+v, want 2 active and the cancelled waiter dequeued", stats)
+	}
+
+	first()
+	first() // A second release is a no-op
+	second()
+	if stats := s.Stats(); stats.Active != 0 || stats.Completed != 2 {
+		t.Errorf("Stats() = %+v, want none active and 2 completed", stats)
+	}
+}
+
+func TestSchedulerDispatchesInteractiveFirst(t *testing.T) {
+	order := grantOrder(t, NewScheduler(1), []queuedWork{
+		{"background", "a", PriorityBackground},
+		{"interactive", "a", PriorityInteractive},
+	})
+	if len(order) != 2 || order[0] != "interactive" {
+		t.Errorf("granted %v, want interactive work before background work queued earlier", order)
+	}
+}
+
+func TestSchedulerSharesSlotsBetweenRequests(t *testing.T) {
+	order := grantOrder(t, NewScheduler(1), []queuedWork{
+		{"a1", "a", PriorityBackground},
+		{"a2", "a", PriorityBackground},
+		{"a3", "a", PriorityBackground},
+		{"b1", "b", PriorityBackground},
+		{"b2", "b", PriorityBackground},
+	})
+	want := []string{"a1", "b1", "a2", "b2", "a3"}
+	if len(order) != len(want) {
+		t.Fatalf("granted %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Errorf("granted %v, want requests taking turns %v", order, want)
+			break
+		}
+	}
+}
+
+func TestSchedulerRejectsWorkThatCannotMeetDeadline(t *testing.T) {
+	s := NewScheduler(1)
+	s.avgLatency = time.Second
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := s.Acquire(ctx, PriorityInteractive); !errors.Is(err, ErrDeadlineTooSoon) {
+		t.Errorf("Acquire() error = %v, want ErrDeadlineTooSoon for a deadline shorter than a call", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	release, err := s.Acquire(ctx, PriorityInteractive)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v, want a slot with time for one call", err)
+	}
+	// With the only slot held, a second call would finish after two calls' latency
+	ctx, cancel = context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+	if _, err := s.Acquire(ctx, PriorityInteractive); !errors.Is(err, ErrDeadlineTooSoon) {
+		t.Errorf("queued Acquire() error = %v, want ErrDeadlineTooSoon behind a held slot", err)
+	}
+	release()
+
+	if rejected := s.Stats().Rejected; rejected != 2 {
+		t.Errorf("Stats().Rejected = %d, want 2", rejected)
+	}
+}
+
+func TestFairQueueRemove(t *testing.T) {
+	q := &fairQueue{waiting: make(map[string][]*waiter)}
+	a, b := &waiter{requestID: "a"}, &waiter{requestID: "b"}
+	q.push(a)
+	q.push(b)
+
+	if !q.remove(a) || q.remove(a) {
+		t.Fatal("remove() should succeed once for a queued waiter")
+	}
+	if q.size != 1 || len(q.order) != 1 || q.pop() != b || q.pop() != nil {
+		t.Errorf("queue after remove = %+v, want only b", q)
+	}
+}
@@ -0,0 +1,226 @@
+package matcher
+
+import (
+	"math"
+	"price-comparison-tool/internal/models"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// BM25 parameters: term frequency saturation and how strongly long titles are normalised
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+
+	// boilerplateWeight is how much a retailer boilerplate token counts towards term frequency and title length
+	boilerplateWeight = 0.1
+)
+
+// boilerplatePhrases are retailer additions that say nothing about the product
+var boilerplatePhrases = []string{
+	"free delivery", "free shipping", "ships free", "fast delivery", "free returns", "same day delivery",
+	"cash on delivery", "no cost emi", "emi available", "bank offer", "exchange offer", "limited time deal",
+	"deal of the day", "best seller", "bestseller", "amazon's choice", "top rated", "hot sale", "new arrival",
+	"in stock", "official store", "authorized seller", "100% original", "with free",
+}
+
+// sellerPattern finds seller names: "sold by X", "visit the X store"
+var sellerPattern = regexp.MustCompile(`\b(?:sold|shipped|fulfilled|dispatched) by \S+(?: \S+)?|\bvisit the (?:\S+ ){1,3}store\b`)
+
+// specUnits are units written apart from their number in some titles and joined in others ("128 GB", "128GB")
+var specUnits = map[string]bool{
+	"gb": true, "tb": true, "mb": true, "mah": true, "w": true, "ml": true, "l": true, "kg": true, "g": true,
+	"inch": true, "mm": true, "cm": true, "hz": true,
+}
+
+// bm25Document is a tokenised title with the weight of each token; boilerplate tokens weigh boilerplateWeight
+type bm25Document struct {
+	frequencies map[string]float64
+	length      float64
+}
+
+// Corpus is the pooled result titles of one search. BM25 scores a title by the query terms it contains, each
+// weighted by how rare it is among the other results, so a model number that sets a title apart outweighs a
+// word every listing repeats.
+type Corpus struct {
+	documents     map[string]bm25Document // By title
+	frequencies   map[string]int          // Titles containing each token
+	averageLength float64
+}
+
+// NewCorpus builds a corpus from the results of one search. Each result's site name counts as boilerplate in
+// its own title, as do retailer phrases and seller names.
+func NewCorpus(products []models.ProductResult) *Corpus {
+	corpus := &Corpus{documents: make(map[string]bm25Document), frequencies: make(map[string]int)}
+	total := 0.0
+	for _, product := range products {
+		if _, seen := corpus.documents[product.ProductName]; seen {
+			continue
+		}
+		document := newBM25Document(product.ProductName, product.Site)
+		corpus.documents[product.ProductName] = document
+		for token := range document.frequencies {
+			corpus.frequencies[token]++
+		}
+		total += document.length
+	}
+	if len(corpus.documents) > 0 {
+		corpus.averageLength = total / float64(len(corpus.documents))
+	}
+	return corpus
+}
+
+// Similarity is the title's BM25 score for the query divided by the score of a title of average length holding
+// every query term once, so a title naming everything asked for scores 1 and missing rare terms costs most.
+// A title outside the corpus is scored as if it had been added to it.
+func (c *Corpus) Similarity(query, title string) float64 {
+	document, known := c.documents[title]
+	if !known {
+		document = newBM25Document(title, "")
+	}
+	averageLength := c.averageLength
+	if averageLength == 0 {
+		averageLength = document.length
+	}
+	documents := float64(len(c.documents))
+	if !known {
+		documents++
+	}
+
+	score, ideal := 0.0, 0.0
+	for _, token := range uniqueTokens(bm25Tokens(query)) {
+		containing := float64(c.frequencies[token])
+		if !known && document.frequencies[token] > 0 {
+			containing++
+		}
+		idf := math.Log(1 + (documents-containing+0.5)/(containing+0.5))
+		ideal += idf
+
+		frequency := document.frequencies[token]
+		if frequency == 0 {
+			continue
+		}
+		norm := 1 - bm25B + bm25B*document.length/math.Max(averageLength, 1)
+		saturation := frequency * (bm25K1 + 1) / (frequency + bm25K1*norm)
+		score += idf * math.Min(1, saturation)
+	}
+	if ideal == 0 {
+		return 0
+	}
+	return score / ideal
+}
+
+// newBM25Document tokenises a title, weighting retailer boilerplate, seller names and the site's own name down
+func newBM25Document(title, site string) bm25Document {
+	text := normalizeQueryText(title)
+	boilerplate := make(map[int]bool) // Byte offsets of boilerplate spans in text, marked per token start
+	mark := func(start, end int) {
+		for i := start; i < end; i++ {
+			boilerplate[i] = true
+		}
+	}
+	for _, phrase := range boilerplatePhrases {
+		for offset := 0; ; {
+			i := strings.Index(text[offset:], phrase)
+			if i < 0 {
+				break
+			}
+			mark(offset+i, offset+i+len(phrase))
+			offset += i + len(phrase)
+		}
+	}
+	for _, span := range sellerPattern.FindAllStringIndex(text, -1) {
+		mark(span[0], span[1])
+	}
+	siteWords := make(map[string]bool)
+	for _, word := range bm25Tokens(site) {
+		siteWords[word] = true
+	}
+
+	document := bm25Document{frequencies: make(map[string]float64)}
+	for _, token := range tokenSpans(text) {
+		weight := 1.0
+		if boilerplate[token.start] || siteWords[token.text] {
+			weight = boilerplateWeight
+		}
+		document.frequencies[token.text] += weight
+		document.length += weight
+	}
+	return document
+}
+
+// tokenSpan is a token and its byte offset in the text it was read from
+type tokenSpan struct {
+	text  string
+	start int
+}
+
+// tokenSpans splits normalised text into letter and digit runs, joining a number to a unit that follows it
+func tokenSpans(text string) []tokenSpan {
+	var spans []tokenSpan
+	start := -1
+	for i, r := range text + " " {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			spans = append(spans, tokenSpan{text[start:i], start})
+			start = -1
+		}
+	}
+
+	joined := spans[:0]
+	for _, span := range spans {
+		if n := len(joined); n > 0 && specUnits[span.text] && isDigits(joined[n-1].text) {
+			joined[n-1].text += span.text
+			continue
+		}
+		joined = append(joined, span)
+	}
+	return joined
+}
+
+// bm25Tokens tokenises a query or site name the way titles are tokenised
+func bm25Tokens(text string) []string {
+	var tokens []string
+	for _, span := range tokenSpans(normalizeQueryText(text)) {
+		tokens = append(tokens, span.text)
+	}
+	return tokens
+}
+
+func uniqueTokens(tokens []string) []string {
+	seen := make(map[string]bool, len(tokens))
+	unique := tokens[:0]
+	for _, token := range tokens {
+		if !seen[token] {
+			seen[token] = true
+			unique = append(unique, token)
+		}
+	}
+	return unique
+}
+
+func isDigits(text string) bool {
+	for _, r := range text {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return text != ""
+}
+
+// bm25Match scores a product like fuzzyMatch with the corpus's BM25 similarity as the base
+func (s *Service) bm25Match(intent models.QueryIntent, productName string, corpus *Corpus) models.ScoreExplanation {
+	explanation := models.ScoreExplanation{Scorer: ScorerBM25}
+	if intent.Raw == "" || productName == "" {
+		return explanation
+	}
+	explanation.BaseSimilarity = corpus.Similarity(intent.Raw, productName)
+	s.scoreFromBase(intent, productName, &explanation)
+	return explanation
+}
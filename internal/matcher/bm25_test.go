@@ -0,0 +1,78 @@
+package matcher
+
+import (
+	"math"
+	"testing"
+
+	"price-comparison-tool/internal/models"
+)
+
+// results lists titles as the results of one search on a single site
+func results(titles ...string) []models.ProductResult {
+	products := make([]models.ProductResult, len(titles))
+	for i, title := range titles {
+		products[i] = models.ProductResult{ProductName: title, Site: "Shop"}
+	}
+	return products
+}
+
+func TestCorpusWeighsTermsByRarityAmongResults(t *testing.T) {
+	const query = "iPhone 15 Pro"
+	// "Pro" sets one title apart in the first search and is in nearly every title of the second
+	rare := NewCorpus(results("Apple iPhone 15 Pro 128GB", "Apple iPhone 15 128GB", "Apple iPhone 15 256GB",
+		"Apple iPhone 15 Plus", "Apple iPhone 15 Blue"))
+	common := NewCorpus(results("Apple iPhone 15 Pro 128GB", "Apple iPhone 15 Pro 256GB", "Apple iPhone 15 Pro Blue",
+		"Apple iPhone 15 Pro Max", "Apple iPhone 15 128GB"))
+
+	for name, corpus := range map[string]*Corpus{"rare": rare, "common": common} {
+		if got := corpus.Similarity(query, "Apple iPhone 15 Pro 128GB"); got < 0.9 {
+			t.Errorf("%s: Similarity() of a title with every term = %.3f, want at least 0.9", name, got)
+		}
+	}
+	withoutPro := "Apple iPhone 15 128GB"
+	if rareScore, commonScore := rare.Similarity(query, withoutPro), common.Similarity(query, withoutPro); rareScore >= commonScore {
+		t.Errorf("Similarity(%q) = %.3f where Pro is rare, %.3f where it is common; want missing a rare term to cost more",
+			withoutPro, rareScore, commonScore)
+	}
+	without15 := "Apple iPhone Pro 128GB"
+	if rareScore, commonScore := rare.Similarity(query, without15), common.Similarity(query, without15); rareScore <= commonScore {
+		t.Errorf("Similarity(%q) = %.3f where Pro is rare, %.3f where it is common; want missing 15 to cost more beside a common Pro",
+			without15, rareScore, commonScore)
+	}
+}
+
+func TestCorpusWeighsBoilerplateDown(t *testing.T) {
+	corpus := NewCorpus(results("Apple iPhone 15 Pro 128GB Free Delivery Sold by Shop Direct", "Apple iPhone 15 Pro 128GB"))
+	if got := corpus.Similarity("iPhone 15 Pro", "Apple iPhone 15 Pro 128GB Free Delivery Sold by Shop Direct"); got < 0.95 {
+		t.Errorf("Similarity() of a title padded with boilerplate = %.3f, want at least 0.95", got)
+	}
+	if got := corpus.Similarity("128 GB", "Apple iPhone 15 Pro 128GB"); got != 1 {
+		t.Errorf("Similarity(128 GB, 128GB) = %.3f, want the unit joined to its number", got)
+	}
+}
+
+func TestCorpusWithOneTitle(t *testing.T) {
+	const query = "iPhone 15 Pro"
+	single := NewCorpus(results("Apple iPhone 15 Pro 128GB"))
+	duplicated := NewCorpus(results("Apple iPhone 15 Pro 128GB", "Apple iPhone 15 Pro 128GB"))
+	empty := NewCorpus(nil)
+
+	for name, corpus := range map[string]*Corpus{"single": single, "duplicated": duplicated, "empty": empty} {
+		if got := corpus.Similarity(query, "Apple iPhone 15 Pro 128GB"); got != 1 {
+			t.Errorf("%s: Similarity() of a title with every term = %.3f, want 1", name, got)
+		}
+		partial := corpus.Similarity(query, "Apple iPhone 15 128GB")
+		if math.IsNaN(partial) || partial <= 0 || partial >= 1 {
+			t.Errorf("%s: Similarity() of a title missing a term = %.3f, want between 0 and 1", name, partial)
+		}
+		if got := corpus.Similarity(query, "Samsung Galaxy S24"); got != 0 {
+			t.Errorf("%s: Similarity() of an unrelated title = %.3f, want 0", name, got)
+		}
+		if got := corpus.Similarity("", "Apple iPhone 15 Pro 128GB"); got != 0 {
+			t.Errorf("%s: Similarity() for an empty query = %.3f, want 0", name, got)
+		}
+	}
+	if a, b := single.Similarity(query, "Apple iPhone 15 128GB"), duplicated.Similarity(query, "Apple iPhone 15 128GB"); a != b {
+		t.Errorf("Similarity() = %.3f with a repeated title, %.3f without; want repeats counted once", b, a)
+	}
+}
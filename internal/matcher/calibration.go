@@ -0,0 +1,232 @@
+package matcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"price-comparison-tool/internal/models"
+	"sort"
+	"time"
+)
+
+// Calibration methods
+const (
+	CalibrationPlatt    = "platt"    // A sigmoid of the raw score; stable on small samples
+	CalibrationIsotonic = "isotonic" // Any non-decreasing map of the raw score; needs more examples
+)
+
+// SourceCSSFallback is the confidence source of results read by the CSS selector fallback, which sets a fixed
+// confidence. Other sources are the scoring strategies, the cascade's stages (see CalibrationSource) and
+// extraction (prompts.Extraction).
+const SourceCSSFallback = "css-fallback"
+
+// CalibrationSource names the source of a score the strategy produced. The cascade passes on the score of
+// whichever stage settled a result, each on its own scale, so its scores are sourced by stage: "cascade/fuzzy"
+// or "cascade/" and the model that scored them.
+func CalibrationSource(strategy string, explanation models.ScoreExplanation) string {
+	if strategy != StrategyCascade {
+		return strategy
+	}
+	if explanation.Model == "" {
+		return StrategyCascade + "/" + StageFuzzy
+	}
+	return StrategyCascade + "/" + explanation.Model
+}
+
+// Calibrator maps one confidence source's raw scores to the probability that a result is the product asked for
+type Calibrator struct {
+	Method string `json:"method"`
+
+	// Platt scaling: p = sigmoid(A*raw + B)
+	A float64 `json:"a,omitempty"`
+	B float64 `json:"b,omitempty"`
+
+	// Isotonic regression: a non-decreasing curve through (Points[i], Values[i]), interpolated between points
+	// and flat beyond the ends
+	Points []float64 `json:"points,omitempty"`
+	Values []float64 `json:"values,omitempty"`
+
+	Examples  int `json:"examples"` // Labelled results the calibrator was fitted to
+	Positives int `json:"positives"`
+}
+
+// Apply maps a raw score to a probability; an unknown method leaves it unchanged
+func (c Calibrator) Apply(raw float64) float64 {
+	switch c.Method {
+	case CalibrationPlatt:
+		return sigmoid(c.A*raw + c.B)
+	case CalibrationIsotonic:
+		n := len(c.Points)
+		if n == 0 || n != len(c.Values) {
+			return raw
+		}
+		i := sort.SearchFloat64s(c.Points, raw)
+		switch {
+		case i == 0:
+			return c.Values[0]
+		case i == n:
+			return c.Values[n-1]
+		}
+		x0, x1 := c.Points[i-1], c.Points[i]
+		return c.Values[i-1] + (c.Values[i]-c.Values[i-1])*(raw-x0)/(x1-x0)
+	}
+	return raw
+}
+
+// FitPlatt fits p = sigmoid(a*score + b) to labelled scores by Newton's method on the log loss. Targets are
+// smoothed towards the class priors as Platt proposed, so a separable sample does not drive the sigmoid to a step.
+func FitPlatt(scores []float64, relevant []bool) Calibrator {
+	positives := countRelevant(relevant)
+	negatives := len(scores) - positives
+	targetPositive := (float64(positives) + 1) / (float64(positives) + 2)
+	targetNegative := 1 / (float64(negatives) + 2)
+
+	a, b := 0.0, math.Log((float64(positives)+1)/(float64(negatives)+1))
+	for iteration := 0; iteration < 100; iteration++ {
+		var gradientA, gradientB, hessianAA, hessianAB, hessianBB float64
+		for i, score := range scores {
+			target := targetNegative
+			if relevant[i] {
+				target = targetPositive
+			}
+			p := sigmoid(a*score + b)
+			residual, weight := p-target, math.Max(p*(1-p), 1e-12)
+			gradientA += residual * score
+			gradientB += residual
+			hessianAA += weight * score * score
+			hessianAB += weight * score
+			hessianBB += weight
+		}
+		// A small ridge keeps the step defined when every score is the same
+		hessianAA += 1e-6
+		hessianBB += 1e-6
+		determinant := hessianAA*hessianBB - hessianAB*hessianAB
+		if determinant == 0 {
+			break
+		}
+		stepA := (hessianBB*gradientA - hessianAB*gradientB) / determinant
+		stepB := (hessianAA*gradientB - hessianAB*gradientA) / determinant
+		a, b = a-stepA, b-stepB
+		if math.Abs(stepA) < 1e-9 && math.Abs(stepB) < 1e-9 {
+			break
+		}
+	}
+	return Calibrator{Method: CalibrationPlatt, A: a, B: b, Examples: len(scores), Positives: positives}
+}
+
+// FitIsotonic fits the non-decreasing step function closest to the labels by pool adjacent violators. Each
+// pooled block becomes one point at its mean score holding its share of relevant results.
+func FitIsotonic(scores []float64, relevant []bool) Calibrator {
+	order := make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return scores[order[i]] < scores[order[j]] })
+
+	type block struct{ sumScore, sumLabel, weight float64 }
+	var blocks []block
+	for position, index := range order {
+		label := 0.0
+		if relevant[index] {
+			label = 1
+		}
+		// Equal scores share one block, so the curve gives them one value
+		if position > 0 && scores[index] == scores[order[position-1]] {
+			last := &blocks[len(blocks)-1]
+			last.sumScore += scores[index]
+			last.sumLabel += label
+			last.weight++
+		} else {
+			blocks = append(blocks, block{scores[index], label, 1})
+		}
+		for len(blocks) > 1 {
+			last, previous := blocks[len(blocks)-1], blocks[len(blocks)-2]
+			if previous.sumLabel/previous.weight < last.sumLabel/last.weight {
+				break
+			}
+			blocks = blocks[:len(blocks)-1]
+			blocks[len(blocks)-1] = block{previous.sumScore + last.sumScore, previous.sumLabel + last.sumLabel, previous.weight + last.weight}
+		}
+	}
+
+	calibrator := Calibrator{Method: CalibrationIsotonic, Examples: len(scores), Positives: countRelevant(relevant)}
+	for _, b := range blocks {
+		calibrator.Points = append(calibrator.Points, b.sumScore/b.weight)
+		calibrator.Values = append(calibrator.Values, b.sumLabel/b.weight)
+	}
+	return calibrator
+}
+
+// Calibration holds a calibrator per confidence source, keyed by strategy name, cascade stage, prompts.Extraction
+// or SourceCSSFallback. It is written by cmd/calibrate.
+type Calibration struct {
+	Sources   map[string]Calibrator `json:"sources"`
+	TrainedAt *time.Time            `json:"trainedAt,omitempty"`
+}
+
+// LoadCalibration reads a calibration file; a missing file or path leaves every source uncalibrated
+func LoadCalibration(path string) (Calibration, error) {
+	calibration := Calibration{Sources: make(map[string]Calibrator)}
+	if path == "" {
+		return calibration, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return calibration, nil
+	} else if err != nil {
+		return calibration, err
+	}
+
+	var loaded Calibration
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return calibration, fmt.Errorf("parse %s: %v", path, err)
+	}
+	if loaded.Sources == nil {
+		loaded.Sources = make(map[string]Calibrator)
+	}
+	return loaded, nil
+}
+
+// SaveCalibration writes calibration to path as indented JSON
+func SaveCalibration(path string, calibration Calibration) error {
+	data, err := json.MarshalIndent(calibration, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Calibrate maps a raw confidence from source to a calibrated probability and names the method used. A source
+// without a calibrator keeps its raw confidence and gives no method.
+func (s *Service) Calibrate(source string, raw float64) (float64, string) {
+	calibrator, calibrated := s.calibration.Sources[source]
+	if !calibrated {
+		return raw, ""
+	}
+	return math.Max(0, math.Min(1, calibrator.Apply(raw))), calibrator.Method
+}
+
+// Calibration returns the calibrators loaded at startup
+func (s *Service) Calibration() Calibration {
+	return s.calibration
+}
+
+func countRelevant(relevant []bool) int {
+	count := 0
+	for _, r := range relevant {
+		if r {
+			count++
+		}
+	}
+	return count
+}
+
+func sigmoid(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
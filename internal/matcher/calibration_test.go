@@ -0,0 +1,151 @@
+package matcher
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+
+	"price-comparison-tool/internal/models"
+)
+
+func TestFitPlattFollowsLabelRates(t *testing.T) {
+	// Scores of 0.2 are relevant a quarter of the time and scores of 0.8 three quarters of the time
+	var scores []float64
+	var relevant []bool
+	for i := 0; i < 40; i++ {
+		scores = append(scores, 0.2, 0.8)
+		relevant = append(relevant, i%4 == 0, i%4 != 0)
+	}
+
+	calibrator := FitPlatt(scores, relevant)
+	if calibrator.Method != CalibrationPlatt || calibrator.Examples != 80 || calibrator.Positives != 40 {
+		t.Fatalf("FitPlatt() = %+v, want platt over 80 examples with 40 positives", calibrator)
+	}
+	if calibrator.A <= 0 {
+		t.Errorf("FitPlatt() slope %.3f, want positive", calibrator.A)
+	}
+	for _, test := range []struct{ score, want float64 }{{0.2, 0.25}, {0.8, 0.75}} {
+		if got := calibrator.Apply(test.score); math.Abs(got-test.want) > 0.02 {
+			t.Errorf("Apply(%.1f) = %.3f, want about %.2f", test.score, got, test.want)
+		}
+	}
+}
+
+func TestFitPlattSeparableSampleStaysFinite(t *testing.T) {
+	scores := []float64{0.1, 0.2, 0.3, 0.7, 0.8, 0.9}
+	relevant := []bool{false, false, false, true, true, true}
+
+	calibrator := FitPlatt(scores, relevant)
+	if math.IsNaN(calibrator.A) || math.IsInf(calibrator.A, 0) || math.IsNaN(calibrator.B) {
+		t.Fatalf("FitPlatt() = %+v, want finite parameters", calibrator)
+	}
+	// Targets smoothed to 1/5 and 4/5 keep the ends short of 0 and 1
+	if low, high := calibrator.Apply(0.1), calibrator.Apply(0.9); low < 0.05 || high > 0.95 || low >= high {
+		t.Errorf("Apply(0.1) = %.3f, Apply(0.9) = %.3f; want increasing and short of 0 and 1", low, high)
+	}
+}
+
+func TestFitIsotonicPoolsViolators(t *testing.T) {
+	scores := []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.5}
+	relevant := []bool{false, true, false, true, true, false}
+
+	calibrator := FitIsotonic(scores, relevant)
+	if calibrator.Method != CalibrationIsotonic || calibrator.Examples != 6 || calibrator.Positives != 3 {
+		t.Fatalf("FitIsotonic() = %+v, want isotonic over 6 examples with 3 positives", calibrator)
+	}
+	// 0.2 and 0.3 pool to 0.5, as do 0.4 with the tied pair at 0.5
+	wantPoints := []float64{0.1, 0.25, 0.4666}
+	wantValues := []float64{0, 0.5, 0.6666}
+	if len(calibrator.Points) != len(wantPoints) {
+		t.Fatalf("FitIsotonic() points %v values %v, want %v and %v", calibrator.Points, calibrator.Values, wantPoints, wantValues)
+	}
+	for i := range wantPoints {
+		if math.Abs(calibrator.Points[i]-wantPoints[i]) > 0.001 || math.Abs(calibrator.Values[i]-wantValues[i]) > 0.001 {
+			t.Errorf("FitIsotonic() point %d = (%.4f, %.4f), want (%.4f, %.4f)",
+				i, calibrator.Points[i], calibrator.Values[i], wantPoints[i], wantValues[i])
+		}
+	}
+	for i := 1; i < len(calibrator.Values); i++ {
+		if calibrator.Values[i] < calibrator.Values[i-1] {
+			t.Errorf("FitIsotonic() values %v, want non-decreasing", calibrator.Values)
+		}
+	}
+}
+
+func TestCalibratorApply(t *testing.T) {
+	isotonic := Calibrator{Method: CalibrationIsotonic, Points: []float64{0.2, 0.6}, Values: []float64{0.1, 0.5}}
+	tests := []struct {
+		calibrator Calibrator
+		raw, want  float64
+	}{
+		{isotonic, 0.0, 0.1}, // Flat below the first point
+		{isotonic, 0.4, 0.3}, // Interpolated between points
+		{isotonic, 0.9, 0.5}, // Flat beyond the last point
+		{Calibrator{Method: CalibrationPlatt, A: 0, B: 0}, 0.9, 0.5},
+		{Calibrator{Method: CalibrationIsotonic}, 0.7, 0.7}, // No points leaves the score unchanged
+		{Calibrator{Method: "unknown"}, 0.7, 0.7},
+	}
+	for _, test := range tests {
+		if got := test.calibrator.Apply(test.raw); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("%s Apply(%.1f) = %.3f, want %.3f", test.calibrator.Method, test.raw, got, test.want)
+		}
+	}
+}
+
+func TestCalibrationRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calibration", "calibration.json")
+
+	missing, err := LoadCalibration(path)
+	if err != nil || len(missing.Sources) != 0 {
+		t.Fatalf("LoadCalibration(missing) = %+v, %v; want no sources and no error", missing, err)
+	}
+
+	saved := Calibration{Sources: map[string]Calibrator{
+		StrategyFuzzy:     {Method: CalibrationPlatt, A: 4, B: -2, Examples: 10, Positives: 4},
+		SourceCSSFallback: {Method: CalibrationIsotonic, Points: []float64{0.5}, Values: []float64{0.4}},
+	}}
+	if err := SaveCalibration(path, saved); err != nil {
+		t.Fatalf("SaveCalibration() error: %v", err)
+	}
+	loaded, err := LoadCalibration(path)
+	if err != nil {
+		t.Fatalf("LoadCalibration() error: %v", err)
+	}
+	for source, want := range saved.Sources {
+		if got := loaded.Sources[source]; got.Method != want.Method || got.Apply(0.5) != want.Apply(0.5) {
+			t.Errorf("loaded %s = %+v, want %+v", source, got, want)
+		}
+	}
+}
+
+func TestCalibrationSourceNamesCascadeStage(t *testing.T) {
+	s := newTestService(t)
+	s.calibration.Sources["cascade/small"] = Calibrator{Method: CalibrationPlatt, A: 10, B: -5}
+
+	tests := []struct {
+		strategy string
+		model    string
+		want     string
+	}{
+		{StrategyFuzzy, "", StrategyFuzzy},
+		{StrategyLLM, "llama3.2", StrategyLLM},
+		{StrategyCascade, "", "cascade/fuzzy"},
+		{StrategyCascade, "small", "cascade/small"},
+	}
+	for _, test := range tests {
+		if got := CalibrationSource(test.strategy, models.ScoreExplanation{Model: test.model}); got != test.want {
+			t.Errorf("CalibrationSource(%s, model %q) = %q, want %q", test.strategy, test.model, got, test.want)
+		}
+	}
+
+	// Each stage is calibrated on its own
+	if got, method := s.Calibrate("cascade/small", 0.5); got != 0.5 || method != CalibrationPlatt {
+		t.Errorf("Calibrate(cascade/small, 0.5) = %.3f, %q; want 0.5 by platt", got, method)
+	}
+	if got, method := s.Calibrate("cascade/small", 0.9); got <= 0.9 || method != CalibrationPlatt {
+		t.Errorf("Calibrate(cascade/small, 0.9) = %.3f, %q; want it raised by platt", got, method)
+	}
+	if got, method := s.Calibrate("cascade/fuzzy", 0.9); got != 0.9 || method != "" {
+		t.Errorf("Calibrate(cascade/fuzzy, 0.9) = %.3f, %q; want it uncalibrated", got, method)
+	}
+}
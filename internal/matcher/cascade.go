@@ -0,0 +1,201 @@
+package matcher
+
+import (
+	"context"
+	"fmt"
+	"price-comparison-tool/internal/models"
+	"sync"
+	"time"
+)
+
+// Cascade stages, in the order a result passes through them
+const (
+	StageFuzzy      = "fuzzy"
+	StageSmallModel = "small-model"
+	StageLargeModel = "large-model"
+)
+
+var cascadeStages = []string{StageFuzzy, StageSmallModel, StageLargeModel}
+
+// StageReporter is a Matcher that reports per-stage counts and latencies
+type StageReporter interface {
+	StageStats() []models.StageStats
+}
+
+// stageOutcome is what a stage did with a result
+type stageOutcome int
+
+const (
+	stageResolved stageOutcome = iota
+	stageEscalated
+	stageFailed
+)
+
+type stageCounter struct {
+	resolved, escalated, failed int64
+	latency                     time.Duration
+}
+
+// cascadeStats accumulates stage counters, once per search and once for the whole process
+type cascadeStats struct {
+	mutex  sync.Mutex
+	stages map[string]*stageCounter
+}
+
+func newCascadeStats() *cascadeStats {
+	stats := &cascadeStats{stages: make(map[string]*stageCounter, len(cascadeStages))}
+	for _, stage := range cascadeStages {
+		stats.stages[stage] = &stageCounter{}
+	}
+	return stats
+}
+
+func (c *cascadeStats) record(stage string, latency time.Duration, outcome stageOutcome) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	counter := c.stages[stage]
+	counter.latency += latency
+	switch outcome {
+	case stageResolved:
+		counter.resolved++
+	case stageEscalated:
+		counter.escalated++
+	case stageFailed:
+		counter.failed++
+	}
+}
+
+// snapshot lists every stage in cascade order with the model it runs
+func (c *cascadeStats) snapshot(smallModel, largeModel string) []models.StageStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	stageModels := map[string]string{StageSmallModel: smallModel, StageLargeModel: largeModel}
+	stats := make([]models.StageStats, 0, len(cascadeStages))
+	for _, stage := range cascadeStages {
+		counter := c.stages[stage]
+		stat := models.StageStats{
+			Stage:     stage,
+			Model:     stageModels[stage],
+			Reached:   counter.resolved + counter.escalated + counter.failed,
+			Resolved:  counter.resolved,
+			Escalated: counter.escalated,
+			Failed:    counter.failed,
+		}
+		if stat.Reached > 0 {
+			stat.AvgLatencyMs = float64(counter.latency.Microseconds()) / 1000 / float64(stat.Reached)
+		}
+		stats = append(stats, stat)
+	}
+	return stats
+}
+
+// CascadeStats reports the cascade strategy's stages across every search since startup
+func (s *Service) CascadeStats() []models.StageStats {
+	return s.cascade.snapshot(s.config.CascadeSmallModel, s.config.CascadeLargeModel)
+}
+
+// cascadeMatcher spends model time only where it is needed. Fuzzy matching settles clear non-matches and clean
+// exact matches; a small model scores what is left, and a large model rescores the results the small model put
+// in the uncertain band. Bound to a search, the fuzzy base similarity is averaged with BM25, so
+// boilerplate-heavy titles do not pass on string similarity alone, and stage counts are kept for that search.
+type cascadeMatcher struct {
+	s      *Service
+	corpus *Corpus
+	search *cascadeStats // nil until bound to a search
+}
+
+func newCascadeMatcher(s *Service) cascadeMatcher {
+	return cascadeMatcher{s: s}
+}
+
+func (m cascadeMatcher) Name() string { return StrategyCascade }
+
+func (m cascadeMatcher) WithCorpus(corpus *Corpus) Matcher {
+	return cascadeMatcher{s: m.s, corpus: corpus, search: newCascadeStats()}
+}
+
+// StageStats reports the stages for the bound search, or across every search when unbound
+func (m cascadeMatcher) StageStats() []models.StageStats {
+	if m.search == nil {
+		return m.s.CascadeStats()
+	}
+	return m.search.snapshot(m.s.config.CascadeSmallModel, m.s.config.CascadeLargeModel)
+}
+
+func (m cascadeMatcher) Match(ctx context.Context, intent models.QueryIntent, product models.ProductResult) (models.ScoreExplanation, error) {
+	cfg := m.s.config
+
+	// Stage 1: fuzzy matching settles the clear cases; an accessory or another variant is never clean
+	started := time.Now()
+	fuzzy := m.fuzzyStage(intent, product.ProductName)
+	clean := fuzzy.RelevancePenalty == 0 && fuzzy.VariantPenalty == 0
+	switch {
+	case fuzzy.Score < cfg.CascadeReject:
+		m.record(StageFuzzy, started, stageResolved)
+		fuzzy.Rules = append(fuzzy.Rules, fmt.Sprintf("cascade: fuzzy score below %.2f, models skipped", cfg.CascadeReject))
+		return fuzzy, nil
+	case fuzzy.Score >= cfg.CascadeAccept && clean:
+		m.record(StageFuzzy, started, stageResolved)
+		fuzzy.Rules = append(fuzzy.Rules, fmt.Sprintf("cascade: clean fuzzy score of at least %.2f, models skipped", cfg.CascadeAccept))
+		return fuzzy, nil
+	}
+	m.record(StageFuzzy, started, stageEscalated)
+
+	// Stage 2: the small model scores the middle band
+	started = time.Now()
+	small, promptVersion, err := m.s.scoreProductMatch(ctx, cfg.CascadeSmallModel, intent, product)
+	if err != nil {
+		m.record(StageSmallModel, started, stageFailed)
+		fuzzy.Rules = append(fuzzy.Rules, fmt.Sprintf("cascade: small model %s failed: %v", cfg.CascadeSmallModel, err))
+		return fuzzy, nil
+	}
+	small.PromptVersion = promptVersion
+	if cfg.CascadeLargeModel == "" || small.Score < cfg.CascadeUncertainLow || small.Score > cfg.CascadeUncertainHigh {
+		m.record(StageSmallModel, started, stageResolved)
+		small.Rules = append(small.Rules, fmt.Sprintf("cascade: settled by small model %s", cfg.CascadeSmallModel))
+		return small, nil
+	}
+	m.record(StageSmallModel, started, stageEscalated)
+
+	// Stage 3: the large model decides what the small model was unsure about
+	started = time.Now()
+	large, promptVersion, err := m.s.scoreProductMatch(ctx, cfg.CascadeLargeModel, intent, product)
+	if err != nil {
+		m.record(StageLargeModel, started, stageFailed)
+		small.Rules = append(small.Rules, fmt.Sprintf("cascade: large model %s failed, small model score kept: %v", cfg.CascadeLargeModel, err))
+		return small, nil
+	}
+	m.record(StageLargeModel, started, stageResolved)
+	large.PromptVersion = promptVersion
+	large.Rules = append(large.Rules, fmt.Sprintf("cascade: small model %s scored %.2f, within [%.2f, %.2f]; escalated to %s",
+		cfg.CascadeSmallModel, small.Score, cfg.CascadeUncertainLow, cfg.CascadeUncertainHigh, cfg.CascadeLargeModel))
+	return large, nil
+}
+
+// fuzzyStage scores like ExplainFuzzyMatch, with the base similarity averaged with BM25 when bound to a search
+func (m cascadeMatcher) fuzzyStage(intent models.QueryIntent, productName string) models.ScoreExplanation {
+	explanation := m.s.fuzzyMatch(intent, productName)
+	var lexical float64
+	blended := m.corpus != nil && intent.Raw != "" && productName != ""
+	if blended {
+		lexical = m.corpus.Similarity(intent.Raw, productName)
+		explanation = models.ScoreExplanation{Scorer: ScorerFuzzy, BaseSimilarity: (explanation.BaseSimilarity + lexical) / 2}
+		m.s.scoreFromBase(intent, productName, &explanation)
+	}
+	m.s.explainRules(intent, productName, &explanation)
+	if blended {
+		explanation.Rules = append(explanation.Rules, fmt.Sprintf("bm25 similarity %.3f averaged into base similarity", lexical))
+	}
+	return explanation
+}
+
+// record adds a stage outcome to the process-wide counters and to the bound search's
+func (m cascadeMatcher) record(stage string, started time.Time, outcome stageOutcome) {
+	latency := time.Since(started)
+	m.s.cascade.record(stage, latency, outcome)
+	if m.search != nil {
+		m.search.record(stage, latency, outcome)
+	}
+}
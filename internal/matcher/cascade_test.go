@@ -0,0 +1,121 @@
+package matcher
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"price-comparison-tool/internal/models"
+)
+
+// modelScores is what each cascade model answers for a title; a title it has no score for fails the call
+type modelScores map[string]map[string]string
+
+// cascadeServer answers scoring prompts by model and the title named in the prompt
+func cascadeServer(t *testing.T, scores modelScores) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req OllamaRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for title, score := range scores[req.Model] {
+			if strings.Contains(req.Prompt, title) {
+				json.NewEncoder(w).Encode(OllamaResponse{Response: score, Done: true})
+				return
+			}
+		}
+		http.Error(w, "no score for prompt", http.StatusInternalServerError)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCascadeEscalatesOnlyWithinUncertainBand(t *testing.T) {
+	server := cascadeServer(t, modelScores{
+		"small": {
+			"Sony Headphones":          "0.9",
+			"Sony WH-1000XM5 Case":     "0.1",
+			"Sony Over-Ear Headphones": "0.5",
+			"Sony Studio Headphones":   "0.35", // On the edge of the band
+		},
+		"large": {"Sony Over-Ear Headphones": "0.8"},
+	})
+	s := newTestService(t)
+	s.config.OllamaHost = server.URL
+	s.config.CascadeAccept, s.config.CascadeReject = 0.95, 0.2
+	s.config.CascadeSmallModel, s.config.CascadeLargeModel = "small", "large"
+	s.config.CascadeUncertainLow, s.config.CascadeUncertainHigh = 0.35, 0.65
+
+	tests := []struct {
+		title string
+		model string // Model that settled the score, "" for the fuzzy stage
+		score float64
+	}{
+		{"Sony WH-1000XM5 Wireless Headphones", "", 1},
+		{"Bose QuietComfort Ultra Earbuds", "", 0},
+		{"Sony Headphones", "small", 0.9},
+		{"Sony WH-1000XM5 Case", "small", 0.05}, // The accessory guard halves the model's score
+		{"Sony Over-Ear Headphones", "large", 0.8},
+		{"Sony Studio Headphones", "small", 0.35}, // The large model fails, so the small model's score stands
+	}
+	var titles []string
+	for _, test := range tests {
+		titles = append(titles, test.title)
+	}
+	cascade := s.Strategy(StrategyCascade).(CorpusMatcher).WithCorpus(NewCorpus(results(titles...)))
+	intent := s.ParseQueryRules("Sony WH-1000XM5")
+
+	for _, test := range tests {
+		explanation, err := cascade.Match(context.Background(), intent, models.ProductResult{ProductName: test.title})
+		if err != nil {
+			t.Errorf("Match(%q) error: %v", test.title, err)
+			continue
+		}
+		if explanation.Model != test.model || math.Abs(explanation.Score-test.score) > 1e-9 {
+			t.Errorf("Match(%q) = %.3f from %q, want %.3f from %q", test.title, explanation.Score, explanation.Model, test.score, test.model)
+		}
+		wantSource := "cascade/fuzzy"
+		if test.model != "" {
+			wantSource = "cascade/" + test.model
+		}
+		if source := CalibrationSource(StrategyCascade, explanation); source != wantSource {
+			t.Errorf("CalibrationSource() of %q = %q, want %q", test.title, source, wantSource)
+		}
+	}
+
+	want := []models.StageStats{
+		{Stage: StageFuzzy, Reached: 6, Resolved: 2, Escalated: 4},
+		{Stage: StageSmallModel, Model: "small", Reached: 4, Resolved: 2, Escalated: 2},
+		{Stage: StageLargeModel, Model: "large", Reached: 2, Resolved: 1, Failed: 1},
+	}
+	for name, stats := range map[string][]models.StageStats{
+		"search":  cascade.(StageReporter).StageStats(),
+		"process": s.CascadeStats(),
+	} {
+		if len(stats) != len(want) {
+			t.Fatalf("%s stage stats %+v, want %d stages", name, stats, len(want))
+		}
+		for i, stat := range stats {
+			stat.AvgLatencyMs = 0
+			if stat != want[i] {
+				t.Errorf("%s stage stats %+v, want %+v", name, stat, want[i])
+			}
+		}
+	}
+
+	// Another search starts its own counts; the process-wide ones keep growing
+	next := s.Strategy(StrategyCascade).(CorpusMatcher).WithCorpus(NewCorpus(nil))
+	next.Match(context.Background(), intent, models.ProductResult{ProductName: "Sony Headphones"})
+	if fuzzy := next.(StageReporter).StageStats()[0]; fuzzy.Reached != 1 {
+		t.Errorf("new search fuzzy stage reached %d, want 1", fuzzy.Reached)
+	}
+	if fuzzy := s.CascadeStats()[0]; fuzzy.Reached != 7 {
+		t.Errorf("process-wide fuzzy stage reached %d, want 7", fuzzy.Reached)
+	}
+}
@@ -0,0 +1,206 @@
+package matcher
+
+import (
+	"price-comparison-tool/internal/identifiers"
+	"price-comparison-tool/internal/models"
+	"sort"
+	"strings"
+)
+
+// clusterThreshold is the similarity at which two offers are treated as the same product
+const clusterThreshold = 0.6
+
+// offerSignature is an offer's title read like a query, plus what clustering compares directly
+type offerSignature struct {
+	intent      models.QueryIntent
+	kind        string
+	condition   string
+	tokens      map[string]bool
+	identifiers models.Identifiers
+}
+
+// ClusterProducts groups offers of the same product across sites into canonical products. Offers are linked
+// by a shared identifier (barcode, ASIN, retailer id, or part number within a brand), or by matching brand,
+// model, variants and specs with similar titles; the most relevant offer of each group is its canonical title.
+// Groups are ordered by relevance, then lowest price.
+func (s *Service) ClusterProducts(products []models.ProductResult) []models.ProductGroup {
+	order := make([]int, len(products))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return products[order[i]].Confidence > products[order[j]].Confidence
+	})
+
+	signatures := make([]offerSignature, len(products))
+	for i, product := range products {
+		signatures[i] = s.offerSignature(product)
+	}
+
+	type cluster struct {
+		members      []int
+		similarities []float64
+	}
+	var clusters []*cluster
+	for _, i := range order {
+		var best *cluster
+		bestSimilarity := 0.0
+		for _, c := range clusters {
+			similarity := s.offerSimilarity(signatures[c.members[0]], products[c.members[0]].ProductName, signatures[i], products[i].ProductName)
+			if similarity >= clusterThreshold && similarity > bestSimilarity {
+				best, bestSimilarity = c, similarity
+			}
+		}
+		if best == nil {
+			clusters = append(clusters, &cluster{members: []int{i}, similarities: []float64{1}})
+			continue
+		}
+		best.members = append(best.members, i)
+		best.similarities = append(best.similarities, bestSimilarity)
+	}
+
+	groups := make([]models.ProductGroup, 0, len(clusters))
+	for _, c := range clusters {
+		representative := signatures[c.members[0]].intent
+		group := models.ProductGroup{
+			Title:     products[c.members[0]].ProductName,
+			Brand:     representative.Brand,
+			Model:     representative.Model,
+			Category:  products[c.members[0]].Category,
+			Relevance: products[c.members[0]].Confidence,
+		}
+
+		total := 0.0
+		for k, i := range c.members {
+			group.Offers = append(group.Offers, products[i])
+			total += c.similarities[k]
+		}
+		// A lone offer is trivially one product; otherwise confidence is the mean similarity to the canonical offer
+		group.Confidence = 1.0
+		if len(c.members) > 1 {
+			group.Confidence = (total - 1) / float64(len(c.members)-1)
+		}
+
+		group.SortOffers()
+		groups = append(groups, group)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Relevance != groups[j].Relevance {
+			return groups[i].Relevance > groups[j].Relevance
+		}
+		return groups[i].MinPrice < groups[j].MinPrice
+	})
+	return groups
+}
+
+func (s *Service) offerSignature(product models.ProductResult) offerSignature {
+	signature := offerSignature{
+		intent:    s.ParseQueryRules(product.ProductName),
+		kind:      product.Kind,
+		condition: ProductCondition(product),
+		tokens:    make(map[string]bool),
+	}
+	if signature.kind == "" {
+		signature.kind = s.taxonomy.Classify(product.ProductName, product.Category).Kind
+	}
+
+	// Title words without quantities or colours, which vary between listings of one product
+	text := stripSpecs(" " + normalizeQueryText(product.ProductName) + " ")
+	for _, token := range strings.Fields(text) {
+		if token != "-" && !isColorTerm(token) {
+			signature.tokens[token] = true
+		}
+	}
+
+	signature.identifiers = identifiers.Merge(product.Identifiers, identifiers.FromLink(product.Link))
+	return signature
+}
+
+// offerSimilarity scores how likely two offers are the same product: 1 for a shared identifier, 0 for a
+// different barcode or any conflicting brand, model, variant, spec, product kind or condition, otherwise title overlap
+// plus brand and model agreement
+func (s *Service) offerSimilarity(a offerSignature, aTitle string, b offerSignature, bTitle string) float64 {
+	brandsConflict := a.intent.Brand != "" && b.intent.Brand != "" && a.intent.Brand != b.intent.Brand
+
+	switch identifiers.Match(a.identifiers, b.identifiers) {
+	case identifiers.KindGTIN, identifiers.KindASIN, identifiers.KindSiteProductID:
+		return 1.0
+	case identifiers.KindMPN:
+		if !brandsConflict {
+			return 1.0
+		}
+	}
+	if identifiers.Conflict(a.identifiers, b.identifiers) || brandsConflict {
+		return 0
+	}
+	if a.intent.Model != "" && b.intent.Model != "" && a.intent.Model != b.intent.Model {
+		return 0
+	}
+	if a.kind != b.kind || a.condition != b.condition {
+		return 0
+	}
+	if len(s.VariantConflicts(a.intent, bTitle)) > 0 || len(s.VariantConflicts(b.intent, aTitle)) > 0 {
+		return 0
+	}
+	if specsDiffer(a.intent.Specs, b.intent.Specs) {
+		return 0
+	}
+
+	similarity := 0.5 * overlapCoefficient(a.tokens, b.tokens)
+	if a.intent.Brand != "" && a.intent.Brand == b.intent.Brand {
+		similarity += 0.25
+	}
+	if a.intent.Model != "" && a.intent.Model == b.intent.Model {
+		similarity += 0.25
+	}
+	return similarity
+}
+
+// specsDiffer reports a dimension both titles state with no value in common, such as 200g against 500g
+func specsDiffer(a, b []models.Spec) bool {
+	for _, wanted := range a {
+		stated, matched := false, false
+		for _, offered := range b {
+			if offered.Dimension != wanted.Dimension {
+				continue
+			}
+			stated = true
+			if specsEqual(wanted.Value, offered.Value, specWeights[wanted.Dimension].tolerance) {
+				matched = true
+				break
+			}
+		}
+		if stated && !matched {
+			return true
+		}
+	}
+	return false
+}
+
+// overlapCoefficient is the share of the smaller token set found in the larger, so a terse listing title
+// still matches a verbose one for the same product
+func overlapCoefficient(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	shared := 0
+	for token := range a {
+		if b[token] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a))
+}
+
+func isColorTerm(token string) bool {
+	for _, color := range colorTerms {
+		if token == color {
+			return true
+		}
+	}
+	return false
+}
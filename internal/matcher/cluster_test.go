@@ -0,0 +1,148 @@
+package matcher
+
+import (
+	"strings"
+	"testing"
+
+	"price-comparison-tool/internal/models"
+)
+
+// groupSites describes each group by its offers' sites in price order, e.g. "b,a|c"
+func groupSites(groups []models.ProductGroup) string {
+	var described []string
+	for _, group := range groups {
+		var sites []string
+		for _, offer := range group.Offers {
+			sites = append(sites, offer.Site)
+		}
+		described = append(described, strings.Join(sites, ","))
+	}
+	return strings.Join(described, "|")
+}
+
+func TestClusterProducts(t *testing.T) {
+	s := newTestService(t)
+
+	offer := func(site, title, price string, confidence float64) models.ProductResult {
+		return models.ProductResult{Site: site, ProductName: title, Price: price, Currency: "USD", Confidence: confidence}
+	}
+	withGTIN := func(product models.ProductResult, gtin string) models.ProductResult {
+		product.Identifiers = models.Identifiers{GTIN: gtin}
+		return product
+	}
+	refurbished := func(product models.ProductResult) models.ProductResult {
+		product.Condition = "refurbished"
+		return product
+	}
+
+	tests := []struct {
+		name     string
+		products []models.ProductResult
+		want     string
+	}{
+		{"same product across sites", []models.ProductResult{
+			offer("a", "Apple iPhone 15 128GB Black", "799.00", 0.9),
+			offer("b", "iPhone 15 (128 GB) - Blue", "749.00", 0.8),
+		}, "b,a"},
+		{"another storage", []models.ProductResult{
+			offer("a", "Apple iPhone 15 128GB Black", "799.00", 0.9),
+			offer("b", "Apple iPhone 15 256GB Black", "899.00", 0.8),
+		}, "a|b"},
+		{"another model", []models.ProductResult{
+			offer("a", "Samsung Galaxy S24 Ultra 256GB", "1199.00", 0.9),
+			offer("b", "Samsung Galaxy S23 Ultra 256GB", "999.00", 0.8),
+		}, "a|b"},
+		{"a case for the phone", []models.ProductResult{
+			offer("a", "Apple iPhone 15 128GB Black", "799.00", 0.9),
+			offer("b", "Silicone Case for Apple iPhone 15", "19.99", 0.4),
+		}, "a|b"},
+		{"refurbished", []models.ProductResult{
+			offer("a", "Apple iPhone 15 128GB Black", "799.00", 0.9),
+			refurbished(offer("b", "Apple iPhone 15 128GB Black", "599.00", 0.9)),
+		}, "b|a"}, // Equal relevance puts the cheaper group first
+		{"shared barcode", []models.ProductResult{
+			withGTIN(offer("a", "Sony WH-1000XM5 Wireless Headphones", "399.00", 0.9), "4548736132566"),
+			withGTIN(offer("b", "Noise Cancelling Over-Ear Bluetooth Headset, Black", "329.00", 0.5), "4548736132566"),
+		}, "b,a"},
+		{"different barcodes", []models.ProductResult{
+			withGTIN(offer("a", "Sony WH-1000XM5 Wireless Headphones", "399.00", 0.9), "4548736132566"),
+			withGTIN(offer("b", "Sony WH-1000XM5 Wireless Headphones", "379.00", 0.9), "4548736132573"),
+		}, "b|a"},
+	}
+	for _, test := range tests {
+		groups := s.ClusterProducts(test.products)
+		if got := groupSites(groups); got != test.want {
+			t.Errorf("%s: ClusterProducts() grouped %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestClusterProductsGroupSummary(t *testing.T) {
+	s := newTestService(t)
+	products := []models.ProductResult{
+		{Site: "c", ProductName: "Nescafe Classic Instant Coffee 200g Jar", Price: "9.49", Currency: "USD", Confidence: 0.6},
+		{Site: "a", ProductName: "Nescafe Classic Coffee 200g", Price: "8.99", Currency: "USD", Confidence: 0.9},
+		{Site: "b", ProductName: "Nescafe Classic Instant Coffee, 50g Pouch", Price: "2.99", Currency: "USD", Confidence: 0.7},
+	}
+
+	groups := s.ClusterProducts(products)
+	if len(groups) != 2 {
+		t.Fatalf("ClusterProducts() = %d groups (%s), want the 200g jars and the 50g pouch apart", len(groups), groupSites(groups))
+	}
+	jars := groups[0]
+	if jars.Title != products[1].ProductName || jars.Relevance != 0.9 {
+		t.Errorf("first group titled %q with relevance %.2f, want the most relevant offer's title and 0.90", jars.Title, jars.Relevance)
+	}
+	if jars.MinPrice != 8.99 || jars.MaxPrice != 9.49 || jars.Currency != "USD" {
+		t.Errorf("first group prices %.2f-%.2f %s, want 8.99-9.49 USD", jars.MinPrice, jars.MaxPrice, jars.Currency)
+	}
+	if jars.Confidence <= clusterThreshold || jars.Confidence > 1 {
+		t.Errorf("first group confidence %.3f, want a similarity above the %.2f threshold", jars.Confidence, clusterThreshold)
+	}
+	if groups[1].Confidence != 1 {
+		t.Errorf("lone offer group confidence %.3f, want 1", groups[1].Confidence)
+	}
+}
+
+func TestOverlapCoefficient(t *testing.T) {
+	set := func(words string) map[string]bool {
+		tokens := make(map[string]bool)
+		for _, word := range strings.Fields(words) {
+			tokens[word] = true
+		}
+		return tokens
+	}
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"iphone 15", "apple iphone 15 smartphone", 1},
+		{"iphone 15 pro", "apple iphone 15", 2.0 / 3},
+		{"iphone", "", 0},
+	}
+	for _, test := range tests {
+		if got := overlapCoefficient(set(test.a), set(test.b)); got != test.want {
+			t.Errorf("overlapCoefficient(%q, %q) = %.3f, want %.3f", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestSpecsDiffer(t *testing.T) {
+	specs := func(text string) []models.Spec {
+		return ExtractSpecs(" " + normalizeQueryText(text) + " ")
+	}
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"200g", "500g", true},
+		{"1kg", "1000 g", false},
+		{"128GB 8GB RAM", "128GB", false}, // A dimension one title leaves out is not a difference
+		{"200g", "", false},
+	}
+	for _, test := range tests {
+		if got := specsDiffer(specs(test.a), specs(test.b)); got != test.want {
+			t.Errorf("specsDiffer(%q, %q) = %v, want %v", test.a, test.b, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package matcher
+
+import (
+	"price-comparison-tool/internal/models"
+	"strings"
+)
+
+// Listing conditions. Retail listings that state none are new.
+const (
+	ConditionNew         = "new"
+	ConditionOpenBox     = "open-box"
+	ConditionRefurbished = "refurbished" // Includes Amazon "Renewed" and certified or seller refurbished
+	ConditionUsed        = "used"
+	ConditionForParts    = "for-parts"
+
+	// ConditionAny disables the condition filter of a request
+	ConditionAny = "any"
+)
+
+// conditionTerms maps condition wording to a canonical condition, most specific first
+var conditionTerms = []struct{ term, condition string }{
+	{"for parts", ConditionForParts},
+	{"not working", ConditionForParts},
+	{"open box", ConditionOpenBox},
+	{"open-box", ConditionOpenBox},
+	{"box open", ConditionOpenBox},
+	{"refurbished", ConditionRefurbished},
+	{"refurb", ConditionRefurbished},
+	{"reconditioned", ConditionRefurbished},
+	{"renewed", ConditionRefurbished},
+	{"like new", ConditionUsed},
+	{"never used", ConditionNew}, // Ahead of "used", which these contain
+	{"unused", ConditionNew},
+	{"brand new", ConditionNew},
+	{"new with tags", ConditionNew},
+	{"pre-owned", ConditionUsed},
+	{"preowned", ConditionUsed},
+	{"pre owned", ConditionUsed},
+	{"second hand", ConditionUsed},
+	{"second-hand", ConditionUsed},
+	{"used", ConditionUsed},
+	{"new", ConditionNew},
+}
+
+// conditionPunctuation separates labels such as "Open Box:" or "[Renewed]" from the words around them
+var conditionPunctuation = strings.NewReplacer(":", " ", "!", " ", "[", " ", "]", " ")
+
+// schemaConditions maps schema.org itemCondition values, with or without the URL prefix
+var schemaConditions = map[string]string{
+	"newcondition":         ConditionNew,
+	"refurbishedcondition": ConditionRefurbished,
+	"usedcondition":        ConditionUsed,
+	"damagedcondition":     ConditionForParts,
+}
+
+// IsValidCondition reports whether condition can be used as a request filter; "" selects the default
+func IsValidCondition(condition string) bool {
+	switch condition {
+	case "", ConditionAny, ConditionNew, ConditionOpenBox, ConditionRefurbished, ConditionUsed, ConditionForParts:
+		return true
+	}
+	return false
+}
+
+// DetectCondition finds condition wording in a title or label, "" when there is none
+func DetectCondition(text string) string {
+	text = conditionPunctuation.Replace(normalizeQueryText(text))
+	padded := " " + strings.Join(strings.Fields(text), " ") + " "
+	for _, c := range conditionTerms {
+		if strings.Contains(padded, " "+c.term+" ") {
+			return c.condition
+		}
+	}
+	return ""
+}
+
+// NormalizeCondition maps a site label ("Pre-Owned"), an extracted value ("refurbished") or a schema.org
+// itemCondition ("https://schema.org/UsedCondition") to a canonical condition, "" when unrecognised
+func NormalizeCondition(label string) string {
+	label = strings.TrimSpace(label)
+	if i := strings.LastIndex(label, "/"); i >= 0 && strings.HasSuffix(strings.ToLower(label), "condition") {
+		label = label[i+1:]
+	}
+	if condition, known := schemaConditions[strings.ToLower(label)]; known {
+		return condition
+	}
+	return DetectCondition(label)
+}
+
+// ProductCondition decides a result's condition: a label from the site or its structured data first, then
+// wording in the title, otherwise new
+func ProductCondition(product models.ProductResult) string {
+	if condition := NormalizeCondition(product.Condition); condition != "" {
+		return condition
+	}
+	if condition := DetectCondition(product.ProductName); condition != "" {
+		return condition
+	}
+	return ConditionNew
+}
+
+// WantedCondition is the condition results are filtered to: the request's, else one named in the query,
+// else new so prices are compared like for like. It returns "" when the request accepts any condition.
+func WantedCondition(req models.PriceRequest, intent models.QueryIntent) string {
+	switch {
+	case req.Condition == ConditionAny:
+		return ""
+	case req.Condition != "":
+		return req.Condition
+	case intent.Condition != "":
+		return intent.Condition
+	}
+	return ConditionNew
+}
+
+// conditionFeatures flags a title in the condition the query names, or in another condition; a query naming
+// none is taken to want new items, and a new title for such a query is neutral
+func conditionFeatures(intent models.QueryIntent, productName string) (match, conflict float64) {
+	wanted := intent.Condition
+	if wanted == "" {
+		wanted = ConditionNew
+	}
+	found := DetectCondition(productName)
+	switch {
+	case found == "":
+		return 0.0, 0.0
+	case found == wanted:
+		if intent.Condition == "" {
+			return 0.0, 0.0
+		}
+		return 1.0, 0.0
+	}
+	return 0.0, 1.0
+}
@@ -0,0 +1,70 @@
+package matcher
+
+import (
+	"testing"
+
+	"price-comparison-tool/internal/models"
+)
+
+func TestDetectCondition(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"Apple iPhone 15 128GB", ""},
+		{"Apple iPhone 15 128GB - Brand New, Sealed", ConditionNew},
+		{"Nikon Z6 II body, never used", ConditionNew},
+		{"Unused Nintendo Switch OLED", ConditionNew},
+		{"Levi's 501 jeans new with tags", ConditionNew},
+		{"Sony WH-1000XM5 (Used - Good)", ConditionUsed},
+		{"Pre-Owned iPad Air", ConditionUsed},
+		{"Galaxy S23 like new", ConditionUsed},
+		{"Open Box: Dell XPS 13", ConditionOpenBox},
+		{"iPhone 14 [Renewed]", ConditionRefurbished},
+		{"iPhone 13 refurbished, like new", ConditionRefurbished},
+		{"MacBook Pro for parts / not working", ConditionForParts},
+		{"Newton's cradle", ""},
+	}
+	for _, test := range tests {
+		if got := DetectCondition(test.text); got != test.want {
+			t.Errorf("DetectCondition(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestNormalizeCondition(t *testing.T) {
+	tests := []struct {
+		label, want string
+	}{
+		{"https://schema.org/NewCondition", ConditionNew},
+		{"http://schema.org/UsedCondition", ConditionUsed},
+		{"schema.org/RefurbishedCondition", ConditionRefurbished},
+		{"DamagedCondition", ConditionForParts},
+		{" Pre-Owned ", ConditionUsed},
+		{"New (other): never used", ConditionNew},
+		{"refurbished", ConditionRefurbished},
+		{"https://schema.org/MintCondition", ""},
+		{"", ""},
+	}
+	for _, test := range tests {
+		if got := NormalizeCondition(test.label); got != test.want {
+			t.Errorf("NormalizeCondition(%q) = %q, want %q", test.label, got, test.want)
+		}
+	}
+}
+
+func TestWantedCondition(t *testing.T) {
+	tests := []struct {
+		request, query, want string
+	}{
+		{"", "", ConditionNew},
+		{"", ConditionRefurbished, ConditionRefurbished},
+		{ConditionUsed, ConditionRefurbished, ConditionUsed},
+		{ConditionAny, ConditionRefurbished, ""},
+	}
+	for _, test := range tests {
+		got := WantedCondition(models.PriceRequest{Condition: test.request}, models.QueryIntent{Condition: test.query})
+		if got != test.want {
+			t.Errorf("WantedCondition(request %q, query %q) = %q, want %q", test.request, test.query, got, test.want)
+		}
+	}
+}
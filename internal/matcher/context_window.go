@@ -0,0 +1,65 @@
+package matcher
+
+import "strings"
+
+// modelContextWindows lists known context sizes (in tokens) for models we run through Ollama.
+// Keys are matched as prefixes of the configured model name so tags like ":mini" or ":8b" resolve.
+var modelContextWindows = map[string]int{
+	"phi3:mini":   4096,
+	"phi3:medium": 4096,
+	"phi3":        4096,
+	"phi3.5":      8192,
+	"llama3.2":    8192,
+	"llama3.1":    8192,
+	"llama3":      8192,
+	"mistral":     8192,
+	"gemma2":      8192,
+	"qwen2.5":     8192,
+}
+
+const (
+	defaultContextWindow = 2048
+
+	// Tokens reserved for the extraction prompt instructions and the JSON response
+	extractionPromptTokens   = 600
+	extractionResponseTokens = 1200
+
+	// Conservative characters-per-token ratio for scraped page text
+	charsPerToken = 3
+
+	minExtractionChunkChars = 1500
+)
+
+// ContextWindow returns the context size in tokens for the configured model
+func (s *Service) ContextWindow() int {
+	return s.contextWindowFor(s.config.OllamaModel)
+}
+
+// contextWindowFor returns the context size in tokens for a model; LLM_CONTEXT_WINDOW overrides it for the
+// configured model only
+func (s *Service) contextWindowFor(model string) int {
+	if s.config.LLMContextWindow > 0 && model == s.config.OllamaModel {
+		return s.config.LLMContextWindow
+	}
+
+	model = strings.ToLower(model)
+	bestPrefix := ""
+	window := defaultContextWindow
+	for prefix, size := range modelContextWindows {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(bestPrefix) {
+			bestPrefix = prefix
+			window = size
+		}
+	}
+	return window
+}
+
+// ExtractionChunkSize returns how many characters of page content fit into one extraction prompt
+func (s *Service) ExtractionChunkSize() int {
+	available := s.ContextWindow() - extractionPromptTokens - extractionResponseTokens
+	chars := available * charsPerToken
+	if chars < minExtractionChunkChars {
+		return minExtractionChunkChars
+	}
+	return chars
+}
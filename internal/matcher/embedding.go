@@ -0,0 +1,200 @@
+package matcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"price-comparison-tool/internal/llm"
+	"price-comparison-tool/internal/models"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Cosine similarity at or below this floor maps to a base score of 0; embedding models rarely go lower
+	// for unrelated product titles, so the raw value would overstate similarity
+	embeddingSimilarityFloor = 0.45
+
+	// After a failed embeddings call the scorer is bypassed for this long so requests fall back quickly
+	embeddingRetryCooldown = time.Minute
+)
+
+// ErrEmbeddingsUnavailable is returned while the embeddings endpoint is in its failure cooldown
+var ErrEmbeddingsUnavailable = errors.New("embeddings endpoint unavailable")
+
+type ollamaEmbeddingRequest struct {
+	Model  string `json:"model"`
+	Prompt string `json:"prompt"`
+}
+
+type ollamaEmbeddingResponse struct {
+	Embedding []float64 `json:"embedding"`
+}
+
+// embeddingCache is a bounded in-process cache of text vectors, evicting the oldest entries first
+type embeddingCache struct {
+	mutex    sync.RWMutex
+	capacity int
+	vectors  map[string][]float64
+	order    []string
+}
+
+func newEmbeddingCache(capacity int) *embeddingCache {
+	return &embeddingCache{
+		capacity: capacity,
+		vectors:  make(map[string][]float64),
+	}
+}
+
+func (c *embeddingCache) get(key string) ([]float64, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	vector, exists := c.vectors[key]
+	return vector, exists
+}
+
+func (c *embeddingCache) put(key string, vector []float64) {
+	if c.capacity <= 0 {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, exists := c.vectors[key]; exists {
+		return
+	}
+	for len(c.order) >= c.capacity {
+		delete(c.vectors, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.vectors[key] = vector
+	c.order = append(c.order, key)
+}
+
+// Embed returns the embedding vector for text, served from the cache when possible
+func (s *Service) Embed(ctx context.Context, text string) ([]float64, error) {
+	key := strings.ToLower(strings.Join(strings.Fields(text), " "))
+	if vector, exists := s.embeddings.get(key); exists {
+		return vector, nil
+	}
+
+	if until, _ := s.embeddingsDownUntil.Load().(time.Time); time.Now().Before(until) {
+		return nil, ErrEmbeddingsUnavailable
+	}
+
+	vector, err := s.callEmbeddings(ctx, key)
+	if err != nil {
+		if ctx.Err() == nil && !errors.Is(err, llm.ErrDeadlineTooSoon) {
+			s.embeddingsDownUntil.Store(time.Now().Add(embeddingRetryCooldown))
+			log.Printf("❌ Embeddings unavailable, using fuzzy matching for %s: %v", embeddingRetryCooldown, err)
+		}
+		return nil, err
+	}
+
+	s.embeddings.put(key, vector)
+	return vector, nil
+}
+
+func (s *Service) callEmbeddings(ctx context.Context, text string) ([]float64, error) {
+	release, err := s.scheduler.Acquire(ctx, llm.PriorityBackground)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	jsonData, err := json.Marshal(ollamaEmbeddingRequest{
+		Model:  s.config.EmbeddingModel,
+		Prompt: text,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.config.OllamaHost+"/api/embeddings", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embeddings endpoint returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var embeddingResp ollamaEmbeddingResponse
+	if err := json.Unmarshal(body, &embeddingResp); err != nil {
+		return nil, err
+	}
+	if len(embeddingResp.Embedding) == 0 {
+		return nil, fmt.Errorf("empty embedding from model %s", s.config.EmbeddingModel)
+	}
+
+	return embeddingResp.Embedding, nil
+}
+
+// EmbeddingProductMatch scores a product by cosine similarity of query and title embeddings,
+// combined with the same brand, model, spec and accessory adjustments as FuzzyProductMatch
+func (s *Service) EmbeddingProductMatch(ctx context.Context, query, productName string) (float64, error) {
+	explanation, err := s.embeddingMatch(ctx, s.ParseQueryRules(query), productName)
+	return explanation.Score, err
+}
+
+func (s *Service) embeddingMatch(ctx context.Context, intent models.QueryIntent, productName string) (models.ScoreExplanation, error) {
+	explanation := models.ScoreExplanation{Scorer: ScorerEmbedding}
+	if intent.Raw == "" || productName == "" {
+		return explanation, nil
+	}
+
+	queryVector, err := s.Embed(ctx, intent.Raw)
+	if err != nil {
+		return explanation, err
+	}
+	productVector, err := s.Embed(ctx, productName)
+	if err != nil {
+		return explanation, err
+	}
+
+	similarity := cosineSimilarity(queryVector, productVector)
+	score := (similarity - embeddingSimilarityFloor) / (1 - embeddingSimilarityFloor)
+	if score < 0 {
+		score = 0
+	}
+	explanation.BaseSimilarity = score
+
+	s.scoreFromBase(intent, productName, &explanation)
+	return explanation, nil
+}
+
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,138 @@
+package matcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// embeddingServer serves fixed vectors by prompt, failing for unknown prompts, and counts the calls it gets
+func embeddingServer(t *testing.T, vectors map[string][]float64) (*httptest.Server, *int32) {
+	t.Helper()
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		var req ollamaEmbeddingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		vector, known := vectors[req.Prompt]
+		if !known {
+			http.Error(w, "model not loaded", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(ollamaEmbeddingResponse{Embedding: vector})
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b []float64
+		want float64
+	}{
+		{[]float64{1, 2, 3}, []float64{2, 4, 6}, 1},
+		{[]float64{1, 0}, []float64{0, 1}, 0},
+		{[]float64{1, 0}, []float64{-1, 0}, -1},
+		{[]float64{3, 4}, []float64{4, 3}, 0.96},
+		{[]float64{1, 0}, []float64{1, 0, 0}, 0}, // Vectors from different models
+		{[]float64{0, 0}, []float64{1, 0}, 0},
+		{nil, nil, 0},
+	}
+	for _, test := range tests {
+		if got := cosineSimilarity(test.a, test.b); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("cosineSimilarity(%v, %v) = %.3f, want %.3f", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestEmbeddingMatchRescalesCosineAboveFloor(t *testing.T) {
+	// Cosines of 1, 0.725 and 0.3 against the query vector
+	server, calls := embeddingServer(t, map[string][]float64{
+		"sony wh-1000xm5":                        {1, 0},
+		"sony wh-1000xm5 wireless headphones":    {1, 0},
+		"sony wh-1000xm5 headphones, black":      {0.725, math.Sqrt(1 - 0.725*0.725)},
+		"bose quietcomfort ultra earbuds, white": {0.3, math.Sqrt(1 - 0.3*0.3)},
+	})
+	s := newTestService(t)
+	s.config.OllamaHost = server.URL
+
+	tests := []struct {
+		title string
+		want  float64 // Base similarity, the cosine rescaled from [floor, 1] to [0, 1]
+	}{
+		{"Sony WH-1000XM5 Wireless Headphones", 1},
+		{"Sony  WH-1000XM5 Headphones, Black", 0.5},
+		{"Bose QuietComfort Ultra Earbuds, White", 0}, // Below the floor
+	}
+	for _, test := range tests {
+		explanation, err := s.embeddingMatch(context.Background(), s.ParseQueryRules("Sony WH-1000XM5"), test.title)
+		if err != nil {
+			t.Errorf("embeddingMatch(%q) error: %v", test.title, err)
+			continue
+		}
+		if math.Abs(explanation.BaseSimilarity-test.want) > 1e-9 {
+			t.Errorf("embeddingMatch(%q) base similarity %.3f, want %.3f", test.title, explanation.BaseSimilarity, test.want)
+		}
+	}
+	// The query is embedded once and served from the cache after
+	if got := atomic.LoadInt32(calls); got != 4 {
+		t.Errorf("embeddings endpoint called %d times, want 4", got)
+	}
+}
+
+func TestEmbedCoolsDownWhenModelUnavailable(t *testing.T) {
+	server, calls := embeddingServer(t, map[string][]float64{"iphone 15": {1, 0}})
+	s := newTestService(t)
+	s.config.OllamaHost = server.URL
+
+	if _, err := s.Embed(context.Background(), "iPhone 15"); err != nil {
+		t.Fatalf("Embed() error: %v", err)
+	}
+	if _, err := s.Embed(context.Background(), "Pixel 8"); err == nil || errors.Is(err, ErrEmbeddingsUnavailable) {
+		t.Fatalf("Embed() error = %v, want the endpoint's failure", err)
+	}
+	if _, err := s.Embed(context.Background(), "Pixel 8a"); !errors.Is(err, ErrEmbeddingsUnavailable) {
+		t.Errorf("Embed() during the cooldown error = %v, want ErrEmbeddingsUnavailable", err)
+	}
+	if _, err := s.EmbeddingProductMatch(context.Background(), "iPhone 15", "Pixel 8a"); !errors.Is(err, ErrEmbeddingsUnavailable) {
+		t.Errorf("EmbeddingProductMatch() during the cooldown error = %v, want ErrEmbeddingsUnavailable", err)
+	}
+	if _, err := s.Embed(context.Background(), "iPhone 15"); err != nil {
+		t.Errorf("Embed() of a cached text during the cooldown error: %v", err)
+	}
+	if got := atomic.LoadInt32(calls); got != 2 {
+		t.Errorf("embeddings endpoint called %d times, want 2 with the cooldown skipping it", got)
+	}
+}
+
+func TestEmbeddingCacheEvictsOldest(t *testing.T) {
+	cache := newEmbeddingCache(2)
+	cache.put("a", []float64{1})
+	cache.put("b", []float64{2})
+	cache.put("a", []float64{3}) // Already cached, kept as it was
+	cache.put("c", []float64{4})
+
+	if _, cached := cache.get("a"); cached {
+		t.Error("get(a) found the oldest entry after the cache filled")
+	}
+	if vector, cached := cache.get("b"); !cached || vector[0] != 2 {
+		t.Errorf("get(b) = %v, %v; want [2]", vector, cached)
+	}
+	if _, cached := cache.get("c"); !cached {
+		t.Error("get(c) missed the newest entry")
+	}
+
+	disabled := newEmbeddingCache(0)
+	disabled.put("a", []float64{1})
+	if _, cached := disabled.get("a"); cached {
+		t.Error("a cache of size 0 kept an entry")
+	}
+}
@@ -0,0 +1,131 @@
+package matcher
+
+import (
+	"context"
+	"fmt"
+	"price-comparison-tool/internal/models"
+	"strings"
+)
+
+// Scorers reported in a ScoreExplanation
+const (
+	ScorerLLM       = "llm"
+	ScorerFuzzy     = "fuzzy"
+	ScorerEmbedding = "embedding"
+	ScorerBasic     = "basic"
+	ScorerBM25      = "bm25"
+)
+
+// ExplainFuzzyMatch scores a product like FuzzyProductMatchIntent and records the components and the rules that fired
+func (s *Service) ExplainFuzzyMatch(intent models.QueryIntent, productName string) models.ScoreExplanation {
+	explanation := s.fuzzyMatch(intent, productName)
+	s.explainRules(intent, productName, &explanation)
+	return explanation
+}
+
+// ExplainEmbeddingMatch scores a product like EmbeddingProductMatch and records the components and the rules that fired
+func (s *Service) ExplainEmbeddingMatch(ctx context.Context, intent models.QueryIntent, productName string) (models.ScoreExplanation, error) {
+	explanation, err := s.embeddingMatch(ctx, intent, productName)
+	if err != nil {
+		return explanation, err
+	}
+	s.explainRules(intent, productName, &explanation)
+	return explanation, nil
+}
+
+// scoreAttributes records the brand, model and spec bonuses and the relevance penalty, returning the bonuses' sum.
+// The relevance penalty is subtracted after clamping, with the variant penalties (see applyPenalties).
+func (s *Service) scoreAttributes(intent models.QueryIntent, productName string, explanation *models.ScoreExplanation) float64 {
+	features, weights := s.attributeFeatures(intent, productName), s.weights
+
+	explanation.BrandBonus = weights.BrandMatch*features.BrandMatch - weights.BrandConflict*features.BrandConflict
+	explanation.ModelBonus = weights.Model * features.ModelMatch
+	explanation.SpecBonus = weights.specAdjustment(features.Specs) + weights.Color*features.ColorMatch +
+		weights.ConditionMatch*features.ConditionMatch - weights.ConditionConflict*features.ConditionConflict
+	explanation.RelevancePenalty = weights.AddOnResult*features.AddOnResult + weights.PrimaryResult*features.PrimaryResult
+
+	return explanation.BrandBonus + explanation.ModelBonus + explanation.SpecBonus
+}
+
+// explainRules lists the rules behind each non-zero component, in the order they are applied. Plain scoring
+// skips it, keeping rule formatting off the path the eval and fallbacks run.
+func (s *Service) explainRules(intent models.QueryIntent, productName string, explanation *models.ScoreExplanation) {
+	productText := " " + normalizeQueryText(productName) + " "
+	add := func(format string, args ...interface{}) {
+		explanation.Rules = append(explanation.Rules, fmt.Sprintf(format, args...))
+	}
+
+	switch {
+	case explanation.BrandBonus > 0:
+		add("brand match: %s", intent.Brand)
+	case explanation.BrandBonus < 0:
+		add("different brand: %s instead of %s", strings.Join(s.brands.MentionedBrands(productText), ", "), intent.Brand)
+	}
+
+	switch {
+	case containsTerm(productText, intent.Model):
+		add("model match: %s", intent.Model)
+	case explanation.ModelBonus > 0:
+		add("model numbers partly match (+%.2f)", explanation.ModelBonus)
+	}
+
+	// A different quantity is listed with the variant conflicts
+	for _, outcome := range specOutcomes(intent.Specs, ExtractSpecs(productText)) {
+		if outcome.matched {
+			add("%s match: %s", outcome.wanted.Dimension, formatSpec(*outcome.offered))
+		}
+	}
+	if containsTerm(productText, intent.Color) {
+		add("colour match: %s", intent.Color)
+	}
+	if match, conflict := conditionFeatures(intent, productText); match > 0 {
+		add("condition match: %s", intent.Condition)
+	} else if conflict > 0 {
+		wanted := intent.Condition
+		if wanted == "" {
+			wanted = ConditionNew
+		}
+		add("condition mismatch: %s instead of %s", DetectCondition(productText), wanted)
+	}
+
+	if explanation.RelevancePenalty > 0 {
+		add("relevance penalty: %s result", s.ClassifyProduct(intent, productName).Kind)
+	}
+	if explanation.Scorer != ScorerLLM {
+		unclamped := explanation.BaseSimilarity + explanation.BrandBonus + explanation.ModelBonus + explanation.SpecBonus
+		if unclamped < 0 || unclamped > 1 {
+			add("score %.3f clamped to [0, 1]", unclamped)
+		}
+	}
+	for _, conflict := range s.VariantConflicts(intent, productName) {
+		add("variant conflict: %s", conflict)
+	}
+}
+
+// scoreFromBase adds the weighted attribute features to the base similarity and penalises accessories and other
+// variants after clamping, so bonuses cannot absorb the penalties
+func (s *Service) scoreFromBase(intent models.QueryIntent, productName string, explanation *models.ScoreExplanation) {
+	explanation.Score = explanation.BaseSimilarity + s.scoreAttributes(intent, productName, explanation)
+	s.penalise(intent, productName, explanation)
+}
+
+// penalise clamps the score to [0, 1] and subtracts the relevance penalty and the variant conflict penalties,
+// recording the variant total
+func (s *Service) penalise(intent models.QueryIntent, productName string, explanation *models.ScoreExplanation) {
+	explanation.Score, explanation.VariantPenalty = s.applyPenalties(explanation.Score, explanation.RelevancePenalty, intent, productName)
+}
+
+// llmGuards lists why an LLM score should not be trusted: a rival brand, an accessory or another variant
+func llmGuards(explanation models.ScoreExplanation, conflicts []VariantConflict) []string {
+	var reasons []string
+	if explanation.BrandBonus < 0 {
+		reasons = append(reasons, "different brand")
+	}
+	if explanation.RelevancePenalty > 0 {
+		reasons = append(reasons, "different kind of product")
+	}
+	for _, conflict := range conflicts {
+		reasons = append(reasons, conflict.String())
+	}
+	return reasons
+}
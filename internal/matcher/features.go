@@ -0,0 +1,56 @@
+package matcher
+
+import (
+	"price-comparison-tool/internal/models"
+	"strings"
+)
+
+// MatchFeatures reads every signal the fuzzy score weighs from a title, with the fuzzy base similarity.
+// Relevance feedback stores them so cmd/trainweights can fit the weights.
+func (s *Service) MatchFeatures(intent models.QueryIntent, productName string) models.MatchFeatures {
+	features := s.attributeFeatures(intent, productName)
+	features.BaseSimilarity = s.fuzzyMatch(intent, productName).BaseSimilarity
+	for _, conflict := range s.VariantConflicts(intent, productName) {
+		if features.Variants == nil {
+			features.Variants = make(map[string]float64)
+		}
+		features.Variants[conflict.Attribute] = 1
+	}
+	return features
+}
+
+// attributeFeatures reads the brand, model, spec, colour, condition and relevance signals; base similarity and
+// variant conflicts are left to the caller, which computes them at a different stage of scoring
+func (s *Service) attributeFeatures(intent models.QueryIntent, productName string) models.MatchFeatures {
+	queryLower := strings.ToLower(strings.TrimSpace(intent.Raw))
+	productText := " " + normalizeQueryText(productName) + " "
+
+	var features models.MatchFeatures
+	features.BrandMatch, features.BrandConflict = s.brandFeatures(intent, productText)
+	features.ModelMatch = s.modelMatch(intent, queryLower, productText)
+
+	// Quantities compared in base units, so 1TB matches 1024GB; a different capacity is recorded as -1 and weighed
+	// as a variant conflict
+	if len(intent.Specs) > 0 {
+		for _, outcome := range specOutcomes(intent.Specs, ExtractSpecs(productText)) {
+			if outcome.offered == nil {
+				continue
+			}
+			if features.Specs == nil {
+				features.Specs = make(map[string]float64)
+			}
+			features.Specs[outcome.wanted.Dimension] = -1
+			if outcome.matched {
+				features.Specs[outcome.wanted.Dimension] = 1
+			}
+		}
+	}
+
+	if containsTerm(productText, intent.Color) {
+		features.ColorMatch = 1
+	}
+	// Refurbished or used listings count against a query wanting new items, and vice versa
+	features.ConditionMatch, features.ConditionConflict = conditionFeatures(intent, productText)
+	features.AddOnResult, features.PrimaryResult = s.relevanceFeatures(intent, productName)
+	return features
+}
@@ -0,0 +1,267 @@
+package matcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"price-comparison-tool/internal/lang"
+	"price-comparison-tool/internal/llm"
+	"price-comparison-tool/internal/models"
+	"price-comparison-tool/internal/prompts"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	thousandsPattern = regexp.MustCompile(`(\d),(\d{3})(\b|[A-Za-z])`)
+	withPattern      = regexp.MustCompile(`(?i)\bw/(o\b)?`)
+	sizeLabelPattern = regexp.MustCompile(`\bsize\s+([a-z0-9.]+)`)
+	quantityPattern  = regexp.MustCompile(`\b(?:pack|set|box) of (\d+)\b|\b(\d+)\s*-?\s*(?:pack|pcs|pieces|count|ct)\b`)
+	modelTokenFilter = regexp.MustCompile(`\d`)
+	numberPattern    = regexp.MustCompile(`\d+`)
+)
+
+var variantTokens = map[string]bool{
+	"pro": true, "max": true, "plus": true, "ultra": true, "mini": true, "lite": true,
+	"se": true, "fe": true, "air": true, "neo": true, "prime": true, "oled": true,
+}
+
+var colorTerms = []string{"black", "white", "red", "blue", "green", "yellow", "purple", "pink", "gold", "silver", "gray", "grey", "titanium"}
+
+// intentCache memoises parsed queries; it is cleared wholesale when full since queries repeat in bursts
+type intentCache struct {
+	mutex   sync.RWMutex
+	intents map[string]models.QueryIntent
+}
+
+const intentCacheLimit = 10000
+
+func (c *intentCache) get(key string) (models.QueryIntent, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	intent, exists := c.intents[key]
+	return intent, exists
+}
+
+func (c *intentCache) put(key string, intent models.QueryIntent) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.intents == nil || len(c.intents) >= intentCacheLimit {
+		c.intents = make(map[string]models.QueryIntent)
+	}
+	c.intents[key] = intent
+}
+
+func (c *intentCache) clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.intents = nil
+}
+
+// ParseQuery returns the structured intent of a query, refined by the LLM when QUERY_LLM is enabled
+func (s *Service) ParseQuery(ctx context.Context, query string) models.QueryIntent {
+	intent := s.ParseQueryRules(query)
+	if !s.config.QueryLLM {
+		return intent
+	}
+
+	key := "llm:" + strings.ToLower(strings.TrimSpace(query))
+	if cached, exists := s.intents.get(key); exists {
+		return cached
+	}
+
+	refined, err := s.refineIntentWithLLM(ctx, intent)
+	if err != nil {
+		log.Printf("Query LLM pass failed, using rule-based intent: %v", err)
+		return intent
+	}
+	s.intents.put(key, refined)
+	return refined
+}
+
+// ParseQueryRules extracts brand, model and attributes from a query using rules only
+func (s *Service) ParseQueryRules(query string) models.QueryIntent {
+	key := strings.ToLower(strings.TrimSpace(query))
+	if cached, exists := s.intents.get(key); exists {
+		return cached
+	}
+
+	intent := s.parseQueryRules(query)
+	s.intents.put(key, intent)
+	return intent
+}
+
+func (s *Service) parseQueryRules(query string) models.QueryIntent {
+	intent := models.QueryIntent{Raw: query, Source: "rules"}
+
+	text := " " + normalizeQueryText(query) + " "
+
+	// Quantities in base units; storage is the largest capacity that is not RAM
+	intent.Specs = ExtractSpecs(text)
+	bestStorageGB := 0.0
+	for _, spec := range intent.Specs {
+		switch spec.Dimension {
+		case DimensionStorage:
+			if spec.Value > bestStorageGB {
+				bestStorageGB = spec.Value
+				intent.Storage = strings.ToUpper(strings.ReplaceAll(spec.Raw, " ", ""))
+			}
+		case DimensionLength:
+			if intent.Size == "" {
+				intent.Size = spec.Raw
+			}
+		}
+	}
+
+	if match := sizeLabelPattern.FindStringSubmatch(text); match != nil && intent.Size == "" {
+		intent.Size = match[1]
+	}
+
+	if match := quantityPattern.FindStringSubmatch(text); match != nil {
+		value := match[1]
+		if value == "" {
+			value = match[2]
+		}
+		intent.Quantity, _ = strconv.Atoi(value)
+	}
+
+	for _, color := range colorTerms {
+		if strings.Contains(text, " "+color+" ") {
+			intent.Color = color
+			break
+		}
+	}
+
+	intent.Condition = DetectCondition(text)
+
+	// Brand from a brand name, alias or transliteration, otherwise from a product line that implies it
+	var brandTerms []string
+	if match, found := s.brands.Detect(query); found {
+		intent.Brand = match.Brand
+		intent.ProductLine = match.Line
+		intent.Category = match.Category
+		brandTerms = append(brandTerms, match.Term, match.Brand, match.Line)
+	}
+
+	// Category and kind from the taxonomy, so "iphone 16 case" asks for an accessory rather than a phone
+	classification := s.taxonomy.Classify(query, intent.Category)
+	if intent.Category == "" {
+		intent.Category = classification.Category
+	}
+	intent.Kind = classification.Kind
+
+	// Model: the first remaining token with a digit once specs and known words are removed
+	remainder := stripSpecs(text)
+	remainder = sizeLabelPattern.ReplaceAllString(remainder, " ")
+	remainder = quantityPattern.ReplaceAllString(remainder, " ")
+	for _, brandTerm := range brandTerms {
+		if brandTerm != "" {
+			remainder = strings.Replace(remainder, " "+brandTerm+" ", " ", 1)
+		}
+	}
+	for _, token := range strings.Fields(remainder) {
+		if token == intent.Brand {
+			continue
+		}
+		if variantTokens[token] {
+			intent.Variants = append(intent.Variants, token)
+			continue
+		}
+		if intent.Model == "" && modelTokenFilter.MatchString(token) {
+			intent.Model = token
+		}
+	}
+
+	return intent
+}
+
+// normalizeQueryText lowercases, joins thousands separators ("5,000mAh"), spells out "w/" and "w/o", so "15 w/ case"
+// is not read as 15 watts, and replaces punctuation that separates attributes with spaces
+func normalizeQueryText(text string) string {
+	text = thousandsPattern.ReplaceAllString(text, "$1$2$3")
+	text = withPattern.ReplaceAllStringFunc(text, func(abbreviation string) string {
+		if len(abbreviation) > 2 {
+			return " without "
+		}
+		return " with "
+	})
+	replacer := strings.NewReplacer(",", " ", ";", " ", "(", " ", ")", " ", "/", " ", "|", " ", "+", " ")
+	return strings.Join(strings.Fields(replacer.Replace(lang.Canonical(text))), " ")
+}
+
+// refineIntentWithLLM asks the model to fill attributes the rules could not find
+func (s *Service) refineIntentWithLLM(ctx context.Context, intent models.QueryIntent) (models.QueryIntent, error) {
+	llmCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	tmpl, err := s.prompts.Get(prompts.Query, prompts.Scope{})
+	if err != nil {
+		return intent, err
+	}
+	prompt, err := tmpl.Render(prompts.QueryData{Query: intent.Raw})
+	if err != nil {
+		return intent, err
+	}
+
+	response, err := s.CallOllama(llmCtx, llm.PriorityInteractive, prompt)
+	if err != nil {
+		return intent, err
+	}
+
+	var parsed models.QueryIntent
+	if err := json.Unmarshal([]byte(ExtractJSONObject(response)), &parsed); err != nil {
+		return intent, fmt.Errorf("failed to parse LLM response: %v", err)
+	}
+
+	// Rules are precise when they fire, so the LLM only fills gaps
+	refined := intent
+	fill := func(target *string, value string) {
+		if *target == "" {
+			*target = strings.ToLower(strings.TrimSpace(value))
+		}
+	}
+	if refined.Brand == "" && parsed.Brand != "" {
+		// Resolve the model's spelling to the canonical brand when the knowledge base knows it
+		if brand, exists := s.brands.Get(parsed.Brand); exists {
+			parsed.Brand = brand.Name
+		}
+	}
+	fill(&refined.Brand, parsed.Brand)
+	fill(&refined.ProductLine, parsed.ProductLine)
+	fill(&refined.Model, parsed.Model)
+	fill(&refined.Category, parsed.Category)
+	fill(&refined.Size, parsed.Size)
+	fill(&refined.Color, parsed.Color)
+	fill(&refined.Condition, parsed.Condition)
+	if refined.Storage == "" && parsed.Storage != "" {
+		refined.Storage = strings.ToUpper(strings.ReplaceAll(parsed.Storage, " ", ""))
+	}
+	if len(refined.Specs) == 0 {
+		refined.Specs = ExtractSpecs(refined.Storage + " " + refined.Size)
+	}
+	if len(refined.Variants) == 0 {
+		for _, variant := range parsed.Variants {
+			refined.Variants = append(refined.Variants, strings.ToLower(variant))
+		}
+	}
+	if refined.Quantity == 0 {
+		refined.Quantity = parsed.Quantity
+	}
+	refined.Source = "rules+llm"
+
+	return refined, nil
+}
+
+// ExtractJSONObject trims any prose or code fence the model wrapped around a JSON object
+func ExtractJSONObject(response string) string {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start < 0 || end < start {
+		return response
+	}
+	return response[start : end+1]
+}
@@ -0,0 +1,16 @@
+package matcher
+
+import "testing"
+
+func TestExtractJSONObject(t *testing.T) {
+	tests := map[string]string{
+		`{"a": 1}`: `{"a": 1}`,
+		"Here are the selectors:\n```json\n{\"candidates\": []}\n```": `{"candidates": []}`,
+		"no object here": "no object here",
+	}
+	for response, want := range tests {
+		if got := ExtractJSONObject(response); got != want {
+			t.Errorf("ExtractJSONObject(%q) = %q, want %q", response, got, want)
+		}
+	}
+}
@@ -8,10 +8,15 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
+	"price-comparison-tool/internal/brands"
 	"price-comparison-tool/internal/config"
+	"price-comparison-tool/internal/lang"
+	"price-comparison-tool/internal/llm"
 	"price-comparison-tool/internal/models"
-	"regexp"
+	"price-comparison-tool/internal/prompts"
+	"price-comparison-tool/internal/taxonomy"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/agnivade/levenshtein"
@@ -21,12 +26,33 @@ import (
 type Service struct {
 	config     *config.Config
 	httpClient *http.Client
+	prompts    *prompts.Registry
+	scheduler  *llm.Scheduler
+	brands     *brands.KnowledgeBase
+	taxonomy   *taxonomy.Taxonomy
+	weights    Weights
+
+	calibration Calibration
+
+	strategies      map[string]Matcher
+	defaultStrategy string
+	cascade         *cascadeStats
+
+	embeddings          *embeddingCache
+	embeddingsDownUntil atomic.Value // time.Time
+
+	intents intentCache
 }
 
 type OllamaRequest struct {
-	Model  string `json:"model"`
-	Prompt string `json:"prompt"`
-	Stream bool   `json:"stream"`
+	Model   string         `json:"model"`
+	Prompt  string         `json:"prompt"`
+	Stream  bool           `json:"stream"`
+	Options *OllamaOptions `json:"options,omitempty"`
+}
+
+type OllamaOptions struct {
+	NumCtx int `json:"num_ctx,omitempty"`
 }
 
 type OllamaResponse struct {
@@ -35,93 +61,144 @@ type OllamaResponse struct {
 }
 
 func NewService(cfg *config.Config) *Service {
-	return &Service{
+	registry, err := prompts.Load(cfg.PromptsDir, prompts.ParsePins(cfg.PromptVersions))
+	if err != nil {
+		log.Fatalf("Failed to load prompt templates: %v", err)
+	}
+
+	knowledgeBase, err := brands.Load(cfg.BrandsFile)
+	if knowledgeBase == nil {
+		log.Fatalf("Failed to load brand knowledge base: %v", err)
+	} else if err != nil {
+		log.Printf("⚠️ Failed to load added brands from %s: %v", cfg.BrandsFile, err)
+	}
+
+	categories, err := taxonomy.Load(cfg.TaxonomyFile)
+	if categories == nil {
+		log.Fatalf("Failed to load category taxonomy: %v", err)
+	} else if err != nil {
+		log.Printf("⚠️ Failed to load taxonomy overrides from %s: %v", cfg.TaxonomyFile, err)
+	}
+
+	weights, err := LoadWeights(cfg.WeightsFile)
+	if err != nil {
+		log.Printf("⚠️ Failed to load scoring weights from %s, using defaults: %v", cfg.WeightsFile, err)
+	} else if weights.TrainedAt != nil {
+		log.Printf("⚖️ Loaded scoring weights trained on %d feedback entries from %s", weights.Examples, cfg.WeightsFile)
+	}
+
+	calibration, err := LoadCalibration(cfg.CalibrationFile)
+	if err != nil {
+		log.Printf("⚠️ Failed to load confidence calibration from %s, using raw confidence: %v", cfg.CalibrationFile, err)
+	} else if len(calibration.Sources) > 0 {
+		log.Printf("📏 Loaded confidence calibration for %d sources from %s", len(calibration.Sources), cfg.CalibrationFile)
+	}
+
+	s := &Service{
 		config: cfg,
 		httpClient: &http.Client{
 			Timeout: 90 * time.Second,
 		},
-	}
-}
+		prompts:    registry,
+		scheduler:  llm.NewScheduler(cfg.LLMConcurrency),
+		brands:     knowledgeBase,
+		taxonomy:   categories,
+		weights:    weights,
+		embeddings: newEmbeddingCache(cfg.EmbeddingCacheSize),
 
-func (s *Service) FilterAndScoreProducts(ctx context.Context, query string, products []models.ProductResult) ([]models.ProductResult, error) {
-	if len(products) == 0 {
-		return products, nil
-	}
+		calibration: calibration,
 
-	var filteredProducts []models.ProductResult
-	
-	for _, product := range products {
-		score, err := s.scoreProductMatch(ctx, query, product.ProductName)
-		if err != nil {
-			// If LLM fails, use fuzzy scoring
-			score = s.FuzzyProductMatch(query, product.ProductName)
-		}
-		
-		// Only include products with reasonable confidence (lowered threshold)
-		if score >= 0.1 {
-			product.Confidence = score
-			filteredProducts = append(filteredProducts, product)
-		}
+		defaultStrategy: defaultStrategy(cfg.MatchStrategy),
+		cascade:         newCascadeStats(),
 	}
-	
-	// If filtering removed all products, return original list with fuzzy scores
-	if len(filteredProducts) == 0 && len(products) > 0 {
-		for _, product := range products {
-			product.Confidence = s.FuzzyProductMatch(query, product.ProductName)
-			filteredProducts = append(filteredProducts, product)
-		}
-	}
-	
-	return filteredProducts, nil
+	s.strategies = newStrategies(s)
+	return s
 }
 
-func (s *Service) scoreProductMatch(ctx context.Context, query, productName string) (float64, error) {
-	// Create timeout context for LLM call
-	llmCtx, cancel := context.WithTimeout(ctx, 120*time.Second)
-	defer cancel()
-	prompt := fmt.Sprintf(`You are a product matching expert. Rate how well this product matches the search query on a scale from 0.0 to 1.0.
+// Scheduler returns the process-wide LLM concurrency governor
+func (s *Service) Scheduler() *llm.Scheduler {
+	return s.scheduler
+}
+
+// Brands returns the brand knowledge base used by every matching strategy
+func (s *Service) Brands() *brands.KnowledgeBase {
+	return s.brands
+}
 
-Search Query: "%s"
-Product Name: "%s"
+// AddBrand extends the brand knowledge base and drops cached query parses that predate it
+func (s *Service) AddBrand(brand brands.Brand) (brands.Brand, error) {
+	added, err := s.brands.Add(brand)
+	s.intents.clear()
+	return added, err
+}
 
-Scoring Guidelines:
-- 1.0: Perfect match (exact product, brand, model, specs)
-- 0.8-0.9: Excellent match (same product, minor spec differences)
-- 0.6-0.7: Good match (same brand/category, different model/version)
-- 0.4-0.5: Moderate match (related products, accessories, or alternatives)
-- 0.2-0.3: Weak match (same category but different brand/purpose)
-- 0.0-0.1: No match (completely unrelated products)
+// Prompts returns the prompt template registry shared by the extraction and scoring stages
+func (s *Service) Prompts() *prompts.Registry {
+	return s.prompts
+}
 
-Examples:
-- Query: "iPhone 15 128GB" vs "Apple iPhone 15 - 128GB Black" = 1.0
-- Query: "iPhone 15" vs "iPhone 14 Pro" = 0.7  
-- Query: "iPhone 15" vs "iPhone Case for 15" = 0.4
-- Query: "iPhone 15" vs "Samsung Galaxy S24" = 0.2
-- Query: "iPhone 15" vs "Laptop Charger" = 0.0
+// LLMProductMatch scores a single title against the query with the scoring prompt
+func (s *Service) LLMProductMatch(ctx context.Context, query, productName string) (float64, error) {
+	explanation, _, err := s.scoreProductMatch(ctx, s.config.OllamaModel, s.ParseQueryRules(query), models.ProductResult{ProductName: productName})
+	return explanation.Score, err
+}
 
-Respond with only the numeric score (0.0-1.0), no explanation.
+// scoreProductMatch scores a product against intent with the scoring prompt on model, returning the explanation and the
+// prompt version used
+func (s *Service) scoreProductMatch(ctx context.Context, model string, intent models.QueryIntent, product models.ProductResult) (models.ScoreExplanation, string, error) {
+	// Create timeout context for LLM call
+	llmCtx, cancel := context.WithTimeout(ctx, 120*time.Second)
+	defer cancel()
 
-Score:`, query, productName)
+	tmpl, err := s.prompts.Get(prompts.Scoring, prompts.Scope{Site: product.Site, Category: product.Category})
+	if err != nil {
+		return models.ScoreExplanation{}, "", err
+	}
+	prompt, err := tmpl.Render(prompts.ScoringData{Query: intent.Raw, ProductName: product.ProductName})
+	if err != nil {
+		return models.ScoreExplanation{}, "", err
+	}
 
-	response, err := s.CallOllama(llmCtx, prompt)
+	response, err := s.callModel(llmCtx, llm.PriorityBackground, model, prompt)
 	if err != nil {
-		return 0, err
+		return models.ScoreExplanation{}, "", err
 	}
 
 	// Parse the score from response
-	score := s.parseScore(response)
-	return score, nil
+	explanation := models.ScoreExplanation{Scorer: ScorerLLM, Model: model, LLMResponse: response}
+	explanation.LLMScore = s.parseScore(response)
+	explanation.Score = explanation.LLMScore
+
+	// Small models often rate a rival brand's flagship, an accessory or a neighbouring variant as a match;
+	// the knowledge base and the variant rules know better
+	s.scoreAttributes(intent, product.ProductName, &explanation)
+	s.explainRules(intent, product.ProductName, &explanation)
+	if guards := llmGuards(explanation, s.VariantConflicts(intent, product.ProductName)); len(guards) > 0 {
+		explanation.Score *= 0.5
+		explanation.Rules = append(explanation.Rules, "llm score halved: "+strings.Join(guards, ", "))
+	}
+
+	return explanation, tmpl.ID(), nil
 }
 
-func (s *Service) CallOllama(ctx context.Context, prompt string) (string, error) {
+// CallOllama runs a prompt through the scheduler so total load on the model server stays bounded
+func (s *Service) CallOllama(ctx context.Context, priority llm.Priority, prompt string) (string, error) {
+	return s.callModel(ctx, priority, s.config.OllamaModel, prompt)
+}
+
+// callModel runs a prompt on the named Ollama model through the scheduler. A call that cannot reach the server is
+// retried once after a short pause; the scheduler slot is given back for the pause and taken again for the retry.
+func (s *Service) callModel(ctx context.Context, priority llm.Priority, model, prompt string) (string, error) {
 	ollamaURL := s.config.OllamaHost + "/api/generate"
-	startTime := time.Now()
 	log.Printf("🔗 Attempting LLM connection to: %s", ollamaURL)
-	
+
 	reqBody := OllamaRequest{
-		Model:  "phi3:mini",
+		Model:  model,
 		Prompt: prompt,
 		Stream: false,
+		Options: &OllamaOptions{
+			NumCtx: s.contextWindowFor(model),
+		},
 	}
 
 	jsonData, err := json.Marshal(reqBody)
@@ -129,48 +206,55 @@ func (s *Service) CallOllama(ctx context.Context, prompt string) (string, error)
 		return "", err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", ollamaURL, bytes.NewBuffer(jsonData))
-	if err != nil {
+	startTime := time.Now()
+	response, unreachable, err := s.generate(ctx, priority, ollamaURL, model, jsonData)
+	if err == nil || !unreachable {
+		return response, err
+	}
+
+	elapsedTime := time.Since(startTime)
+	log.Printf("❌ LLM connection failed to %s: %v (took: %.2fs)", ollamaURL, err, elapsedTime.Seconds())
+	if elapsedTime >= 60*time.Second {
 		return "", err
 	}
 
+	log.Printf("🔄 Retrying LLM call after brief delay...")
+	select {
+	case <-time.After(2 * time.Second):
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	retryStart := time.Now()
+	response, _, retryErr := s.generate(ctx, priority, ollamaURL, model, jsonData)
+	if retryErr != nil {
+		log.Printf("❌ LLM retry also failed: %v", retryErr)
+		return "", retryErr
+	}
+	log.Printf("✅ LLM retry successful (took: %.2fs)", time.Since(retryStart).Seconds())
+	return response, nil
+}
+
+// generate makes one call to the Ollama generate endpoint while holding a scheduler slot. unreachable reports
+// that the server could not be reached at all, which is worth a retry; an error status from the server is not.
+func (s *Service) generate(ctx context.Context, priority llm.Priority, ollamaURL, model string, jsonData []byte) (response string, unreachable bool, err error) {
+	release, err := s.scheduler.Acquire(ctx, priority)
+	if err != nil {
+		log.Printf("⏳ LLM %s call not admitted: %v", priority, err)
+		return "", false, err
+	}
+	defer release()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", ollamaURL, bytes.NewReader(jsonData))
+	if err != nil {
+		return "", false, err
+	}
 	req.Header.Set("Content-Type", "application/json")
 
+	startTime := time.Now()
 	resp, err := s.httpClient.Do(req)
 	if err != nil {
-		elapsedTime := time.Since(startTime)
-		log.Printf("❌ LLM connection failed to %s: %v (took: %.2fs)", ollamaURL, err, elapsedTime.Seconds())
-		
-		// Retry logic with exponential backoff
-		if elapsedTime < 60*time.Second {
-			log.Printf("🔄 Retrying LLM call after brief delay...")
-			time.Sleep(2 * time.Second)
-			
-			// Retry once
-			retryStart := time.Now()
-			resp, retryErr := s.httpClient.Do(req)
-			if retryErr == nil {
-				defer resp.Body.Close()
-				retryElapsed := time.Since(retryStart)
-				log.Printf("✅ LLM retry successful (took: %.2fs)", retryElapsed.Seconds())
-				
-				body, err := ioutil.ReadAll(resp.Body)
-				if err != nil {
-					return "", err
-				}
-				
-				var ollamaResp OllamaResponse
-				if err := json.Unmarshal(body, &ollamaResp); err != nil {
-					return "", err
-				}
-				
-				return ollamaResp.Response, nil
-			} else {
-				log.Printf("❌ LLM retry also failed: %v", retryErr)
-			}
-		}
-		
-		return "", err
+		return "", ctx.Err() == nil, err
 	}
 	defer resp.Body.Close()
 
@@ -179,15 +263,19 @@ func (s *Service) CallOllama(ctx context.Context, prompt string) (string, error)
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return "", err
+		return "", false, err
+	}
+	// An unknown model or a server error comes back as a JSON error, which would otherwise parse as an empty response
+	if resp.StatusCode != http.StatusOK {
+		return "", false, fmt.Errorf("model %s returned status %d: %s", model, resp.StatusCode, strings.TrimSpace(string(body)))
 	}
 
 	var ollamaResp OllamaResponse
 	if err := json.Unmarshal(body, &ollamaResp); err != nil {
-		return "", err
+		return "", false, err
 	}
 
-	return ollamaResp.Response, nil
+	return ollamaResp.Response, false, nil
 }
 
 func (s *Service) parseScore(response string) float64 {
@@ -247,33 +335,55 @@ func (s *Service) BasicProductMatch(query, productName string) float64 {
 	
 	score := float64(matchCount) / float64(len(queryWords))
 	
-	// Boost score if it contains key brand/model info
-	if strings.Contains(queryLower, "iphone") && strings.Contains(productLower, "iphone") {
+	// Boost score when the brand, model variants and storage asked for are all present
+	intent := s.ParseQueryRules(query)
+	productText := " " + normalizeQueryText(productLower) + " "
+	if intent.Brand != "" && s.brands.Mentions(productLower, intent.Brand) {
 		score += 0.3
 	}
-	if strings.Contains(queryLower, "pro") && strings.Contains(productLower, "pro") {
-		score += 0.2
-	}
-	if strings.Contains(queryLower, "128gb") && strings.Contains(productLower, "128") {
-		score += 0.2
+	if len(intent.Variants) > 0 {
+		allPresent := true
+		for _, variant := range intent.Variants {
+			if !containsTerm(productText, variant) {
+				allPresent = false
+				break
+			}
+		}
+		if allPresent {
+			score += 0.2
+		}
 	}
-	
-	// Clamp score
-	if score > 1 {
-		score = 1
+	// Storage is compared in GB, so "1TB" matches "1024 GB" but not the "1" of "1 year warranty"
+	for _, outcome := range specOutcomes(intent.Specs, ExtractSpecs(productText)) {
+		if outcome.wanted.Dimension == DimensionStorage && outcome.matched {
+			score += 0.2
+		}
 	}
 	
+	score, _ = s.applyPenalties(score, s.calculateRelevancePenalty(intent, productName), intent, productName)
 	return score
 }
 
 // FuzzyProductMatch uses fuzzy string matching with semantic bonuses for better product matching
 func (s *Service) FuzzyProductMatch(query, productName string) float64 {
-	if query == "" || productName == "" {
-		return 0.0
+	return s.FuzzyProductMatchIntent(s.ParseQueryRules(query), productName)
+}
+
+// FuzzyProductMatchIntent scores a product against a parsed query, comparing brand, model and specs attribute by attribute
+func (s *Service) FuzzyProductMatchIntent(intent models.QueryIntent, productName string) float64 {
+	return s.fuzzyMatch(intent, productName).Score
+}
+
+// fuzzyMatch computes the fuzzy score along with the components it is made of
+func (s *Service) fuzzyMatch(intent models.QueryIntent, productName string) models.ScoreExplanation {
+	explanation := models.ScoreExplanation{Scorer: ScorerFuzzy}
+	if intent.Raw == "" || productName == "" {
+		return explanation
 	}
 
-	queryLower := strings.ToLower(strings.TrimSpace(query))
-	productLower := strings.ToLower(strings.TrimSpace(productName))
+	// Compare canonical forms, so a German or Japanese title is measured against an English query in English
+	queryLower := lang.Canonical(strings.TrimSpace(intent.Raw))
+	productLower := lang.Canonical(strings.TrimSpace(productName))
 
 	// Stage 1: Calculate base fuzzy similarity using Jaro-Winkler
 	jaroWinkler := smetrics.JaroWinkler(queryLower, productLower, 0.7, 4)
@@ -284,7 +394,7 @@ func (s *Service) FuzzyProductMatch(query, productName string) float64 {
 		maxLen = len(productLower)
 	}
 	if maxLen == 0 {
-		return 0.0
+		return explanation
 	}
 	
 	levenshteinDist := levenshtein.ComputeDistance(queryLower, productLower)
@@ -295,76 +405,51 @@ func (s *Service) FuzzyProductMatch(query, productName string) float64 {
 	if levenshteinSim > jaroWinkler {
 		baseSimilarity = levenshteinSim
 	}
+	explanation.BaseSimilarity = baseSimilarity
 
-	// Stage 2: Add semantic bonuses
-	score := baseSimilarity
-	
-	// Brand matching bonus
-	brandBonus := s.calculateBrandBonus(queryLower, productLower)
-	score += brandBonus
-	
-	// Model/number matching bonus  
-	modelBonus := s.calculateModelBonus(queryLower, productLower)
-	score += modelBonus
-	
-	// Storage/specification matching bonus
-	specBonus := s.calculateSpecBonus(queryLower, productLower)
-	score += specBonus
-	
-	// Stage 3: Apply penalties for clearly irrelevant products
-	penalty := s.calculateRelevancePenalty(queryLower, productLower)
-	score -= penalty
+	// Stage 2: Add semantic bonuses from the structured query
+	explanation.Score = baseSimilarity + s.scoreAttributes(intent, productName, &explanation)
 
-	// Ensure score stays within 0.0 to 1.0 range
-	if score > 1.0 {
-		score = 1.0
-	} else if score < 0.0 {
-		score = 0.0
-	}
+	// Stages 3 and 4: Penalise accessories and other variants after clamping, so bonuses cannot absorb the penalties
+	s.penalise(intent, productName, &explanation)
+	return explanation
+}
 
-	return score
+// containsTerm reports whether term appears as whole words in text, which must be space-padded normalized text
+func containsTerm(text, term string) bool {
+	return term != "" && strings.Contains(text, " "+term+" ")
 }
 
-func (s *Service) calculateBrandBonus(query, product string) float64 {
-	brands := []string{"apple", "iphone", "samsung", "galaxy", "google", "pixel", "oneplus", "xiaomi", "huawei", "oppo", "vivo", "realme"}
-	
-	queryBrand := ""
-	productBrand := ""
-	
-	// Find brand in query and product
-	for _, brand := range brands {
-		if strings.Contains(query, brand) {
-			queryBrand = brand
-		}
-		if strings.Contains(product, brand) {
-			productBrand = brand
-		}
-	}
-	
-	// Special handling for iPhone (Apple product)
-	if strings.Contains(query, "iphone") && strings.Contains(product, "apple") {
-		queryBrand = "apple"
-		productBrand = "apple"
+// brandFeatures reports whether the title names the query's brand, or names only other brands
+func (s *Service) brandFeatures(intent models.QueryIntent, product string) (match, conflict float64) {
+	if intent.Brand == "" {
+		return 0.0, 0.0
 	}
-	if strings.Contains(query, "apple") && strings.Contains(product, "iphone") {
-		queryBrand = "apple"
-		productBrand = "apple"
+
+	mentioned := s.brands.MentionedBrands(product)
+	for _, brand := range mentioned {
+		if brand == intent.Brand {
+			return 1.0, 0.0 // Strong brand match
+		}
 	}
-	
-	if queryBrand != "" && queryBrand == productBrand {
-		return 0.3 // Strong brand match bonus
-	} else if queryBrand != "" && productBrand != "" && queryBrand != productBrand {
-		return -0.2 // Different brand penalty
+	if len(mentioned) > 0 {
+		return 0.0, 1.0 // Different brand
 	}
-	
-	return 0.0
+
+	return 0.0, 0.0
 }
 
-func (s *Service) calculateModelBonus(query, product string) float64 {
-	// Extract numbers/models from both strings
-	numRegex := regexp.MustCompile(`\d+`)
-	queryNumbers := numRegex.FindAllString(query, -1)
-	productNumbers := numRegex.FindAllString(product, -1)
+// modelMatch is 1 when the title contains the parsed model token, otherwise the share of query numbers it contains.
+// Numbers belonging to quantities are left out: the spec weights compare those, and "1kg" says nothing of the model.
+func (s *Service) modelMatch(intent models.QueryIntent, query, product string) float64 {
+	// A parsed model token present in the title is a full match
+	if containsTerm(product, intent.Model) {
+		return 1.0
+	}
+
+	// Otherwise compare numbers, which catches models written differently ("s24" vs "s 24")
+	queryNumbers := numberPattern.FindAllString(stripSpecs(normalizeQueryText(query)), -1)
+	productNumbers := numberPattern.FindAllString(stripSpecs(product), -1)
 	
 	if len(queryNumbers) == 0 || len(productNumbers) == 0 {
 		return 0.0
@@ -380,76 +465,32 @@ func (s *Service) calculateModelBonus(query, product string) float64 {
 		}
 	}
 	
-	if matchCount > 0 {
-		similarity := float64(matchCount) / float64(len(queryNumbers))
-		return similarity * 0.2 // Model number match bonus
-	}
-	
-	return 0.0
+	return float64(matchCount) / float64(len(queryNumbers))
 }
 
-func (s *Service) calculateSpecBonus(query, product string) float64 {
-	bonus := 0.0
-	
-	// Storage matching
-	storageRegex := regexp.MustCompile(`(\d+)\s*(gb|tb)`)
-	queryStorage := storageRegex.FindAllString(query, -1)
-	productStorage := storageRegex.FindAllString(product, -1)
-	
-	for _, qStorage := range queryStorage {
-		for _, pStorage := range productStorage {
-			if strings.EqualFold(qStorage, pStorage) {
-				bonus += 0.15
-			}
-		}
-	}
-	
-	// Color matching
-	colors := []string{"black", "white", "red", "blue", "green", "yellow", "purple", "pink", "gold", "silver", "gray", "grey"}
-	for _, color := range colors {
-		if strings.Contains(query, color) && strings.Contains(product, color) {
-			bonus += 0.05
-		}
-	}
-	
-	// Condition matching
-	conditions := []string{"new", "used", "refurbished", "renewed", "good", "excellent", "fair"}
-	for _, condition := range conditions {
-		if strings.Contains(query, condition) && strings.Contains(product, condition) {
-			bonus += 0.05
-		}
+// relevanceFeatures flags results of a different kind than the query asks for: accessories, consumables and
+// parts when the query names a product, or the product itself when the query names an add-on
+func (s *Service) relevanceFeatures(intent models.QueryIntent, productName string) (addOn, primary float64) {
+	product := s.ClassifyProduct(intent, productName)
+	queryWantsAddOn := taxonomy.Classification{Kind: intent.Kind}.IsAddOn()
+
+	switch {
+	case !queryWantsAddOn && product.IsAddOn():
+		return 1.0, 0.0 // Accessory for a product query
+	case queryWantsAddOn && product.Kind == taxonomy.KindPrimary:
+		return 0.0, 1.0
 	}
-	
-	return bonus
+	return 0.0, 0.0
 }
 
-func (s *Service) calculateRelevancePenalty(query, product string) float64 {
-	penalty := 0.0
-	
-	// If query is for a phone but product is clearly an accessory
-	phoneTerms := []string{"iphone", "galaxy", "pixel", "phone", "smartphone"}
-	accessoryTerms := []string{"case", "cover", "charger", "cable", "screen protector", "tempered glass", "stand", "holder", "adapter"}
-	
-	queryIsPhone := false
-	productIsAccessory := false
-	
-	for _, term := range phoneTerms {
-		if strings.Contains(query, term) {
-			queryIsPhone = true
-			break
-		}
-	}
-	
-	for _, term := range accessoryTerms {
-		if strings.Contains(product, term) {
-			productIsAccessory = true
-			break
-		}
-	}
-	
-	if queryIsPhone && productIsAccessory {
-		penalty += 0.4 // Heavy penalty for phone query matching accessories
-	}
-	
-	return penalty
-}
\ No newline at end of file
+// calculateRelevancePenalty weighs the relevance features
+func (s *Service) calculateRelevancePenalty(intent models.QueryIntent, productName string) float64 {
+	addOn, primary := s.relevanceFeatures(intent, productName)
+	return s.weights.AddOnResult*addOn + s.weights.PrimaryResult*primary
+}
+
+// ClassifyProduct detects a result's category and whether it is an accessory, consumable or part,
+// using the query's category to settle terms shared between categories
+func (s *Service) ClassifyProduct(intent models.QueryIntent, productName string) taxonomy.Classification {
+	return s.taxonomy.Classify(productName, intent.Category)
+}
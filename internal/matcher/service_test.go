@@ -0,0 +1,217 @@
+package matcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"price-comparison-tool/internal/config"
+	"price-comparison-tool/internal/llm"
+	"price-comparison-tool/internal/models"
+)
+
+// newTestService builds a matcher over the embedded brands, taxonomy, prompts and default weights
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	return NewService(&config.Config{LLMConcurrency: 1, EmbeddingCacheSize: 16})
+}
+
+func TestModelMatchIgnoresSpecNumbers(t *testing.T) {
+	s := newTestService(t)
+
+	tests := []struct {
+		query, title string
+		want         float64
+	}{
+		{"Tata Salt 1kg", "Tata Sampann Unpolished Toor Dal 1kg", 0},
+		{"Samsung 55 inch TV", "Samsung 55\" Crystal UHD 4K Smart TV", 0},
+		{"Dell XPS 13", "Dell XPS 13 9340 Laptop", 1},
+		{"Galaxy S24", "Samsung Galaxy S 24 Ultra", 1},
+	}
+	for _, test := range tests {
+		intent := s.ParseQueryRules(test.query)
+		product := " " + normalizeQueryText(test.title) + " "
+		if got := s.modelMatch(intent, test.query, product); got != test.want {
+			t.Errorf("modelMatch(%q, %q) = %.2f, want %.2f", test.query, test.title, got, test.want)
+		}
+	}
+}
+
+func TestFuzzyMatchRanksOtherProductLineBelow(t *testing.T) {
+	s := newTestService(t)
+	intent := s.ParseQueryRules("Dell XPS 13")
+
+	exact := s.FuzzyProductMatchIntent(intent, "Dell XPS 13 9340 Laptop, Intel Core Ultra 7, 16GB RAM, 512GB SSD")
+	other := s.FuzzyProductMatchIntent(intent, "Dell Inspiron 15 3520 Laptop")
+	if other >= exact {
+		t.Errorf("Inspiron scored %.3f, want below XPS at %.3f", other, exact)
+	}
+	if conflicts := s.VariantConflicts(intent, "Dell Inspiron 15 3520 Laptop"); len(conflicts) == 0 || conflicts[0].Attribute != "line" {
+		t.Errorf("VariantConflicts() = %v, want a line conflict first", conflicts)
+	}
+}
+
+func TestBasicProductMatchComparesStorageInGB(t *testing.T) {
+	s := newTestService(t)
+
+	matching := s.BasicProductMatch("Pixel 8 1TB", "Pixel 8 1024 GB")
+	unrelatedOne := s.BasicProductMatch("Pixel 8 1TB", "Pixel 8 with 1 year warranty")
+	if matching-unrelatedOne < 0.19 {
+		t.Errorf("1024 GB scored %.3f and a title with only a \"1\" %.3f, want storage credit only for 1024 GB", matching, unrelatedOne)
+	}
+}
+
+func TestAccessoryRanksBelowDevice(t *testing.T) {
+	s := newTestService(t)
+
+	tests := []struct {
+		query, device, accessory string
+	}{
+		{"iPhone 16 Pro 128GB", "Apple iPhone 16 Pro 128GB Black Titanium 5G", "iPhone 16 Pro Case 128GB compatible"},
+		{"Samsung Galaxy S24 Ultra 1TB", "Samsung Galaxy S24 Ultra 1TB Titanium Black Unlocked", "Galaxy S24 Ultra 1TB Leather Case"},
+		{"Samsung 55 inch Crystal UHD TV", "Samsung 55\" Crystal UHD 4K Smart TV", "Wall Mount for Samsung 55 inch Crystal UHD TV"},
+	}
+	for _, test := range tests {
+		intent := s.ParseQueryRules(test.query)
+		for _, scorer := range []struct {
+			name  string
+			score func(string) float64
+		}{
+			{"fuzzy", func(title string) float64 { return s.FuzzyProductMatchIntent(intent, title) }},
+			{"basic", func(title string) float64 { return s.BasicProductMatch(test.query, title) }},
+		} {
+			device, accessory := scorer.score(test.device), scorer.score(test.accessory)
+			if accessory >= device {
+				t.Errorf("%s: %q scored %.3f, want below %q at %.3f", scorer.name, test.accessory, accessory, test.device, device)
+			}
+		}
+	}
+}
+
+func TestExplanationSubtractsRelevancePenaltyAfterClamp(t *testing.T) {
+	s := newTestService(t)
+	intent := s.ParseQueryRules("iPhone 16 Pro 128GB")
+
+	explanation := s.ExplainFuzzyMatch(intent, "iPhone 16 Pro Case 128GB compatible")
+	if explanation.RelevancePenalty == 0 {
+		t.Fatal("no relevance penalty for a case")
+	}
+	if want := 1 - explanation.RelevancePenalty; explanation.Score > want+0.001 {
+		t.Errorf("score %.3f, want at most %.3f: bonuses above 1.0 must not absorb the penalty", explanation.Score, want)
+	}
+}
+
+func TestWeightsScoreMatchesServedFuzzyScore(t *testing.T) {
+	s := newTestService(t)
+	weights := s.Weights()
+
+	tests := []struct{ query, title string }{
+		{"iPhone 16 Pro 128GB", "Apple iPhone 16 Pro 128GB Black Titanium 5G"},
+		{"iPhone 16 Pro 128GB", "Apple iPhone 16 Pro Max (256 GB) - Natural Titanium"},
+		{"iPhone 16 Pro 128GB", "Spigen Ultra Hybrid Case for iPhone 16 Pro - Crystal Clear"},
+		{"Dell XPS 13", "Dell Inspiron 15 3520 Laptop"},
+		{"Nescafe Classic 200g", "Bru Instant Coffee Powder 200g"},
+	}
+	for _, test := range tests {
+		intent := s.ParseQueryRules(test.query)
+		served := s.FuzzyProductMatchIntent(intent, test.title)
+		if fitted := weights.Score(s.MatchFeatures(intent, test.title)); math.Abs(fitted-served) > 1e-9 {
+			t.Errorf("Weights.Score(%q, %q) = %.4f, want the served %.4f", test.query, test.title, fitted, served)
+		}
+	}
+}
+
+func TestCallModelReleasesSlotWhileBackingOff(t *testing.T) {
+	s := newTestService(t)
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close() // Nothing listens, so the call fails to connect and backs off
+	s.config.OllamaHost = server.URL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errs := make(chan error, 1)
+	go func() {
+		_, err := s.callModel(ctx, llm.PriorityBackground, "model", "prompt")
+		errs <- err
+	}()
+
+	for deadline := time.Now().Add(time.Second); s.Scheduler().Stats().Completed == 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("the failed call never gave its slot back")
+		}
+	}
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer waitCancel()
+	release, err := s.Scheduler().Acquire(waitCtx, llm.PriorityInteractive)
+	if err != nil {
+		t.Fatalf("Acquire() during the backoff error: %v, want the only slot free", err)
+	}
+	release()
+
+	cancel()
+	select {
+	case err := <-errs:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("callModel() error = %v, want the cancellation that cut the backoff short", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("callModel() kept backing off after its context was cancelled")
+	}
+}
+
+func TestCallModelRetriesWithFreshRequest(t *testing.T) {
+	s := newTestService(t)
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			// Drop the first connection without a response
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		var req OllamaRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Prompt != "prompt" {
+			http.Error(w, "retry sent no prompt", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"response": "0.9"}`))
+	}))
+	defer server.Close()
+	s.config.OllamaHost = server.URL
+
+	response, err := s.callModel(context.Background(), llm.PriorityBackground, "model", "prompt")
+	if err != nil || response != "0.9" {
+		t.Errorf("callModel() = %q, %v; want the retry's response", response, err)
+	}
+	if stats := s.Scheduler().Stats(); stats.Active != 0 || stats.Completed != 2 {
+		t.Errorf("scheduler %+v, want both attempts completed and no slot held", stats)
+	}
+}
+
+func TestScoreProductMatchGuardsWithScoredIntent(t *testing.T) {
+	s := newTestService(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"response": "0.9"}`))
+	}))
+	defer server.Close()
+	s.config.OllamaHost = server.URL
+
+	// The model is known only from the LLM's reading of the query, not from its text
+	intent := s.ParseQueryRules("galaxy phone")
+	intent.Model, intent.Source = "s24", "llm"
+
+	explanation, _, err := s.scoreProductMatch(context.Background(), "model", intent, models.ProductResult{ProductName: "Samsung Galaxy S23 5G"})
+	if err != nil {
+		t.Fatalf("scoreProductMatch() error: %v", err)
+	}
+	if explanation.LLMScore != 0.9 || explanation.Score != 0.45 {
+		t.Errorf("scoreProductMatch() score %.2f from LLM score %.2f, want 0.45 with the s23 generation conflict halving it",
+			explanation.Score, explanation.LLMScore)
+	}
+}
@@ -0,0 +1,235 @@
+package matcher
+
+import (
+	"math"
+	"price-comparison-tool/internal/models"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Spec dimensions, each normalized to one base unit
+const (
+	DimensionStorage = "storage" // GB
+	DimensionRAM     = "ram"     // GB
+	DimensionLength  = "length"  // inch, mostly screen sizes
+	DimensionBattery = "battery" // mAh
+	DimensionPower   = "power"   // W
+	DimensionVolume  = "volume"  // ml
+	DimensionWeight  = "weight"  // g
+)
+
+// specPattern matches a number and a unit: "1TB", "128 GB", "6.1-inch", "5,000mAh", "1.5 L", "200g", "8 quart".
+// The number allows thousands separators; units are anchored so "in" in "7-in-1" is rejected separately, as is
+// the "in" of "iPhone 16 in black" (see prepositionIn) and the focal lengths of camera lenses (see lensSpec).
+var specPattern = regexp.MustCompile(`(\d{1,3}(?:,\d{3})+|\d+(?:\.\d+)?)\s*-?\s*(tb|gb|mb|mah|kw|watts?|w|ml|millilitres?|milliliters?|litres?|liters?|ltrs?|l|kgs?|kilograms?|grams?|gms?|g|mg|lbs?|pounds?|oz|qt|quarts?|inch(?:es)?|in|"|”|cm|mm)(?:\b|\s|$)(\s*ram)?`)
+
+// Lens specs: "18-45mm" focal length ranges, "50mm lens" and the aperture after a focal length ("f/1.8", normalized
+// to "f 1.8"). They describe a camera kit's lens, not a length, and "f/4 L" is not 4 litres.
+var (
+	focalRangePattern = regexp.MustCompile(`\d+(?:\.\d+)?\s*-\s*$`)
+	lensPattern       = regexp.MustCompile(`^\s*(?:lens|f\s*/?\s*\d)`)
+	aperturePattern   = regexp.MustCompile(`\dmm\s+f\s*/?\s*$`)
+)
+
+// unitScales maps a unit to its dimension and the factor converting it to the dimension's base unit
+var unitScales = map[string]struct {
+	dimension string
+	factor    float64
+}{
+	"tb": {DimensionStorage, 1024}, "gb": {DimensionStorage, 1}, "mb": {DimensionStorage, 1.0 / 1024},
+	"mah": {DimensionBattery, 1},
+	"kw":  {DimensionPower, 1000}, "w": {DimensionPower, 1}, "watt": {DimensionPower, 1}, "watts": {DimensionPower, 1},
+	"ml": {DimensionVolume, 1}, "millilitre": {DimensionVolume, 1}, "millilitres": {DimensionVolume, 1},
+	"milliliter": {DimensionVolume, 1}, "milliliters": {DimensionVolume, 1},
+	"l": {DimensionVolume, 1000}, "ltr": {DimensionVolume, 1000}, "ltrs": {DimensionVolume, 1000},
+	"litre": {DimensionVolume, 1000}, "litres": {DimensionVolume, 1000}, "liter": {DimensionVolume, 1000}, "liters": {DimensionVolume, 1000},
+	"qt": {DimensionVolume, 946.353}, "quart": {DimensionVolume, 946.353}, "quarts": {DimensionVolume, 946.353},
+	"kg": {DimensionWeight, 1000}, "kgs": {DimensionWeight, 1000}, "kilogram": {DimensionWeight, 1000}, "kilograms": {DimensionWeight, 1000},
+	"g": {DimensionWeight, 1}, "gm": {DimensionWeight, 1}, "gms": {DimensionWeight, 1}, "gram": {DimensionWeight, 1}, "grams": {DimensionWeight, 1},
+	"mg": {DimensionWeight, 0.001}, "oz": {DimensionWeight, 28.3495},
+	"lb": {DimensionWeight, 453.592}, "lbs": {DimensionWeight, 453.592}, "pound": {DimensionWeight, 453.592}, "pounds": {DimensionWeight, 453.592},
+	"inch": {DimensionLength, 1}, "inches": {DimensionLength, 1}, "in": {DimensionLength, 1}, `"`: {DimensionLength, 1}, "”": {DimensionLength, 1},
+	"cm": {DimensionLength, 1 / 2.54}, "mm": {DimensionLength, 1 / 25.4},
+}
+
+// specWeights sets the default weight by which a matching quantity raises the score, per dimension; a weights file
+// may replace them. A different quantity is a variant conflict instead (see specAttributes). tolerance is the
+// relative difference still counted as equal, so 1TB matches "1000 GB" and 13" matches 13.3".
+var specWeights = map[string]struct {
+	weight    float64
+	tolerance float64
+}{
+	DimensionStorage: {0.15, 0.03},
+	DimensionRAM:     {0.05, 0.03},
+	DimensionLength:  {0.10, 0.05},
+	DimensionBattery: {0.05, 0.03},
+	DimensionPower:   {0.05, 0.03},
+	DimensionVolume:  {0.10, 0.03},
+	DimensionWeight:  {0.10, 0.03},
+}
+
+// ExtractSpecs finds the quantities in text and normalizes each to its dimension's base unit
+func ExtractSpecs(text string) []models.Spec {
+	lower := strings.ToLower(text)
+
+	var specs []models.Spec
+	for _, loc := range specPattern.FindAllStringSubmatchIndex(lower, -1) {
+		number := strings.ReplaceAll(lower[loc[2]:loc[3]], ",", "")
+		unit := lower[loc[4]:loc[5]]
+
+		// "7-in-1" and "2 in 1" describe functions, not inches, and "5G" is a network rather than a weight
+		if unit == "in" && loc[5] < len(lower) && (lower[loc[5]] == '-' || strings.HasPrefix(lower[loc[5]:], " 1")) {
+			continue
+		}
+		if prepositionIn(lower, loc) || withSlash(lower, loc) {
+			continue
+		}
+		if _, lens := lensSpec(lower, loc); lens {
+			continue
+		}
+		if unit == "g" && len(number) == 1 && number >= "2" && number <= "5" {
+			continue
+		}
+
+		value, err := strconv.ParseFloat(number, 64)
+		if err != nil || value == 0 {
+			continue
+		}
+		scale, known := unitScales[unit]
+		if !known {
+			continue
+		}
+
+		dimension := scale.dimension
+		if dimension == DimensionStorage && loc[6] >= 0 {
+			dimension = DimensionRAM
+		}
+		specs = append(specs, models.Spec{
+			Dimension: dimension,
+			Value:     math.Round(value*scale.factor*1000) / 1000,
+			Unit:      baseUnit(dimension),
+			Raw:       strings.TrimSpace(lower[loc[2]:loc[5]]),
+		})
+	}
+	return specs
+}
+
+// prepositionIn reports a spec match whose unit is the word "in" rather than inches: set apart from the number
+// and followed by another word, as in "iPhone 16 in black". "13in", "13-in" and a trailing "55 in" stay sizes.
+func prepositionIn(lower string, loc []int) bool {
+	if lower[loc[4]:loc[5]] != "in" || !strings.ContainsAny(lower[loc[3]:loc[4]], " \t") {
+		return false
+	}
+	rest := strings.TrimLeft(lower[loc[5]:], " \t")
+	if rest == "" {
+		return false
+	}
+	r := []rune(rest)[0]
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// withSlash reports a "w" that abbreviates "with", as in "15 w/ case", in text that was not normalized
+func withSlash(lower string, loc []int) bool {
+	return lower[loc[4]:loc[5]] == "w" && loc[5] < len(lower) && lower[loc[5]] == '/'
+}
+
+// lensSpec reports a focal length or aperture rather than a length or volume, and where the spec starts: at the
+// lower end of a focal length range, so stripping "18-45mm" leaves no "18-" behind
+func lensSpec(lower string, loc []int) (int, bool) {
+	unit := lower[loc[4]:loc[5]]
+	if unit == "mm" {
+		if rangeStart := focalRangePattern.FindStringIndex(lower[:loc[2]]); rangeStart != nil {
+			return rangeStart[0], true
+		}
+		if lensPattern.MatchString(lower[loc[5]:]) {
+			return loc[0], true
+		}
+	}
+	return loc[0], aperturePattern.MatchString(lower[:loc[2]])
+}
+
+// stripSpecs blanks every quantity in lower, normalized text, keeping the "in" of "16 in black" and the model
+// number before it
+func stripSpecs(lower string) string {
+	var stripped strings.Builder
+	last := 0
+	for _, loc := range specPattern.FindAllStringSubmatchIndex(lower, -1) {
+		if prepositionIn(lower, loc) || withSlash(lower, loc) {
+			continue
+		}
+		start, _ := lensSpec(lower, loc)
+		if start < last {
+			start = last
+		}
+		stripped.WriteString(lower[last:start])
+		stripped.WriteByte(' ')
+		last = loc[1]
+	}
+	stripped.WriteString(lower[last:])
+	return stripped.String()
+}
+
+func baseUnit(dimension string) string {
+	switch dimension {
+	case DimensionStorage, DimensionRAM:
+		return "GB"
+	case DimensionLength:
+		return "inch"
+	case DimensionBattery:
+		return "mAh"
+	case DimensionPower:
+		return "W"
+	case DimensionVolume:
+		return "ml"
+	case DimensionWeight:
+		return "g"
+	}
+	return ""
+}
+
+// specOutcome is how a title fared on one dimension the query states; offered is nil when the title does not state it
+type specOutcome struct {
+	wanted  models.Spec
+	offered *models.Spec
+	matched bool
+}
+
+// specOutcomes compares each weighted dimension of the query once, in query order
+func specOutcomes(query, product []models.Spec) []specOutcome {
+	var outcomes []specOutcome
+	compared := make(map[string]bool)
+	for _, wanted := range query {
+		if compared[wanted.Dimension] {
+			continue
+		}
+		compared[wanted.Dimension] = true
+
+		rule, known := specWeights[wanted.Dimension]
+		if !known {
+			continue
+		}
+
+		outcome := specOutcome{wanted: wanted}
+		for i := range product {
+			if product[i].Dimension != wanted.Dimension {
+				continue
+			}
+			if outcome.offered == nil {
+				outcome.offered = &product[i]
+			}
+			if specsEqual(wanted.Value, product[i].Value, rule.tolerance) {
+				outcome.offered, outcome.matched = &product[i], true
+				break
+			}
+		}
+		outcomes = append(outcomes, outcome)
+	}
+	return outcomes
+}
+
+func specsEqual(a, b, tolerance float64) bool {
+	larger := math.Max(a, b)
+	return larger == 0 || math.Abs(a-b)/larger <= tolerance
+}
@@ -0,0 +1,91 @@
+package matcher
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestExtractSpecs(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string // dimension:value in base units
+	}{
+		{"iPhone 16 Pro 128GB", []string{"storage:128"}},
+		{"Galaxy S24 Ultra 1TB", []string{"storage:1024"}},
+		{"OnePlus 12 (12GB RAM, 256GB Storage)", []string{"ram:12", "storage:256"}},
+		{"5,000mAh battery", []string{"battery:5000"}},
+		{"Nescafe Classic 200g", []string{"weight:200"}},
+		{"Tata Salt 1 kg", []string{"weight:1000"}},
+		{"Coca-Cola 750ml", []string{"volume:750"}},
+		{"Coca-Cola 2.25 L", []string{"volume:2250"}},
+		{"Instant Pot 8 Quart", []string{"volume:7570.824"}},
+		{"MacBook Air 13.6-inch", []string{"length:13.6"}},
+		{`Samsung 55" Crystal UHD`, []string{"length:55"}},
+		{"Dell 13in laptop", []string{"length:13"}},
+		{"TV 55 in", []string{"length:55"}},
+		{"iPhone 16 in black", nil},
+		{"Pixel 8 in 128GB", []string{"storage:128"}},
+		{"Instant Pot Duo 7-in-1", nil},
+		{"2 in 1 laptop", nil},
+		{"Galaxy S24 5G", nil},
+		{"iPhone 15 w/ case", nil},
+		{"Anker 65W charger w/o cable", []string{"power:65"}},
+		{"Canon EOS R50 with 18-45mm lens", nil},
+		{"Canon EOS R50 Kit 18-150mm", nil},
+		{"Sony FE 50mm f/1.8 lens", nil},
+		{"Canon RF 24-105mm f/4 L", nil},
+		{"Nikon Z50 24GB w/ 16-50mm", []string{"storage:24"}},
+		{"Dell monitor 600mm wide", []string{"length:23.622"}},
+	}
+	for _, test := range tests {
+		var got []string
+		for _, spec := range ExtractSpecs(" " + normalizeQueryText(test.text) + " ") {
+			got = append(got, spec.Dimension+":"+strconv.FormatFloat(spec.Value, 'f', -1, 64))
+		}
+		if strings.Join(got, ",") != strings.Join(test.want, ",") {
+			t.Errorf("ExtractSpecs(%q) = %v, want %v", test.text, got, test.want)
+		}
+	}
+}
+
+func TestStripSpecsKeepsPrepositionIn(t *testing.T) {
+	tests := map[string]string{
+		" iphone 16 in black ":  "iphone 16 in black",
+		" iphone 16 pro 128gb ": "iphone 16 pro",
+		" tv 55 in ":            "tv",
+	}
+	for text, want := range tests {
+		if got := strings.Join(strings.Fields(stripSpecs(text)), " "); got != want {
+			t.Errorf("stripSpecs(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestParseQueryKeepsModelBesideLensAndWith(t *testing.T) {
+	s := newTestService(t)
+	tests := map[string]string{
+		"iPhone 15 w/ case":               "15",
+		"Canon EOS R50 with 18-45mm lens": "r50",
+		"Canon EOS R50 Kit 18-150mm":      "r50",
+	}
+	for query, model := range tests {
+		if intent := s.ParseQueryRules(query); intent.Model != model || len(intent.Specs) != 0 {
+			t.Errorf("ParseQueryRules(%q) model %q, specs %v; want model %s and no specs", query, intent.Model, intent.Specs, model)
+		}
+	}
+
+	// Kits with different lenses are not variants of each other
+	kit := s.ParseQueryRules("Canon EOS R50 with 18-45mm lens")
+	if conflicts := s.VariantConflicts(kit, "Canon EOS R50 Mirrorless Camera with RF-S 18-150mm Lens"); len(conflicts) != 0 {
+		t.Errorf("VariantConflicts() = %v between lens kits, want none", conflicts)
+	}
+}
+
+func TestParseQueryReadsModelBeforePrepositionIn(t *testing.T) {
+	s := newTestService(t)
+	intent := s.ParseQueryRules("iPhone 16 in black")
+	if intent.Model != "16" || intent.Size != "" || len(intent.Specs) != 0 {
+		t.Errorf("ParseQueryRules() model %q, size %q, specs %v; want model 16 and no size", intent.Model, intent.Size, intent.Specs)
+	}
+}
@@ -0,0 +1,177 @@
+package matcher
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"price-comparison-tool/internal/models"
+)
+
+// Scoring strategies selectable per request
+const (
+	StrategyFuzzy     = "fuzzy"
+	StrategyBasic     = "basic"
+	StrategyBM25      = "bm25" // BM25 over the search's pooled results
+	StrategyLLM       = "llm"
+	StrategyEmbedding = "embedding"
+	StrategyCascade   = "cascade" // Fuzzy settles clear cases, a small model the middle band, a large model the uncertain rest
+	StrategyHybrid    = "hybrid"  // Former name of the cascade strategy, still accepted
+)
+
+// strategyNames lists every strategy in the order they are documented
+var strategyNames = []string{StrategyFuzzy, StrategyBasic, StrategyBM25, StrategyLLM, StrategyEmbedding, StrategyCascade}
+
+// strategyAliases maps former strategy names to the strategy that replaced them, so existing requests and
+// MATCH_STRATEGY settings keep working
+var strategyAliases = map[string]string{StrategyHybrid: StrategyCascade}
+
+// Matcher scores how well a product matches a query. Each strategy is one implementation; an error means the
+// strategy could not score the product, and callers fall back to fuzzy matching (see Service.Score).
+type Matcher interface {
+	Name() string
+	Match(ctx context.Context, intent models.QueryIntent, product models.ProductResult) (models.ScoreExplanation, error)
+}
+
+// CorpusMatcher is a Matcher that weighs a title against the other results of the same search
+type CorpusMatcher interface {
+	Matcher
+	WithCorpus(corpus *Corpus) Matcher
+}
+
+// ForResults binds m to the results of one search when it scores against them, otherwise returns m
+func ForResults(m Matcher, products []models.ProductResult) Matcher {
+	if corpusMatcher, ok := m.(CorpusMatcher); ok {
+		return corpusMatcher.WithCorpus(NewCorpus(products))
+	}
+	return m
+}
+
+// IsValidStrategy reports whether name is a known scoring strategy ("" selects the default)
+func IsValidStrategy(name string) bool {
+	if name == "" {
+		return true
+	}
+	if _, alias := strategyAliases[name]; alias {
+		return true
+	}
+	for _, strategy := range strategyNames {
+		if name == strategy {
+			return true
+		}
+	}
+	return false
+}
+
+// Strategies lists the names of every scoring strategy
+func Strategies() []string {
+	return append([]string(nil), strategyNames...)
+}
+
+// newStrategies builds one matcher per strategy over s; an alias shares the matcher, and its stats, of the
+// strategy it names
+func newStrategies(s *Service) map[string]Matcher {
+	strategies := map[string]Matcher{
+		StrategyFuzzy:     fuzzyMatcher{s},
+		StrategyBasic:     basicMatcher{s},
+		StrategyBM25:      bm25Matcher{s: s},
+		StrategyLLM:       llmMatcher{s},
+		StrategyEmbedding: embeddingMatcher{s},
+		StrategyCascade:   newCascadeMatcher(s),
+	}
+	for alias, name := range strategyAliases {
+		strategies[alias] = strategies[name]
+	}
+	return strategies
+}
+
+// defaultStrategy is the configured strategy, or the LLM when the configuration names an unknown one
+func defaultStrategy(name string) string {
+	if name == "" || !IsValidStrategy(name) {
+		if name != "" {
+			log.Printf("⚠️ Unknown MATCH_STRATEGY %q, using %s", name, StrategyLLM)
+		}
+		return StrategyLLM
+	}
+	return name
+}
+
+// Strategy returns the matcher for a strategy name; "" or an unknown name gives the configured default
+func (s *Service) Strategy(name string) Matcher {
+	if matcher, known := s.strategies[name]; known {
+		return matcher
+	}
+	return s.strategies[s.defaultStrategy]
+}
+
+// Score matches product with m, falling back to fuzzy matching when m fails. It returns the explanation and
+// the strategy that produced it.
+func (s *Service) Score(ctx context.Context, m Matcher, intent models.QueryIntent, product models.ProductResult) (models.ScoreExplanation, string) {
+	explanation, err := m.Match(ctx, intent, product)
+	if err == nil {
+		return explanation, m.Name()
+	}
+	explanation = s.ExplainFuzzyMatch(intent, product.ProductName)
+	explanation.Rules = append(explanation.Rules, fmt.Sprintf("%s scoring failed: %v", m.Name(), err))
+	return explanation, StrategyFuzzy
+}
+
+// fuzzyMatcher scores with string similarity and the weighted attribute features
+type fuzzyMatcher struct{ s *Service }
+
+func (m fuzzyMatcher) Name() string { return StrategyFuzzy }
+
+func (m fuzzyMatcher) Match(ctx context.Context, intent models.QueryIntent, product models.ProductResult) (models.ScoreExplanation, error) {
+	return m.s.ExplainFuzzyMatch(intent, product.ProductName), nil
+}
+
+// basicMatcher scores by the share of query words found in the title
+type basicMatcher struct{ s *Service }
+
+func (m basicMatcher) Name() string { return StrategyBasic }
+
+func (m basicMatcher) Match(ctx context.Context, intent models.QueryIntent, product models.ProductResult) (models.ScoreExplanation, error) {
+	return models.ScoreExplanation{Scorer: ScorerBasic, Score: m.s.BasicProductMatch(intent.Raw, product.ProductName)}, nil
+}
+
+// bm25Matcher scores by BM25 similarity over the search's results with the weighted attribute features. Unbound
+// to a search, each title is its own corpus and every query term weighs the same.
+type bm25Matcher struct {
+	s      *Service
+	corpus *Corpus
+}
+
+func (m bm25Matcher) Name() string { return StrategyBM25 }
+
+func (m bm25Matcher) WithCorpus(corpus *Corpus) Matcher {
+	return bm25Matcher{s: m.s, corpus: corpus}
+}
+
+func (m bm25Matcher) Match(ctx context.Context, intent models.QueryIntent, product models.ProductResult) (models.ScoreExplanation, error) {
+	corpus := m.corpus
+	if corpus == nil {
+		corpus = NewCorpus([]models.ProductResult{product})
+	}
+	explanation := m.s.bm25Match(intent, product.ProductName, corpus)
+	m.s.explainRules(intent, product.ProductName, &explanation)
+	return explanation, nil
+}
+
+// llmMatcher scores with the scoring prompt, guarded by the brand and variant rules
+type llmMatcher struct{ s *Service }
+
+func (m llmMatcher) Name() string { return StrategyLLM }
+
+func (m llmMatcher) Match(ctx context.Context, intent models.QueryIntent, product models.ProductResult) (models.ScoreExplanation, error) {
+	explanation, promptVersion, err := m.s.scoreProductMatch(ctx, m.s.config.OllamaModel, intent, product)
+	explanation.PromptVersion = promptVersion
+	return explanation, err
+}
+
+// embeddingMatcher scores by embedding similarity with the weighted attribute features
+type embeddingMatcher struct{ s *Service }
+
+func (m embeddingMatcher) Name() string { return StrategyEmbedding }
+
+func (m embeddingMatcher) Match(ctx context.Context, intent models.QueryIntent, product models.ProductResult) (models.ScoreExplanation, error) {
+	return m.s.ExplainEmbeddingMatch(ctx, intent, product.ProductName)
+}
@@ -0,0 +1,19 @@
+package matcher
+
+import "testing"
+
+func TestHybridIsAnAliasOfCascade(t *testing.T) {
+	s := newTestService(t)
+
+	if !IsValidStrategy(StrategyHybrid) {
+		t.Fatalf("IsValidStrategy(%q) = false, want the former name accepted", StrategyHybrid)
+	}
+	if got := s.Strategy(StrategyHybrid).Name(); got != StrategyCascade {
+		t.Errorf("Strategy(%q).Name() = %q, want %q", StrategyHybrid, got, StrategyCascade)
+	}
+	for _, name := range Strategies() {
+		if name == StrategyHybrid {
+			t.Errorf("Strategies() lists the alias %q", StrategyHybrid)
+		}
+	}
+}
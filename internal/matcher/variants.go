@@ -0,0 +1,301 @@
+package matcher
+
+import (
+	"fmt"
+	"math"
+	"price-comparison-tool/internal/models"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// specAttributes names the variant conflict of each spec dimension, in the order they are checked
+var specAttributes = []struct{ dimension, attribute string }{
+	{DimensionStorage, "storage"},
+	{DimensionRAM, "ram"},
+	{DimensionLength, "size"},
+	{DimensionBattery, "battery"},
+	{DimensionPower, "power"},
+	{DimensionVolume, "volume"},
+	{DimensionWeight, "weight"},
+}
+
+// modelShapePattern splits a model token into its leading letters and the rest, so "s24" and "s23" share "s"
+var modelShapePattern = regexp.MustCompile(`^([a-z-]*)(\d+)(.*)$`)
+
+// VariantConflict is one attribute on which a title names a different variant than the query
+type VariantConflict struct {
+	Attribute string // "line", "suffix", "generation", "pack" or a spec attribute such as "storage" or "size"
+	Wanted    string
+	Found     string
+	Penalty   float64 // The attribute's weight in Weights.Variants
+}
+
+// String explains the conflict, e.g. "storage: 256GB instead of 128GB"
+func (c VariantConflict) String() string {
+	wanted, found := c.Wanted, c.Found
+	if wanted == "" {
+		wanted = "none"
+	}
+	if found == "" {
+		found = "none"
+	}
+	return fmt.Sprintf("%s: %s instead of %s", c.Attribute, found, wanted)
+}
+
+// VariantConflicts lists the attributes on which productName names a different variant than the query:
+// product lines (XPS vs Inspiron), model suffixes (Pro vs Pro Max, S24 vs S24 Ultra), generations (16 vs 15,
+// 8a vs 8), every quantity (storage, RAM, screen size, battery, power, volume, weight) and pack count. Attributes
+// the title does not state are not conflicts.
+func (s *Service) VariantConflicts(intent models.QueryIntent, productName string) []VariantConflict {
+	productText := " " + normalizeQueryText(productName) + " "
+	productSpecs := ExtractSpecs(productText)
+	stripped := " " + strings.Join(strings.Fields(stripSpecs(productText)), " ") + " "
+
+	var conflicts []VariantConflict
+	if conflict, found := s.lineConflict(intent, productText); found {
+		conflicts = append(conflicts, conflict)
+	}
+	if conflict, found := suffixConflict(intent, stripped); found {
+		conflicts = append(conflicts, conflict)
+	}
+	if conflict, found := generationConflict(intent, stripped); found {
+		conflicts = append(conflicts, conflict)
+	}
+	for _, spec := range specAttributes {
+		if conflict, found := specConflict(intent.Specs, productSpecs, spec.dimension, spec.attribute); found {
+			conflicts = append(conflicts, conflict)
+		}
+	}
+	if conflict, found := packConflict(intent, productText); found {
+		conflicts = append(conflicts, conflict)
+	}
+	for i := range conflicts {
+		conflicts[i].Penalty = s.weights.Variants[conflicts[i].Attribute]
+	}
+	return conflicts
+}
+
+// applyPenalties clamps score to [0, 1] and then subtracts the relevance penalty and the penalty of every variant
+// conflict, returning the score and the total variant penalty. Subtracting after the clamp keeps a title whose
+// bonuses overflow 1.0, such as a case naming the phone's model and storage, from hiding the penalty.
+func (s *Service) applyPenalties(score, relevancePenalty float64, intent models.QueryIntent, productName string) (float64, float64) {
+	score = math.Max(0, math.Min(1, score)) - relevancePenalty
+	penalty := 0.0
+	for _, conflict := range s.VariantConflicts(intent, productName) {
+		score -= conflict.Penalty
+		penalty += conflict.Penalty
+	}
+	return math.Max(0, score), penalty
+}
+
+// lineConflict finds a title naming only other product lines of the query's brand, "inspiron" for "xps"
+func (s *Service) lineConflict(intent models.QueryIntent, product string) (VariantConflict, bool) {
+	if intent.Brand == "" || intent.ProductLine == "" {
+		return VariantConflict{}, false
+	}
+	lines := s.brands.Lines(product, intent.Brand)
+	if len(lines) == 0 {
+		return VariantConflict{}, false
+	}
+	for _, line := range lines {
+		if line == intent.ProductLine {
+			return VariantConflict{}, false
+		}
+	}
+	return VariantConflict{
+		Attribute: "line",
+		Wanted:    intent.ProductLine,
+		Found:     lines[0],
+	}, true
+}
+
+// suffixConflict compares the suffixes around the query's model and product line in the title: "16 pro max"
+// against "16 pro", "macbook pro" against "macbook air", or "s24" against "s24 ultra". Suffixes are only read next
+// to those anchors, so "Ultra HD" elsewhere is ignored. A title leaving out the suffix asked for names the base
+// model, so a suffix on only one side is a conflict too.
+func suffixConflict(intent models.QueryIntent, product string) (VariantConflict, bool) {
+	// Words of the product line are part of the name, not suffixes: "air max" in "Nike Air Max 270"
+	lineWords := strings.Fields(intent.ProductLine)
+	isLineWord := make(map[string]bool)
+	for _, word := range lineWords {
+		isLineWord[word] = true
+	}
+	var anchors []string
+	if intent.Model != "" {
+		anchors = append(anchors, intent.Model)
+	}
+	if len(lineWords) > 0 {
+		anchors = append(anchors, lineWords[len(lineWords)-1])
+	}
+
+	tokens := strings.Fields(product)
+	found := make(map[string]bool)
+	anchored := false
+	for _, anchor := range anchors {
+		for i, token := range tokens {
+			if token != anchor {
+				continue
+			}
+			anchored = true
+			// Dashes and other punctuation between the anchor and a suffix are skipped: "switch – oled"
+			for j := i - 1; j >= 0 && (variantTokens[tokens[j]] || isPunctuation(tokens[j])); j-- {
+				if variantTokens[tokens[j]] && !isLineWord[tokens[j]] {
+					found[tokens[j]] = true
+				}
+			}
+			for j := i + 1; j < len(tokens) && (variantTokens[tokens[j]] || isPunctuation(tokens[j])); j++ {
+				if variantTokens[tokens[j]] && !isLineWord[tokens[j]] {
+					found[tokens[j]] = true
+				}
+			}
+			break
+		}
+	}
+	if !anchored {
+		return VariantConflict{}, false
+	}
+
+	wanted := make(map[string]bool, len(intent.Variants))
+	for _, variant := range intent.Variants {
+		wanted[variant] = true
+	}
+	if len(found) == len(wanted) {
+		same := true
+		for variant := range wanted {
+			same = same && found[variant]
+		}
+		if same {
+			return VariantConflict{}, false
+		}
+	}
+
+	return VariantConflict{
+		Attribute: "suffix",
+		Wanted:    strings.Join(intent.Variants, " "),
+		Found:     joinInOrder(tokens, found),
+	}, true
+}
+
+// generationConflict finds a model token with the query model's leading letters but another number or letters
+// after it: "s23" for "s24", "wh-1000xm4" for "wh-1000xm5", or "8" and "7a" for "8a". Bare numbers like "16" only
+// count right after the product line, as in "iphone 15".
+func generationConflict(intent models.QueryIntent, product string) (VariantConflict, bool) {
+	if intent.Model == "" || containsTerm(product, intent.Model) {
+		return VariantConflict{}, false
+	}
+	wanted := modelShapePattern.FindStringSubmatch(intent.Model)
+	if wanted == nil {
+		return VariantConflict{}, false
+	}
+
+	lineWords := strings.Fields(intent.ProductLine)
+	tokens := strings.Fields(product)
+	for i, token := range tokens {
+		candidate := modelShapePattern.FindStringSubmatch(token)
+		if candidate == nil || candidate[1] != wanted[1] {
+			continue
+		}
+		if wanted[1] == "" {
+			// A bare number needs the product line just before it to be read as a generation
+			if len(lineWords) == 0 || i == 0 || tokens[i-1] != lineWords[len(lineWords)-1] {
+				continue
+			}
+		}
+		return VariantConflict{
+			Attribute: "generation",
+			Wanted:    intent.Model,
+			Found:     token,
+		}, true
+	}
+	return VariantConflict{}, false
+}
+
+// specConflict reports a title that states the dimension only with values other than the one asked for
+func specConflict(query, product []models.Spec, dimension, attribute string) (VariantConflict, bool) {
+	var wanted *models.Spec
+	for i := range query {
+		if query[i].Dimension == dimension {
+			wanted = &query[i]
+			break
+		}
+	}
+	if wanted == nil {
+		return VariantConflict{}, false
+	}
+
+	tolerance := specWeights[dimension].tolerance
+	var offered *models.Spec
+	for i := range product {
+		if product[i].Dimension != dimension {
+			continue
+		}
+		if specsEqual(wanted.Value, product[i].Value, tolerance) {
+			return VariantConflict{}, false
+		}
+		if offered == nil {
+			offered = &product[i]
+		}
+	}
+	if offered == nil {
+		return VariantConflict{}, false
+	}
+
+	return VariantConflict{
+		Attribute: attribute,
+		Wanted:    formatSpec(*wanted),
+		Found:     formatSpec(*offered),
+	}, true
+}
+
+// packConflict compares pack counts when the query states one, so "pack of 3" conflicts with "Pack of 6"
+func packConflict(intent models.QueryIntent, product string) (VariantConflict, bool) {
+	if intent.Quantity == 0 {
+		return VariantConflict{}, false
+	}
+	count := packCount(product)
+	if count == 0 || count == intent.Quantity {
+		return VariantConflict{}, false
+	}
+	return VariantConflict{
+		Attribute: "pack",
+		Wanted:    strconv.Itoa(intent.Quantity),
+		Found:     strconv.Itoa(count),
+	}, true
+}
+
+// packCount reads "pack of 6", "6 pack" or "6 pcs" from normalized text, 0 when none is stated
+func packCount(text string) int {
+	match := quantityPattern.FindStringSubmatch(text)
+	if match == nil {
+		return 0
+	}
+	number := match[1]
+	if number == "" {
+		number = match[2]
+	}
+	count, _ := strconv.Atoi(number)
+	return count
+}
+
+func formatSpec(spec models.Spec) string {
+	return strconv.FormatFloat(spec.Value, 'f', -1, 64) + spec.Unit
+}
+
+// isPunctuation reports a token without letters or digits, such as a dash between words
+func isPunctuation(token string) bool {
+	return strings.IndexFunc(token, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) < 0
+}
+
+func joinInOrder(tokens []string, set map[string]bool) string {
+	var ordered []string
+	seen := make(map[string]bool)
+	for _, token := range tokens {
+		if set[token] && !seen[token] {
+			ordered = append(ordered, token)
+			seen[token] = true
+		}
+	}
+	return strings.Join(ordered, " ")
+}
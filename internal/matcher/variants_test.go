@@ -0,0 +1,94 @@
+package matcher
+
+import "testing"
+
+func TestVariantConflicts(t *testing.T) {
+	s := newTestService(t)
+
+	tests := []struct {
+		query, title string
+		want         string // attribute of the first conflict, "" for none
+	}{
+		{"Nescafe Classic 200g", "Nescafe Classic Instant Coffee, 50g Pouch", "weight"},
+		{"Nescafe Classic 200g", "Nescafe Classic Instant Coffee Powder, 200g Jar", ""},
+		{"Tata Salt 1kg", "Tata Salt Iodised 1000 g Pouch", ""},
+		{"Instant Pot Duo 8 quart", "Instant Pot Duo 7-in-1 Electric Pressure Cooker, 6 Quart", "volume"},
+		{"Coca-Cola 750ml", "Coca-Cola Soft Drink 2.25 L Bottle", "volume"},
+		{"Coca-Cola 750ml", "Coke Original Taste 0.75 L PET Bottle", ""},
+		{"OnePlus 12 16GB RAM", "OnePlus 12 (Silky Black, 12GB RAM, 256GB Storage)", "ram"},
+		{"Samsung 55 inch Crystal UHD TV", "Samsung 65\" Crystal UHD 4K Smart TV", "size"},
+		{"iPhone 16 in black", "Apple iPhone 16 128GB Black", ""},
+		{"Samsung Galaxy S24 Ultra", "Samsung Galaxy S24 5G (Onyx Black, 8GB, 128GB)", "suffix"},
+		{"Samsung Galaxy S24", "Samsung Galaxy S24 Ultra 256GB Titanium Violet", "suffix"},
+		{"Nintendo Switch OLED", "Nintendo Switch Lite - Turquoise", "suffix"},
+		{"Nintendo Switch Lite", "Nintendo Switch – OLED Model w/ Neon Red & Neon Blue Joy-Con", "suffix"},
+		{"Google Pixel 8a", "Google Pixel 8 Pro 256GB Bay", "generation"},
+		{"iPhone 15 128GB", "Apple iPhone 16 (128 GB) - Black", "generation"},
+		{"Samsung Galaxy S24 Ultra 1TB", "Samsung Galaxy S24 Ultra 512GB Titanium Gray Unlocked", "storage"},
+		{"Nintendo Switch OLED", "Nintendo Switch – OLED Model w/ White Joy-Con", ""},
+		{"MacBook Air M2", "Apple 2022 MacBook Air Laptop with M2 chip", ""},
+		{"PlayStation 5 Slim", "PlayStation 5 Console Slim Disc Edition", ""},
+		{"Samsung Galaxy S24 Ultra", "Samsung Galaxy S24 Ultra 5G AI Smartphone", ""},
+	}
+	for _, test := range tests {
+		conflicts := s.VariantConflicts(s.ParseQueryRules(test.query), test.title)
+		got := ""
+		if len(conflicts) > 0 {
+			got = conflicts[0].Attribute
+		}
+		if got != test.want {
+			t.Errorf("VariantConflicts(%q, %q) = %v, want %q first", test.query, test.title, conflicts, test.want)
+		}
+	}
+}
+
+func TestSpecMismatchPenalisedAfterClamp(t *testing.T) {
+	s := newTestService(t)
+
+	tests := []struct {
+		query, exact, other, attribute string
+	}{
+		{"Nescafe Classic 200g", "Nescafe Classic Instant Coffee Powder, 200g Jar", "Nescafe Classic Instant Coffee, 50g Pouch", "weight"},
+		{"Instant Pot Duo 8 quart", "Instant Pot Duo 7-in-1 Electric Pressure Cooker, 8 Quart", "Instant Pot Duo 7-in-1 Electric Pressure Cooker, 6 Quart", "volume"},
+	}
+	for _, test := range tests {
+		intent := s.ParseQueryRules(test.query)
+		exact, other := s.FuzzyProductMatchIntent(intent, test.exact), s.FuzzyProductMatchIntent(intent, test.other)
+		if penalty := s.weights.Variants[test.attribute]; other > exact-penalty+0.001 {
+			t.Errorf("%q scored %.3f, want at least the %s penalty %.2f below %q at %.3f",
+				test.other, other, test.attribute, penalty, test.exact, exact)
+		}
+	}
+}
+
+func TestOtherVariantFallsBelowMinConfidence(t *testing.T) {
+	s := newTestService(t)
+
+	tests := []struct {
+		query, exact, other string
+	}{
+		{"Samsung Galaxy S24 Ultra 1TB", "Samsung Galaxy S24 Ultra 1TB Titanium Black Unlocked", "Samsung Galaxy S24 Ultra 512GB Titanium Gray Unlocked"},
+		{"iPhone 15 128GB", "Apple iPhone 15 (128 GB) - Black", "Apple iPhone 16 (128 GB) - Black"},
+		{"Samsung Galaxy S24 Ultra", "Samsung Galaxy S24 Ultra 5G AI Smartphone", "Samsung Galaxy S24 5G (Onyx Black, 8GB, 128GB)"},
+		{"Nintendo Switch OLED", "Nintendo Switch (OLED model) Console, Neon Blue/Red", "Nintendo Switch Lite - Turquoise"},
+		{"Google Pixel 8a", "Google Pixel 8a 128GB Obsidian Unlocked", "Google Pixel 8 Pro 256GB Bay"},
+	}
+	const minConfidence = 0.3 // MIN_CONFIDENCE default
+	for _, test := range tests {
+		intent := s.ParseQueryRules(test.query)
+		for _, scorer := range []struct {
+			name  string
+			score func(string) float64
+		}{
+			{"fuzzy", func(title string) float64 { return s.FuzzyProductMatchIntent(intent, title) }},
+			{"basic", func(title string) float64 { return s.BasicProductMatch(test.query, title) }},
+		} {
+			if exact := scorer.score(test.exact); exact < minConfidence {
+				t.Errorf("%s: %q scored %.3f, want at least %.2f", scorer.name, test.exact, exact, minConfidence)
+			}
+			if other := scorer.score(test.other); other >= minConfidence {
+				t.Errorf("%s: %q scored %.3f for %q, want below %.2f", scorer.name, test.other, other, test.query, minConfidence)
+			}
+		}
+	}
+}
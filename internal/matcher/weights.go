@@ -0,0 +1,226 @@
+package matcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"price-comparison-tool/internal/models"
+	"time"
+)
+
+// variantAttributes are the attributes VariantConflicts reports, in the order it checks them
+var variantAttributes = []string{"line", "suffix", "generation", "storage", "ram", "size", "battery", "power", "volume", "weight", "pack"}
+
+// specDimensions fixes the order spec weights are summed and listed in
+var specDimensions = []string{DimensionStorage, DimensionRAM, DimensionLength, DimensionBattery, DimensionPower, DimensionVolume, DimensionWeight}
+
+// Weights are the coefficients of the fuzzy and embedding scores. Every weight is a magnitude: bonuses are added
+// and conflicts and penalties subtracted. A weights file written by cmd/trainweights replaces the defaults.
+type Weights struct {
+	BrandMatch        float64            `json:"brandMatch"`
+	BrandConflict     float64            `json:"brandConflict"`
+	Model             float64            `json:"model"`
+	Specs             map[string]float64 `json:"specs"` // Per dimension, added when equal; a different quantity is a variant conflict
+	Color             float64            `json:"color"`
+	ConditionMatch    float64            `json:"conditionMatch"`
+	ConditionConflict float64            `json:"conditionConflict"`
+	AddOnResult       float64            `json:"addOnResult"`
+	PrimaryResult     float64            `json:"primaryResult"`
+	Variants          map[string]float64 `json:"variants"` // Per conflicting attribute, subtracted after clamping
+
+	TrainedAt *time.Time `json:"trainedAt,omitempty"`
+	Examples  int        `json:"examples,omitempty"` // Feedback entries the weights were fitted to
+}
+
+// DefaultWeights are the hand-set weights used until a weights file is trained. Variant penalties outweigh the
+// bonuses a near-identical title earns, so "iPhone 16 Pro Max 256GB" ranks below an exact "iPhone 16 Pro 128GB".
+// A conflict on what identifies the product (line, suffix, generation, storage, size, volume or weight) takes even
+// a perfect score below the default MIN_CONFIDENCE of 0.3; RAM, battery, power and pack count weigh less.
+func DefaultWeights() Weights {
+	specs := make(map[string]float64, len(specWeights))
+	for dimension, rule := range specWeights {
+		specs[dimension] = rule.weight
+	}
+	return Weights{
+		BrandMatch:        0.3,
+		BrandConflict:     0.2,
+		Model:             0.2,
+		Specs:             specs,
+		Color:             0.05,
+		ConditionMatch:    0.05,
+		ConditionConflict: 0.1,
+		AddOnResult:       0.4,
+		PrimaryResult:     0.3,
+		Variants: map[string]float64{
+			"line":       0.75,
+			"suffix":     0.75,
+			"generation": 0.75,
+			"storage":    0.75,
+			"ram":        0.3,
+			"size":       0.75,
+			"battery":    0.3,
+			"power":      0.3,
+			"volume":     0.75,
+			"weight":     0.75,
+			"pack":       0.5,
+		},
+	}
+}
+
+// LoadWeights reads a weights file over the defaults; a missing file or path gives the defaults
+func LoadWeights(path string) (Weights, error) {
+	weights := DefaultWeights()
+	if path == "" {
+		return weights, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return weights, nil
+	} else if err != nil {
+		return weights, err
+	}
+
+	var loaded Weights
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return weights, fmt.Errorf("parse %s: %v", path, err)
+	}
+	// Dimensions and attributes the file leaves out keep their defaults
+	for dimension, weight := range weights.Specs {
+		if _, set := loaded.Specs[dimension]; !set {
+			if loaded.Specs == nil {
+				loaded.Specs = make(map[string]float64)
+			}
+			loaded.Specs[dimension] = weight
+		}
+	}
+	for attribute, weight := range weights.Variants {
+		if _, set := loaded.Variants[attribute]; !set {
+			if loaded.Variants == nil {
+				loaded.Variants = make(map[string]float64)
+			}
+			loaded.Variants[attribute] = weight
+		}
+	}
+	return loaded, nil
+}
+
+// SaveWeights writes weights to path as indented JSON
+func SaveWeights(path string, weights Weights) error {
+	data, err := json.MarshalIndent(weights, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Weights returns the weights the fuzzy and embedding scores use
+func (s *Service) Weights() Weights {
+	return s.weights
+}
+
+// WeightTerm is one trainable weight with the feature it multiplies. Sign is 1 for a bonus and -1 for a
+// penalty, so a trainer keeping weights non-negative never turns a penalty into a bonus. AfterClamp marks the
+// relevance and variant penalties, which are subtracted once the score is clamped to [0, 1].
+type WeightTerm struct {
+	Name       string
+	Sign       float64
+	AfterClamp bool
+	Feature    func(models.MatchFeatures) float64
+	Get        func() float64
+	Set        func(float64)
+}
+
+// Terms lists every weight of w in a fixed order for trainers; Set writes through to w
+func (w *Weights) Terms() []WeightTerm {
+	field := func(name string, sign float64, feature func(models.MatchFeatures) float64, weight *float64) WeightTerm {
+		return WeightTerm{
+			Name:    name,
+			Sign:    sign,
+			Feature: feature,
+			Get:     func() float64 { return *weight },
+			Set:     func(value float64) { *weight = value },
+		}
+	}
+	entry := func(name string, sign float64, weights map[string]float64, key string, features func(models.MatchFeatures) map[string]float64) WeightTerm {
+		return WeightTerm{
+			Name:    name + "." + key,
+			Sign:    sign,
+			Feature: func(f models.MatchFeatures) float64 { return features(f)[key] },
+			Get:     func() float64 { return weights[key] },
+			Set:     func(value float64) { weights[key] = value },
+		}
+	}
+
+	terms := []WeightTerm{
+		field("brandMatch", 1, func(f models.MatchFeatures) float64 { return f.BrandMatch }, &w.BrandMatch),
+		field("brandConflict", -1, func(f models.MatchFeatures) float64 { return f.BrandConflict }, &w.BrandConflict),
+		field("model", 1, func(f models.MatchFeatures) float64 { return f.ModelMatch }, &w.Model),
+	}
+	for _, dimension := range specDimensions {
+		terms = append(terms, entry("specs", 1, w.Specs, dimension, equalSpecs))
+	}
+	terms = append(terms,
+		field("color", 1, func(f models.MatchFeatures) float64 { return f.ColorMatch }, &w.Color),
+		field("conditionMatch", 1, func(f models.MatchFeatures) float64 { return f.ConditionMatch }, &w.ConditionMatch),
+		field("conditionConflict", -1, func(f models.MatchFeatures) float64 { return f.ConditionConflict }, &w.ConditionConflict),
+	)
+	relevance := []WeightTerm{
+		field("addOnResult", -1, func(f models.MatchFeatures) float64 { return f.AddOnResult }, &w.AddOnResult),
+		field("primaryResult", -1, func(f models.MatchFeatures) float64 { return f.PrimaryResult }, &w.PrimaryResult),
+	}
+	for _, attribute := range variantAttributes {
+		relevance = append(relevance, entry("variants", -1, w.Variants, attribute, func(f models.MatchFeatures) map[string]float64 { return f.Variants }))
+	}
+	for i := range relevance {
+		relevance[i].AfterClamp = true
+	}
+	return append(terms, relevance...)
+}
+
+// Score is the fuzzy score the matcher serves for features: the base similarity plus the weighted bonuses,
+// clamped to [0, 1], minus the relevance and variant penalties, floored at 0. Trainers fit this form, so the
+// weights they write are judged on the score results are ranked and filtered by.
+func (w *Weights) Score(features models.MatchFeatures) float64 {
+	terms := w.Terms()
+	score := features.BaseSimilarity
+	for _, term := range terms {
+		if !term.AfterClamp {
+			score += term.Sign * term.Get() * term.Feature(features)
+		}
+	}
+	score = math.Max(0, math.Min(1, score))
+	for _, term := range terms {
+		if term.AfterClamp {
+			score += term.Sign * term.Get() * term.Feature(features)
+		}
+	}
+	return math.Max(0, score)
+}
+
+// specAdjustment adds the weight of each dimension stated with an equal quantity; different quantities are
+// penalised as variant conflicts after clamping
+func (w Weights) specAdjustment(specs map[string]float64) float64 {
+	adjustment := 0.0
+	for dimension, equal := range equalSpecs(models.MatchFeatures{Specs: specs}) {
+		adjustment += w.Specs[dimension] * equal
+	}
+	return adjustment
+}
+
+// equalSpecs keeps the dimensions stated with an equal quantity. Features record a different quantity as -1,
+// which the spec weights no longer subtract; the variant conflict does.
+func equalSpecs(features models.MatchFeatures) map[string]float64 {
+	equal := make(map[string]float64, len(features.Specs))
+	for dimension, value := range features.Specs {
+		if value > 0 {
+			equal[dimension] = value
+		}
+	}
+	return equal
+}
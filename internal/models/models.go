@@ -1,21 +1,150 @@
 package models
 
-import "time"
+import (
+	"price-comparison-tool/internal/money"
+	"sort"
+	"time"
+)
 
 type PriceRequest struct {
-	Country string `json:"country" binding:"required"`
-	Query   string `json:"query" binding:"required"`
+	Country  string `json:"country" binding:"required"`
+	Query    string `json:"query"`              // Required unless GTIN is set
+	GTIN     string `json:"gtin,omitempty"`     // Barcode to search for; offers carrying it match with confidence 1
+	Strategy string `json:"strategy,omitempty"` // Scoring strategy: "fuzzy", "basic", "bm25", "llm", "embedding" or "cascade"; defaults to MATCH_STRATEGY
+
+	// MinConfidence drops results scoring below it; defaults to MIN_CONFIDENCE
+	MinConfidence *float64 `json:"minConfidence,omitempty"`
+
+	// GroupVariants moves results for other variants (Pro Max for Pro, 256GB for 128GB) into OtherVariants
+	GroupVariants bool `json:"groupVariants,omitempty"`
+
+	// Grouped clusters offers of the same product across sites and returns them as Groups
+	Grouped bool `json:"grouped,omitempty"`
+
+	// Condition keeps only results in this condition: "new", "open-box", "refurbished", "used", "for-parts"
+	// or "any". When empty, a condition named in the query is used, otherwise "new".
+	Condition string `json:"condition,omitempty"`
+
+	// Explain attaches a ScoreExplanation to every result
+	Explain bool `json:"explain,omitempty"`
+
+	// DisplayCurrency adds each result's price converted into this ISO 4217 currency. Results whose confidence
+	// falls in the same band of 0.1 are then ranked by the converted price, cheapest first.
+	DisplayCurrency string `json:"displayCurrency,omitempty"`
+
+	// LandedCost adds each result's estimated total with shipping and tax for a shopper in Country. Results whose
+	// confidence falls in the same band of 0.1 are then ranked by that total, converted into DisplayCurrency when
+	// set. PostalCode picks the state or province rate where sales tax varies within the country.
+	LandedCost bool   `json:"landedCost,omitempty"`
+	PostalCode string `json:"postalCode,omitempty"`
 }
 
 type ProductResult struct {
-	Link        string    `json:"link"`
-	Price       string    `json:"price"`
-	Currency    string    `json:"currency"`
-	ProductName string    `json:"productName"`
-	Site        string    `json:"site"`
-	Country     string    `json:"country"`
-	Confidence  float64   `json:"confidence,omitempty"`
-	FetchedAt   time.Time `json:"fetchedAt"`
+	Link            string    `json:"link"`
+	Price           string    `json:"price"`
+	Currency        string    `json:"currency"`
+	ProductName     string    `json:"productName"`
+	Site            string    `json:"site"`
+	Country         string    `json:"country"`
+	Category        string    `json:"category,omitempty"`
+	Kind            string    `json:"kind,omitempty"`            // "primary", "accessory", "consumable" or "part", detected from the title
+	Accessory       bool      `json:"accessory"`                 // Set for accessories, consumables and parts
+	VariantMismatch []string  `json:"variantMismatch,omitempty"` // How the title differs from the variant asked for, e.g. "storage: 256GB instead of 128GB"
+	Confidence      float64   `json:"confidence,omitempty"`      // Probability of relevance when Calibration is set, otherwise RawConfidence
+	RawConfidence   float64   `json:"rawConfidence,omitempty"`   // Confidence on the scale of the source that produced it
+	Calibration     string    `json:"calibration,omitempty"`     // "platt" or "isotonic" when Confidence is calibrated
+	MatchedBy       string    `json:"matchedBy,omitempty"`       // Identifier kind that made the match exact, e.g. "gtin"
+	Strategy        string    `json:"strategy,omitempty"`        // Scoring strategy that produced the confidence, or "extraction" or "css-fallback" when kept from scraping
+	Condition       string    `json:"condition"`                 // "new", "open-box", "refurbished", "used" or "for-parts"
+	FetchedAt       time.Time `json:"fetchedAt"`
+
+	// PriceText is the price as the site displays it, e.g. "1.299,00 €". Price is the selling price, after any
+	// sale, as a plain decimal in Currency, e.g. "1299.00", and PriceValue holds it exactly in minor units. For a
+	// range such as "₹999 – ₹1,499" Price is the lowest and PriceRange holds both ends.
+	PriceText       string       `json:"priceText,omitempty"`
+	PriceValue      *money.Money `json:"priceValue,omitempty"`
+	PriceRange      *PriceRange  `json:"priceRange,omitempty"`
+	ListPrice       *money.Money `json:"listPrice,omitempty"`       // Struck-through original price, e.g. MRP or "Was $24.99"
+	DiscountPercent float64      `json:"discountPercent,omitempty"` // How far Price is below ListPrice
+
+	// Converted is the price in the request's display currency
+	Converted *ConvertedPrice `json:"converted,omitempty"`
+
+	// ShippingText is the delivery charge as the listing shows it, e.g. "+$5.99 shipping". Shipping is that
+	// charge, zero for free delivery, and nil when neither the listing nor its detail page shows one.
+	// TaxIncluded reports whether Price includes VAT or sales tax, as labelled or by the country's tax rule.
+	ShippingText string       `json:"shippingText,omitempty"`
+	Shipping     *money.Money `json:"shipping,omitempty"`
+	TaxIncluded  *bool        `json:"taxIncluded,omitempty"`
+
+	// Landed is the estimated total cost to the shopper when the request sets LandedCost
+	Landed *LandedCost `json:"landed,omitempty"`
+
+	// Identifiers read from the offer link, the page's structured data or the product detail page
+	Identifiers
+
+	// PromptVersions records which prompt revision produced each LLM stage, e.g. {"extraction": "v1"}
+	PromptVersions map[string]string `json:"promptVersions,omitempty"`
+
+	// Explanation breaks Confidence into its components when the request sets Explain
+	Explanation *ScoreExplanation `json:"explanation,omitempty"`
+}
+
+// ScoreExplanation records how a result's confidence was reached. For the fuzzy and embedding scorers the score
+// is BaseSimilarity plus the bonuses, clamped to [0, 1], minus RelevancePenalty and VariantPenalty. For the
+// LLM scorer the score is the model's, and the components are what the guard rules checked.
+type ScoreExplanation struct {
+	Scorer           string   `json:"scorer"` // "llm", "fuzzy", "basic", "bm25", "embedding", "gtin" or "extraction"
+	Score            float64  `json:"score"`
+	BaseSimilarity   float64  `json:"baseSimilarity"` // String or embedding similarity of query and title
+	BrandBonus       float64  `json:"brandBonus"`
+	ModelBonus       float64  `json:"modelBonus"`
+	SpecBonus        float64  `json:"specBonus"` // Specs, colour and condition
+	RelevancePenalty float64  `json:"relevancePenalty"`
+	VariantPenalty   float64  `json:"variantPenalty"`
+	LLMScore         float64  `json:"llmScore,omitempty"`      // Score parsed from the LLM response, before guard rules
+	Model            string   `json:"model,omitempty"`         // Model that produced LLMScore
+	LLMResponse      string   `json:"llmResponse,omitempty"`   // Raw LLM response
+	PromptVersion    string   `json:"promptVersion,omitempty"` // Scoring prompt revision behind LLMScore
+	Rules            []string `json:"rules,omitempty"`         // Rules that fired, e.g. "brand match: apple"
+}
+
+// FeedbackRequest judges a search result: relevant or irrelevant, or the correct match among the results shown.
+// Result and Others are results as the search returned them.
+type FeedbackRequest struct {
+	Query   string        `json:"query" binding:"required"`
+	Country string        `json:"country,omitempty"`
+	Label   string        `json:"label" binding:"required"` // "relevant", "irrelevant" or "correct"
+	Result  ProductResult `json:"result"`
+
+	// Others are the other results shown when picking the correct match; they are recorded as irrelevant
+	Others []ProductResult `json:"others,omitempty"`
+}
+
+// MatchFeatures are the signals the fuzzy and embedding scores weigh, recorded with relevance feedback so the
+// weights can be fitted to it. Flags are 1 when the signal fired and 0 otherwise.
+type MatchFeatures struct {
+	BaseSimilarity    float64            `json:"baseSimilarity"`
+	BrandMatch        float64            `json:"brandMatch"`
+	BrandConflict     float64            `json:"brandConflict"`      // The title names only other brands
+	ModelMatch        float64            `json:"modelMatch"`         // 1 for the model token, else the share of query numbers in the title
+	Specs             map[string]float64 `json:"specs,omitempty"`    // Per dimension the query states: 1 equal, -1 different (weighed as a variant conflict)
+	ColorMatch        float64            `json:"colorMatch"`
+	ConditionMatch    float64            `json:"conditionMatch"`
+	ConditionConflict float64            `json:"conditionConflict"`
+	AddOnResult       float64            `json:"addOnResult"`        // An accessory, consumable or part for a product query
+	PrimaryResult     float64            `json:"primaryResult"`      // The product itself for an accessory query
+	Variants          map[string]float64 `json:"variants,omitempty"` // 1 per conflicting attribute: line, suffix, generation, a spec such as storage, pack
+}
+
+// SetPromptVersion records the prompt revision used for a stage without mutating maps shared with copies
+func (p *ProductResult) SetPromptVersion(stage, version string) {
+	versions := make(map[string]string, len(p.PromptVersions)+1)
+	for k, v := range p.PromptVersions {
+		versions[k] = v
+	}
+	versions[stage] = version
+	p.PromptVersions = versions
 }
 
 type PriceResponse struct {
@@ -23,6 +152,182 @@ type PriceResponse struct {
 	Query   string          `json:"query"`
 	Country string          `json:"country"`
 	Count   int             `json:"count"`
+	Intent  *QueryIntent    `json:"intent,omitempty"`
+
+	// OtherVariants holds results for other variants of the product when the request groups them
+	OtherVariants []ProductResult `json:"otherVariants,omitempty"`
+
+	// Groups holds the results clustered into canonical products when the request is grouped
+	Groups []ProductGroup `json:"groups,omitempty"`
+
+	// Stages reports how far this search's results travelled through the cascade strategy, when it scored them
+	Stages []StageStats `json:"stages,omitempty"`
+
+	// Truncated lists the sites whose results page was only partly extracted
+	Truncated []Truncation `json:"truncated,omitempty"`
+}
+
+// StageStats counts the results that reached one cascade stage, what became of them and how long the stage took
+type StageStats struct {
+	Stage        string  `json:"stage"`
+	Model        string  `json:"model,omitempty"`
+	Reached      int64   `json:"reached"`
+	Resolved     int64   `json:"resolved"`  // Results whose final score the stage gave
+	Escalated    int64   `json:"escalated"` // Results passed on to the next stage
+	Failed       int64   `json:"failed"`    // Results the stage could not score; the previous stage's score stands
+	AvgLatencyMs float64 `json:"avgLatencyMs"`
+}
+
+// SplitVariants separates results naming the variant asked for from those with a variant mismatch, keeping order
+func SplitVariants(results []ProductResult) (matching, others []ProductResult) {
+	for _, result := range results {
+		if len(result.VariantMismatch) > 0 {
+			others = append(others, result)
+		} else {
+			matching = append(matching, result)
+		}
+	}
+	return matching, others
+}
+
+// Identifiers are the codes a retailer publishes for a product; offers sharing one are the same product
+type Identifiers struct {
+	GTIN          string `json:"gtin,omitempty"`          // EAN-13, UPC-A, EAN-8 or GTIN-14 barcode
+	ASIN          string `json:"asin,omitempty"`          // Amazon Standard Identification Number
+	MPN           string `json:"mpn,omitempty"`           // Manufacturer part number
+	SiteProductID string `json:"siteProductId,omitempty"` // The retailer's own id, such as a Flipkart pid
+}
+
+// ProductGroup is one canonical product with the offers for it from every site
+type ProductGroup struct {
+	Title      string          `json:"title"` // Title of the most relevant offer
+	Brand      string          `json:"brand,omitempty"`
+	Model      string          `json:"model,omitempty"`
+	Category   string          `json:"category,omitempty"`
+	Offers     []ProductResult `json:"offers"` // Cheapest first
+	MinPrice   float64         `json:"minPrice"`
+	MaxPrice   float64         `json:"maxPrice"`
+	Currency   string          `json:"currency,omitempty"`
+	Confidence float64         `json:"confidence"` // How sure the clustering is that the offers are one product
+	Relevance  float64         `json:"relevance"`  // Match confidence of the most relevant offer
+}
+
+// SortOffers orders the offers by price, or landed total when estimated, in the display currency when converted,
+// cheapest first, and records the price range. Offers without a readable price go last and are left out of the range.
+func (g *ProductGroup) SortOffers() {
+	sort.SliceStable(g.Offers, func(i, j int) bool {
+		priceI, errI := g.Offers[i].RankAmount()
+		priceJ, errJ := g.Offers[j].RankAmount()
+		if errI != nil || errJ != nil {
+			return errI == nil
+		}
+		return priceI.Float() < priceJ.Float()
+	})
+
+	g.MinPrice, g.MaxPrice, g.Currency = 0, 0, ""
+	priced := false
+	for _, offer := range g.Offers {
+		amount, err := offer.DisplayAmount()
+		if err != nil {
+			continue
+		}
+		price := amount.Float()
+		if !priced {
+			g.MinPrice, g.MaxPrice, g.Currency = price, price, amount.Currency
+			priced = true
+		}
+		if price < g.MinPrice {
+			g.MinPrice = price
+		}
+		if price > g.MaxPrice {
+			g.MaxPrice = price
+		}
+	}
+}
+
+// PriceRange is a price shown as a range, e.g. across sizes or sellers
+type PriceRange struct {
+	Min money.Money `json:"min"`
+	Max money.Money `json:"max"`
+}
+
+// ConvertedPrice is a price converted into another currency, with the exchange rate used
+type ConvertedPrice struct {
+	Price       string      `json:"price"` // Plain decimal, like ProductResult.Price
+	Value       money.Money `json:"value"`
+	Rate        float64     `json:"rate"`        // Units of the display currency per unit of the result's currency
+	RatesDate   time.Time   `json:"ratesDate"`   // When the rates were published
+	RatesSource string      `json:"ratesSource"` // File, URL or "built-in"
+}
+
+// LandedCost is an offer's estimated total to the shopper: the price, shipping, and any tax the price leaves out
+type LandedCost struct {
+	Price       money.Money     `json:"price"`
+	Shipping    money.Money     `json:"shipping"`
+	Tax         money.Money     `json:"tax"` // Added on top of Price; zero when Price includes it
+	Total       money.Money     `json:"total"`
+	TaxName     string          `json:"taxName,omitempty"`     // e.g. "VAT" or "sales tax"
+	TaxRate     float64         `json:"taxRate"`               // e.g. 0.0885
+	TaxRegion   string          `json:"taxRegion,omitempty"`   // State or province the rate is for, e.g. "CA" or "ON"
+	Assumptions []string        `json:"assumptions,omitempty"` // What the estimate could not know, e.g. "shipping not shown, counted as free"
+	Converted   *ConvertedPrice `json:"converted,omitempty"`   // Total in the request's display currency
+}
+
+// Amount is the offer's price. Results without PriceValue, such as those sent back by clients, are read from
+// Price, which is always a plain decimal.
+func (p ProductResult) Amount() (money.Money, error) {
+	if p.PriceValue != nil {
+		return *p.PriceValue, nil
+	}
+	return money.ParseIn(p.Price, "", p.Currency)
+}
+
+// DisplayAmount is the price in the request's display currency when it was converted, otherwise Amount
+func (p ProductResult) DisplayAmount() (money.Money, error) {
+	if p.Converted != nil {
+		return p.Converted.Value, nil
+	}
+	return p.Amount()
+}
+
+// RankAmount is what offers are ranked by: the landed total when it was estimated, otherwise DisplayAmount,
+// in the display currency when converted
+func (p ProductResult) RankAmount() (money.Money, error) {
+	if p.Landed != nil {
+		if p.Landed.Converted != nil {
+			return p.Landed.Converted.Value, nil
+		}
+		if p.Converted == nil {
+			return p.Landed.Total, nil
+		}
+	}
+	return p.DisplayAmount()
+}
+
+// Spec is a quantity normalized to the base unit of its dimension, e.g. "1TB" as {storage, 1024, GB}
+type Spec struct {
+	Dimension string  `json:"dimension"`
+	Value     float64 `json:"value"`
+	Unit      string  `json:"unit"`
+	Raw       string  `json:"raw,omitempty"`
+}
+
+// QueryIntent is the structured reading of a search query used for attribute-level matching
+type QueryIntent struct {
+	Raw         string   `json:"raw"`
+	Brand       string   `json:"brand,omitempty"`
+	ProductLine string   `json:"productLine,omitempty"`
+	Category    string   `json:"category,omitempty"` // From the brand knowledge base or taxonomy, e.g. "phones"
+	Kind        string   `json:"kind,omitempty"`     // "primary", "accessory", "consumable" or "part"
+	Model       string   `json:"model,omitempty"`
+	Variants    []string `json:"variants,omitempty"` // Model suffixes such as "pro", "max", "ultra"
+	Storage     string   `json:"storage,omitempty"`  // Normalized, e.g. "128GB" or "1TB"
+	Specs       []Spec   `json:"specs,omitempty"`    // Every quantity in the query, in base units
+	Size        string   `json:"size,omitempty"`
+	Color       string   `json:"color,omitempty"`
+	Condition   string   `json:"condition,omitempty"`
+	Quantity    int      `json:"quantity,omitempty"`
+	Source      string   `json:"source"` // "rules" or "rules+llm"
 }
 
 type SiteConfig struct {
@@ -34,6 +339,7 @@ type SiteConfig struct {
 	Headers        map[string]string `json:"headers,omitempty"`
 	RateLimit      int               `json:"rateLimit,omitempty"`
 	RequiresJS     bool              `json:"requiresJs,omitempty"`
+	Category       string            `json:"category,omitempty"` // Default product category for category-specific prompts
 }
 
 type SiteSelectors struct {
@@ -42,19 +348,36 @@ type SiteSelectors struct {
 	Title       string `json:"title"`
 	Link        string `json:"link"`
 	Currency    string `json:"currency,omitempty"`
+	Condition   string `json:"condition,omitempty"` // Condition label on each card, e.g. eBay's "Pre-Owned"
+	ListPrice   string `json:"listPrice,omitempty"` // Struck-through original price, e.g. Amazon's "M.R.P."; excluded from Price
+	Shipping    string `json:"shipping,omitempty"`  // Delivery charge on each card, e.g. eBay's "+$5.99 shipping"
 }
 
 type ScrapingResult struct {
-	Products []ProductResult
-	Site     string
-	Error    error
+	Products  []ProductResult
+	Site      string
+	Error     error
+	Truncated *Truncation // Set when only part of the results page was extracted
+}
+
+// Truncation reports a results page cut to MAX_CHUNKS_PER_PAGE extraction prompts; products in the chunks left
+// out are missing from the site's results
+type Truncation struct {
+	Site      string `json:"site"`
+	Chunks    int    `json:"chunks"`    // Chunks the page was split into
+	Extracted int    `json:"extracted"` // Chunks sent for extraction
 }
 
 type StreamingResult struct {
-	Site       string          `json:"site"`
-	Products   []ProductResult `json:"products,omitempty"`
-	Status     string          `json:"status"` // "processing", "completed", "error"
-	Error      string          `json:"error,omitempty"`
-	Progress   int             `json:"progress"` // 0-100
-	Message    string          `json:"message,omitempty"`
+	Site          string          `json:"site"`
+	Products      []ProductResult `json:"products,omitempty"`
+	Status        string          `json:"status"` // "processing", "completed", "error"
+	Error         string          `json:"error,omitempty"`
+	Progress      int             `json:"progress"` // 0-100
+	Message       string          `json:"message,omitempty"`
+	Intent        *QueryIntent    `json:"intent,omitempty"`
+	OtherVariants []ProductResult `json:"otherVariants,omitempty"` // Set instead of mixing them into Products when grouping variants
+	Groups        []ProductGroup  `json:"groups,omitempty"`        // Every site's results clustered, on the final message of a grouped stream
+	Stages        []StageStats    `json:"stages,omitempty"`        // The site's results through the cascade strategy, when it scored them
+	Truncated     *Truncation     `json:"truncated,omitempty"`     // Set when only part of the site's results page was extracted
 }
\ No newline at end of file
@@ -0,0 +1,302 @@
+// Package money reads scraped prices into exact amounts. Separators are interpreted by the conventions of the
+// site's country, so "1.299,00 €" on a German site, "₹1,29,900" on an Indian one and "￥32,978" on a Japanese
+// one all give the amount the shopper sees.
+package money
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/width"
+)
+
+// Money is an amount in the minor unit of its currency, so prices compare and add without rounding
+type Money struct {
+	Minor    int64  `json:"minor"`    // e.g. cents; yen have no minor unit, so a yen amount is whole yen
+	Currency string `json:"currency"` // ISO 4217 code
+}
+
+// exponents are the minor unit digits of currencies that do not use two
+var exponents = map[string]int{"JPY": 0, "KRW": 0, "CLP": 0, "VND": 0, "BHD": 3, "KWD": 3, "OMR": 3}
+
+// Exponent is how many decimal digits the currency's minor unit has
+func Exponent(currency string) int {
+	if exponent, known := exponents[currency]; known {
+		return exponent
+	}
+	return 2
+}
+
+// locale is how a country writes prices
+type locale struct {
+	currency string
+	decimal  rune // Separator of the fraction; the other of '.' and ',' groups thousands
+}
+
+var locales = map[string]locale{
+	"US": {"USD", '.'},
+	"CA": {"CAD", '.'},
+	"UK": {"GBP", '.'},
+	"AU": {"AUD", '.'},
+	"IN": {"INR", '.'},
+	"DE": {"EUR", ','},
+	"FR": {"EUR", ','},
+	"JP": {"JPY", '.'},
+}
+
+// CurrencyFor is the currency prices are shown in for a country; USD for unknown countries
+func CurrencyFor(country string) string {
+	if l, known := locales[country]; known {
+		return l.currency
+	}
+	return "USD"
+}
+
+// isoCodePattern finds a currency written as its code, e.g. "EUR 1.299,00"
+var isoCodePattern = regexp.MustCompile(`\b(USD|CAD|GBP|AUD|INR|EUR|JPY)\b`)
+
+// currencySymbols are checked in order, so prefixed dollars come before the bare "$"
+var currencySymbols = []struct{ symbol, currency string }{
+	{"US$", "USD"}, {"CDN$", "CAD"}, {"CA$", "CAD"}, {"C$", "CAD"}, {"AU$", "AUD"}, {"A$", "AUD"},
+	{"€", "EUR"}, {"£", "GBP"}, {"₹", "INR"}, {"Rs", "INR"}, {"¥", "JPY"}, {"円", "JPY"},
+}
+
+// numberPattern finds digit runs joined by separators: '.', ',', apostrophes and spaces
+var numberPattern = regexp.MustCompile(`\d+(?:[.,'’ \x{00A0}\x{202F}]\d+)*`)
+
+// Parse reads the first price in text, shown on a site for country. The currency is read from a code or symbol
+// in the text, otherwise it is the country's.
+func Parse(text, country string) (Money, error) {
+	return ParseIn(text, country, "")
+}
+
+// ParseIn is Parse with the currency given, e.g. by extraction; "" or anything but a currency code reads it
+// from the text
+func ParseIn(text, country, currency string) (Money, error) {
+	text = width.Narrow.String(text)
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if !isCode(currency) {
+		currency = DetectCurrency(text, country)
+	}
+
+	number := numberPattern.FindString(text)
+	if number == "" {
+		return Money{}, fmt.Errorf("no amount in %q", text)
+	}
+	exponent := Exponent(currency)
+	integer, fraction := splitNumber(number, country, exponent)
+	if len(integer) > 15 {
+		return Money{}, fmt.Errorf("amount too large in %q", text)
+	}
+
+	units, err := strconv.ParseInt("0"+integer, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("no amount in %q", text)
+	}
+	minor := units * pow10(exponent)
+	// The fraction is padded or rounded half up to the minor unit
+	if padded := (fraction + strings.Repeat("0", exponent))[:exponent]; padded != "" {
+		kept, _ := strconv.ParseInt(padded, 10, 64)
+		minor += kept
+	}
+	if len(fraction) > exponent && fraction[exponent] >= '5' {
+		minor++
+	}
+	return Money{Minor: minor, Currency: currency}, nil
+}
+
+// rangeSeparator is what stands between the two ends of a price range once currency symbols are removed:
+// "₹999 – ₹1,499", "$10.99 to $15.99", "1.299 € bis 1.499 €", "￥3,980～￥5,980"
+var rangeSeparator = regexp.MustCompile(`^\s*(?:[-–—~〜～]|to|bis|à)\s*$`)
+
+// ParseRange reads a price that may be a range, giving its lowest and highest amounts. A single price gives the
+// same amount twice. Both ends are in one currency, read from the whole text unless given.
+func ParseRange(text, country, currency string) (low, high Money, err error) {
+	text = width.Narrow.String(text)
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if !isCode(currency) {
+		currency = DetectCurrency(text, country)
+	}
+
+	numbers := numberPattern.FindAllStringIndex(text, 3)
+	if len(numbers) < 2 || !rangeSeparator.MatchString(stripCurrency(text[numbers[0][1]:numbers[1][0]])) {
+		low, err = ParseIn(text, country, currency)
+		return low, low, err
+	}
+	if low, err = ParseIn(text[numbers[0][0]:numbers[0][1]], country, currency); err != nil {
+		return low, low, err
+	}
+	if high, err = ParseIn(text[numbers[1][0]:numbers[1][1]], country, currency); err != nil {
+		return low, low, err
+	}
+	if high.Minor < low.Minor {
+		low, high = high, low
+	}
+	return low, high, nil
+}
+
+// stripCurrency removes currency codes and symbols from text
+func stripCurrency(text string) string {
+	text = isoCodePattern.ReplaceAllString(text, "")
+	for _, s := range currencySymbols {
+		text = strings.ReplaceAll(text, s.symbol, "")
+	}
+	return strings.ReplaceAll(text, "$", "")
+}
+
+// DiscountPercent is how far sale is below list, as a percentage to one decimal; 0 unless sale is lower and
+// both are in the same currency
+func DiscountPercent(list, sale Money) float64 {
+	if list.Currency != sale.Currency || list.Minor <= 0 || sale.Minor >= list.Minor {
+		return 0
+	}
+	return math.Round(float64(list.Minor-sale.Minor)/float64(list.Minor)*1000) / 10
+}
+
+// DetectCurrency reads the currency from a code or symbol in a price, otherwise gives the country's. A bare "$"
+// is the country's dollar, or USD where the country has none.
+func DetectCurrency(text, country string) string {
+	if currency, shown := SymbolCurrency(text); shown {
+		return currency
+	}
+	local := CurrencyFor(country)
+	if strings.Contains(text, "$") && local != "USD" && local != "CAD" && local != "AUD" {
+		return "USD"
+	}
+	return local
+}
+
+// SymbolCurrency reads the currency from a code or symbol in a price. A bare "$" names no particular dollar.
+func SymbolCurrency(text string) (string, bool) {
+	text = width.Narrow.String(text)
+	if code := isoCodePattern.FindString(text); code != "" {
+		return code, true
+	}
+	for _, s := range currencySymbols {
+		if strings.Contains(text, s.symbol) {
+			return s.currency, true
+		}
+	}
+	return "", false
+}
+
+func isCode(currency string) bool {
+	if len(currency) != 3 {
+		return false
+	}
+	for _, r := range currency {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// splitNumber separates the integer digits of a number from its fraction. Spaces and apostrophes always group
+// thousands. Of '.' and ',', the last is the decimal separator when both appear; a separator appearing more than
+// once groups. A single separator groups when three digits follow it or the currency has no minor unit, and
+// otherwise is the decimal separator when one or two digits follow or it is the country's decimal separator.
+func splitNumber(number, country string, exponent int) (integer, fraction string) {
+	type part struct {
+		separator rune
+		digits    string
+	}
+	var parts []part
+	current := part{}
+	for _, r := range number {
+		if r >= '0' && r <= '9' {
+			current.digits += string(r)
+			continue
+		}
+		parts = append(parts, current)
+		current = part{separator: r}
+	}
+	parts = append(parts, current)
+
+	// A space followed by anything but a group of three ends the number: "2 128" is not 2128
+	for i := 1; i < len(parts); i++ {
+		if isSpace(parts[i].separator) && len(parts[i].digits) != 3 {
+			parts = parts[:i]
+			break
+		}
+	}
+
+	counts := map[rune]int{}
+	for _, p := range parts[1:] {
+		counts[p.separator]++
+	}
+	decimalAt := -1
+	if last := len(parts) - 1; last > 0 && (parts[last].separator == '.' || parts[last].separator == ',') {
+		separator, after := parts[last].separator, len(parts[last].digits)
+		decimal := locales[country].decimal
+		if decimal == 0 {
+			decimal = '.'
+		}
+		switch {
+		case counts['.'] > 0 && counts[','] > 0:
+			decimalAt = last
+		case counts[separator] > 1, after == 3, exponent == 0:
+		case after <= 2, separator == decimal:
+			decimalAt = last
+		}
+	}
+
+	for i, p := range parts {
+		if i == decimalAt {
+			fraction = p.digits
+			continue
+		}
+		integer += p.digits
+	}
+	return strings.TrimLeft(integer, "0"), fraction
+}
+
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\u00A0' || r == '\u202F'
+}
+
+func pow10(exponent int) int64 {
+	return int64(math.Pow10(exponent))
+}
+
+// FromFloat rounds an amount in major units, e.g. 1299.5, to money in currency
+func FromFloat(amount float64, currency string) Money {
+	return Money{Minor: int64(math.Round(amount * math.Pow10(Exponent(currency)))), Currency: currency}
+}
+
+// Float is the amount in major units
+func (m Money) Float() float64 {
+	return float64(m.Minor) / math.Pow10(Exponent(m.Currency))
+}
+
+// Decimal writes the amount in major units with the currency's minor digits, e.g. "1299.00" or "32978"
+func (m Money) Decimal() string {
+	exponent := Exponent(m.Currency)
+	sign, minor := "", m.Minor
+	if minor < 0 {
+		sign, minor = "-", -minor
+	}
+	if exponent == 0 {
+		return sign + strconv.FormatInt(minor, 10)
+	}
+	scale := pow10(exponent)
+	return fmt.Sprintf("%s%d.%0*d", sign, minor/scale, exponent, minor%scale)
+}
+
+// String writes the amount and currency, e.g. "1299.00 EUR"
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+// MarshalJSON adds the amount in major units as "decimal", so clients need not know each currency's exponent
+func (m Money) MarshalJSON() ([]byte, error) {
+	type plain Money
+	return json.Marshal(struct {
+		plain
+		Decimal string `json:"decimal"`
+	}{plain(m), m.Decimal()})
+}
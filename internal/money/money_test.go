@@ -0,0 +1,103 @@
+package money
+
+import "testing"
+
+func TestSplitNumber(t *testing.T) {
+	tests := []struct {
+		number, country   string
+		exponent          int
+		integer, fraction string
+	}{
+		{"1,299.00", "US", 2, "1299", "00"},
+		{"1.299,00", "DE", 2, "1299", "00"},
+		{"1.299", "DE", 2, "1299", ""}, // A lone separator before three digits groups
+		{"1,299", "US", 2, "1299", ""},
+		{"12,5", "DE", 2, "12", "5"},        // One digit after: decimal
+		{"12,50", "US", 2, "12", "50"},      // Two digits after: decimal whatever the country
+		{"1,29,900", "IN", 2, "129900", ""}, // Lakh grouping repeats the separator
+		{"32,978", "JP", 0, "32978", ""},
+		{"1.5", "JP", 0, "15", ""}, // No minor unit, so a separator only groups
+		{"1 299,00", "FR", 2, "1299", "00"},
+		{"1'299.50", "US", 2, "1299", "50"},
+		{"2 128", "US", 2, "2128", ""},
+		{"2 12", "US", 2, "2", ""}, // A space not followed by three digits ends the number
+		{"0.99", "US", 2, "", "99"},
+	}
+	for _, test := range tests {
+		integer, fraction := splitNumber(test.number, test.country, test.exponent)
+		if integer != test.integer || fraction != test.fraction {
+			t.Errorf("splitNumber(%q, %s, %d) = %q, %q; want %q, %q",
+				test.number, test.country, test.exponent, integer, fraction, test.integer, test.fraction)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text, country string
+		want          string
+	}{
+		{"$1,299.99", "US", "1299.99 USD"},
+		{"1.299,00 €", "DE", "1299.00 EUR"},
+		{"₹1,29,900", "IN", "129900.00 INR"},
+		{"￥32,978", "JP", "32978 JPY"},
+		{"CDN$ 49.99", "US", "49.99 CAD"},
+		{"$19.99", "UK", "19.99 USD"},       // A bare dollar where the country has none
+		{"£1,299.995", "UK", "1300.00 GBP"}, // Rounded half up to the minor unit
+		{"1 299,00 €", "FR", "1299.00 EUR"},
+	}
+	for _, test := range tests {
+		got, err := Parse(test.text, test.country)
+		if err != nil {
+			t.Errorf("Parse(%q, %s) error: %v", test.text, test.country, err)
+			continue
+		}
+		if got.String() != test.want {
+			t.Errorf("Parse(%q, %s) = %s, want %s", test.text, test.country, got, test.want)
+		}
+	}
+
+	for _, text := range []string{"", "Price not available", "$1234567890123456"} {
+		if got, err := Parse(text, "US"); err == nil {
+			t.Errorf("Parse(%q) = %s, want an error", text, got)
+		}
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		text, country string
+		low, high     string
+	}{
+		{"₹999 – ₹1,499", "IN", "999.00 INR", "1499.00 INR"},
+		{"$10.99 to $15.99", "US", "10.99 USD", "15.99 USD"},
+		{"1.299 € bis 1.499 €", "DE", "1299.00 EUR", "1499.00 EUR"},
+		{"￥3,980～￥5,980", "JP", "3980 JPY", "5980 JPY"},
+		{"$15.99 - $10.99", "US", "10.99 USD", "15.99 USD"}, // Ends in either order
+		{"$24.99", "US", "24.99 USD", "24.99 USD"},
+		{"$24.99 2 pack", "US", "24.99 USD", "24.99 USD"}, // Two numbers without a range separator
+	}
+	for _, test := range tests {
+		low, high, err := ParseRange(test.text, test.country, "")
+		if err != nil {
+			t.Errorf("ParseRange(%q) error: %v", test.text, err)
+			continue
+		}
+		if low.String() != test.low || high.String() != test.high {
+			t.Errorf("ParseRange(%q) = %s, %s; want %s, %s", test.text, low, high, test.low, test.high)
+		}
+	}
+}
+
+func TestDiscountPercent(t *testing.T) {
+	list, sale := Money{Minor: 2499, Currency: "USD"}, Money{Minor: 1999, Currency: "USD"}
+	if got := DiscountPercent(list, sale); got != 20 {
+		t.Errorf("DiscountPercent(24.99, 19.99) = %v, want 20", got)
+	}
+	if got := DiscountPercent(sale, list); got != 0 {
+		t.Errorf("DiscountPercent() = %v for a sale above the list price, want 0", got)
+	}
+	if got := DiscountPercent(list, Money{Minor: 1999, Currency: "EUR"}); got != 0 {
+		t.Errorf("DiscountPercent() = %v across currencies, want 0", got)
+	}
+}
@@ -0,0 +1,280 @@
+package prompts
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"price-comparison-tool/internal/models"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// Prompt names used by the extraction and matching pipelines
+const (
+	Extraction = "extraction"
+	Scoring    = "scoring"
+	Selectors  = "selectors"
+	Query      = "query"
+)
+
+//go:embed templates/*.tmpl
+var embeddedTemplates embed.FS
+
+// Scope identifies the site and product category a prompt is rendered for.
+// Overrides are resolved most specific first: site, then category, then the default template.
+type Scope struct {
+	Site     string
+	Category string
+}
+
+// Prompt is a single parsed template revision
+type Prompt struct {
+	Name    string
+	Version string
+	Variant string // "" for the default, otherwise e.g. "site-amazon-japan" or "category-fashion"
+	tmpl    *template.Template
+}
+
+// ID returns the identifier recorded on results, e.g. "v1" or "v1/site-amazon-japan"
+func (p *Prompt) ID() string {
+	if p.Variant == "" {
+		return p.Version
+	}
+	return p.Version + "/" + p.Variant
+}
+
+// Render executes the template with the given data
+func (p *Prompt) Render(data interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := p.tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("render prompt %s/%s: %v", p.Name, p.ID(), err)
+	}
+	return strings.TrimRight(buf.String(), "\n"), nil
+}
+
+// ExtractionData is the input to the extraction prompt
+type ExtractionData struct {
+	Query   string
+	Country string
+	Site    string
+	Content string
+}
+
+// ScoringData is the input to the match scoring prompt
+type ScoringData struct {
+	Query       string
+	ProductName string
+}
+
+// QueryData is the input to the query understanding prompt
+type QueryData struct {
+	Query string
+}
+
+// SelectorsData is the input to the selector induction prompt
+type SelectorsData struct {
+	Site    string
+	Current models.SiteSelectors
+	Outline string
+}
+
+// Registry holds every known prompt revision, keyed by name, version and variant
+type Registry struct {
+	mutex   sync.RWMutex
+	prompts map[string]map[string]map[string]*Prompt
+	pins    map[string]string
+}
+
+// Load parses the embedded templates and then any *.tmpl files in dir, which replace embedded
+// revisions with the same file name. pins maps a prompt name to the version to use instead of the latest.
+func Load(dir string, pins map[string]string) (*Registry, error) {
+	r := &Registry{
+		prompts: make(map[string]map[string]map[string]*Prompt),
+		pins:    pins,
+	}
+
+	sub, err := fs.Sub(embeddedTemplates, "templates")
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadFS(sub); err != nil {
+		return nil, fmt.Errorf("embedded prompts: %v", err)
+	}
+
+	if dir != "" {
+		if err := r.loadFS(os.DirFS(dir)); err != nil {
+			return r, fmt.Errorf("prompt overrides in %s: %v", dir, err)
+		}
+		log.Printf("📝 Loaded prompt overrides from %s", dir)
+	}
+
+	for name, pinned := range pins {
+		if _, exists := r.prompts[name][pinned]; !exists {
+			return r, fmt.Errorf("pinned prompt %s version %s does not exist", name, pinned)
+		}
+	}
+
+	return r, nil
+}
+
+func (r *Registry) loadFS(fsys fs.FS) error {
+	files, err := fs.Glob(fsys, "*.tmpl")
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		name, version, variant, err := parseFileName(file)
+		if err != nil {
+			return err
+		}
+
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+
+		tmpl, err := template.New(file).Option("missingkey=error").Parse(string(content))
+		if err != nil {
+			return err
+		}
+
+		r.add(&Prompt{Name: name, Version: version, Variant: variant, tmpl: tmpl})
+	}
+
+	return nil
+}
+
+// parseFileName splits "<name>.<version>[.<variant>].tmpl", e.g. "extraction.v1.site-amazon-japan.tmpl"
+func parseFileName(file string) (name, version, variant string, err error) {
+	parts := strings.Split(strings.TrimSuffix(file, ".tmpl"), ".")
+	if len(parts) < 2 || len(parts) > 3 || versionNumber(parts[1]) < 0 {
+		return "", "", "", fmt.Errorf("invalid prompt file name %q, expected <name>.v<N>[.<variant>].tmpl", file)
+	}
+	if len(parts) == 3 {
+		variant = parts[2]
+	}
+	return parts[0], parts[1], variant, nil
+}
+
+func (r *Registry) add(p *Prompt) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.prompts[p.Name] == nil {
+		r.prompts[p.Name] = make(map[string]map[string]*Prompt)
+	}
+	if r.prompts[p.Name][p.Version] == nil {
+		r.prompts[p.Name][p.Version] = make(map[string]*Prompt)
+	}
+	r.prompts[p.Name][p.Version][p.Variant] = p
+}
+
+// Get resolves the prompt to use for name within scope
+func (r *Registry) Get(name string, scope Scope) (*Prompt, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	versions := r.prompts[name]
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("unknown prompt %q", name)
+	}
+
+	version := r.pins[name]
+	if version == "" {
+		version = latestVersion(versions)
+	}
+
+	variants := versions[version]
+	for _, candidate := range scope.variants() {
+		if p, exists := variants[candidate]; exists {
+			return p, nil
+		}
+	}
+
+	return nil, fmt.Errorf("prompt %s version %s has no default template", name, version)
+}
+
+// Versions lists the available revisions of each prompt, for diagnostics
+func (r *Registry) Versions() map[string][]string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	result := make(map[string][]string)
+	for name, versions := range r.prompts {
+		for _, variants := range versions {
+			for _, p := range variants {
+				result[name] = append(result[name], p.ID())
+			}
+		}
+		sort.Strings(result[name])
+	}
+	return result
+}
+
+func (s Scope) variants() []string {
+	var candidates []string
+	if s.Site != "" {
+		candidates = append(candidates, "site-"+Slug(s.Site))
+	}
+	if s.Category != "" {
+		candidates = append(candidates, "category-"+Slug(s.Category))
+	}
+	return append(candidates, "")
+}
+
+// Slug converts a site or category name to the form used in template file names
+func Slug(value string) string {
+	var b strings.Builder
+	lastDash := true
+	for _, r := range strings.ToLower(value) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			lastDash = false
+		} else if !lastDash {
+			b.WriteByte('-')
+			lastDash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+func latestVersion(versions map[string]map[string]*Prompt) string {
+	latest := ""
+	for version := range versions {
+		if latest == "" || versionNumber(version) > versionNumber(latest) {
+			latest = version
+		}
+	}
+	return latest
+}
+
+// versionNumber parses "v12" into 12, returning -1 for anything else
+func versionNumber(version string) int {
+	if !strings.HasPrefix(version, "v") {
+		return -1
+	}
+	n, err := strconv.Atoi(version[1:])
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// ParsePins parses "scoring=v1,extraction=v2" into a name to version map
+func ParsePins(value string) map[string]string {
+	pins := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		name, version, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if found && name != "" && version != "" {
+			pins[strings.TrimSpace(name)] = strings.TrimSpace(version)
+		}
+	}
+	return pins
+}
@@ -0,0 +1,149 @@
+package prompts
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// loadOverrides writes each file into a temporary override directory and loads the registry with pins
+func loadOverrides(t *testing.T, files map[string]string, pins map[string]string) (*Registry, error) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return Load(dir, pins)
+}
+
+// mustGet resolves the test prompt "greeting" within scope
+func mustGet(t *testing.T, r *Registry, scope Scope) *Prompt {
+	t.Helper()
+	prompt, err := r.Get("greeting", scope)
+	if err != nil {
+		t.Fatalf("Get(%+v) error: %v", scope, err)
+	}
+	return prompt
+}
+
+func TestGetResolvesSiteThenCategoryThenDefault(t *testing.T) {
+	files := map[string]string{
+		"greeting.v1.tmpl":                   "default {{.Query}}",
+		"greeting.v1.site-amazon-japan.tmpl": "site {{.Query}}",
+		"greeting.v1.category-fashion.tmpl":  "category {{.Query}}",
+		"greeting.v2.tmpl":                   "latest {{.Query}}",
+		"greeting.v10.category-fashion.tmpl": "no default",
+	}
+
+	pinned, err := loadOverrides(t, files, map[string]string{"greeting": "v1"})
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	tests := []struct {
+		scope  Scope
+		wantID string
+	}{
+		{Scope{Site: "Amazon Japan", Category: "Fashion"}, "v1/site-amazon-japan"},
+		{Scope{Site: "Rakuten", Category: "Fashion"}, "v1/category-fashion"},
+		{Scope{Site: "Rakuten", Category: "Electronics"}, "v1"},
+		{Scope{}, "v1"},
+	}
+	for _, test := range tests {
+		prompt, err := pinned.Get("greeting", test.scope)
+		if err != nil {
+			t.Errorf("Get(%+v) error: %v", test.scope, err)
+			continue
+		}
+		if prompt.ID() != test.wantID {
+			t.Errorf("Get(%+v) = %s, want %s", test.scope, prompt.ID(), test.wantID)
+		}
+	}
+	rendered, err := mustGet(t, pinned, Scope{Site: "Amazon Japan"}).Render(QueryData{Query: "iphone"})
+	if err != nil || rendered != "site iphone" {
+		t.Errorf("Render() = %q, %v; want %q", rendered, err, "site iphone")
+	}
+
+	// Unpinned, the highest version number wins, v10 over v2, and it has no default to fall back to
+	latest, err := loadOverrides(t, files, nil)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if prompt := mustGet(t, latest, Scope{Category: "Fashion"}); prompt.ID() != "v10/category-fashion" {
+		t.Errorf("Get() unpinned = %s, want v10/category-fashion", prompt.ID())
+	}
+	if _, err := latest.Get("greeting", Scope{Site: "Rakuten"}); err == nil {
+		t.Error("Get() found a template for a version with no default and no matching variant")
+	}
+	if _, err := latest.Get("farewell", Scope{}); err == nil {
+		t.Error("Get() found an unknown prompt")
+	}
+}
+
+func TestLoadRejectsBadOverrides(t *testing.T) {
+	if _, err := loadOverrides(t, map[string]string{"greeting.v1.tmpl": "{{.Query}}"}, map[string]string{"greeting": "v3"}); err == nil {
+		t.Error("Load() accepted a pin to a version that does not exist")
+	}
+	if _, err := loadOverrides(t, map[string]string{"greeting.latest.tmpl": "{{.Query}}"}, nil); err == nil {
+		t.Error("Load() accepted a malformed file name")
+	}
+	if _, err := loadOverrides(t, map[string]string{"greeting.v1.tmpl": "{{.Query"}, nil); err == nil {
+		t.Error("Load() accepted a template that does not parse")
+	}
+}
+
+func TestOverrideReplacesEmbeddedRevision(t *testing.T) {
+	r, err := loadOverrides(t, map[string]string{"selectors.v1.tmpl": "override {{.Site}}"}, nil)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	prompt, err := r.Get(Selectors, Scope{})
+	if err != nil {
+		t.Fatalf("Get() error: %v", err)
+	}
+	if rendered, err := prompt.Render(SelectorsData{Site: "eBay"}); err != nil || rendered != "override eBay" {
+		t.Errorf("Render() = %q, %v; want the override", rendered, err)
+	}
+	if _, err := prompt.Render(QueryData{Query: "iphone"}); err == nil {
+		t.Error("Render() accepted data missing a field the template uses")
+	}
+}
+
+func TestParseFileName(t *testing.T) {
+	tests := []struct {
+		file                   string
+		name, version, variant string
+		valid                  bool
+	}{
+		{"extraction.v1.tmpl", "extraction", "v1", "", true},
+		{"extraction.v12.site-amazon-japan.tmpl", "extraction", "v12", "site-amazon-japan", true},
+		{"extraction.tmpl", "", "", "", false},
+		{"extraction.1.tmpl", "", "", "", false},
+		{"extraction.vx.tmpl", "", "", "", false},
+		{"extraction.v1.site.extra.tmpl", "", "", "", false},
+	}
+	for _, test := range tests {
+		name, version, variant, err := parseFileName(test.file)
+		if (err == nil) != test.valid {
+			t.Errorf("parseFileName(%q) error = %v, want valid %v", test.file, err, test.valid)
+			continue
+		}
+		if name != test.name || version != test.version || variant != test.variant {
+			t.Errorf("parseFileName(%q) = %q, %q, %q; want %q, %q, %q",
+				test.file, name, version, variant, test.name, test.version, test.variant)
+		}
+	}
+}
+
+func TestSlugAndParsePins(t *testing.T) {
+	for value, want := range map[string]string{"Amazon Japan": "amazon-japan", "Fashion & Apparel": "fashion-apparel", "eBay!": "ebay"} {
+		if got := Slug(value); got != want {
+			t.Errorf("Slug(%q) = %q, want %q", value, got, want)
+		}
+	}
+	pins := ParsePins(" scoring=v1, extraction = v2 ,bad,=v3")
+	if len(pins) != 2 || pins["scoring"] != "v1" || pins["extraction"] != "v2" {
+		t.Errorf("ParsePins() = %v, want scoring=v1 and extraction=v2", pins)
+	}
+}
@@ -0,0 +1,263 @@
+// Package rates converts prices between currencies. Exchange rates are loaded from a configurable source, an
+// ECB-style XML file, a JSON file, or either served over HTTP, and cached for a TTL. A built-in table stands in
+// when no source is configured or it cannot be reached, so conversion works offline and in tests.
+package rates
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"price-comparison-tool/internal/money"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SourceBuiltIn names the rates embedded in the binary
+const SourceBuiltIn = "built-in"
+
+// retryInterval is how long a failed source is left alone before it is tried again
+const retryInterval = time.Minute
+
+//go:embed rates.json
+var builtInRates []byte
+
+// Table is one set of exchange rates, quoted as units of each currency per unit of Base
+type Table struct {
+	Base      string             `json:"base"`
+	Date      time.Time          `json:"date"` // When the rates were published
+	Rates     map[string]float64 `json:"rates"`
+	Source    string             `json:"source"`              // File, URL or "built-in"
+	FetchedAt time.Time          `json:"fetchedAt,omitempty"` // When they were loaded
+}
+
+// Supports reports whether the table can convert to and from currency
+func (t Table) Supports(currency string) bool {
+	_, known := t.rate(currency)
+	return known
+}
+
+func (t Table) rate(currency string) (float64, bool) {
+	if currency == t.Base {
+		return 1, true
+	}
+	rate, known := t.Rates[currency]
+	return rate, known && rate > 0
+}
+
+// Rate is how many units of to one unit of from buys
+func (t Table) Rate(from, to string) (float64, error) {
+	fromRate, known := t.rate(from)
+	if !known {
+		return 0, fmt.Errorf("no exchange rate for %s", from)
+	}
+	toRate, known := t.rate(to)
+	if !known {
+		return 0, fmt.Errorf("no exchange rate for %s", to)
+	}
+	return toRate / fromRate, nil
+}
+
+// Convert gives amount in currency to, rounded to its minor unit, and the rate used
+func (t Table) Convert(amount money.Money, to string) (money.Money, float64, error) {
+	if amount.Currency == to {
+		return amount, 1, nil
+	}
+	rate, err := t.Rate(amount.Currency, to)
+	if err != nil {
+		return money.Money{}, 0, err
+	}
+	return money.FromFloat(amount.Float()*rate, to), rate, nil
+}
+
+// Parse reads rates as ECB XML (eurofxref) or as JSON: {"base": "EUR", "date": "2024-05-17", "rates": {...}}
+func Parse(data []byte) (Table, error) {
+	trimmed := strings.TrimSpace(string(data))
+	if strings.HasPrefix(trimmed, "<") {
+		return parseECB(data)
+	}
+
+	var raw struct {
+		Base  string             `json:"base"`
+		Date  string             `json:"date"`
+		Rates map[string]float64 `json:"rates"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return Table{}, err
+	}
+	if raw.Base == "" || len(raw.Rates) == 0 {
+		return Table{}, fmt.Errorf("rates need a base and at least one rate")
+	}
+	date, err := parseDate(raw.Date)
+	if err != nil {
+		return Table{}, err
+	}
+	return Table{Base: strings.ToUpper(raw.Base), Date: date, Rates: upperKeys(raw.Rates)}, nil
+}
+
+// parseECB reads the most recent day of an ECB reference rates file, which quotes every currency against EUR
+func parseECB(data []byte) (Table, error) {
+	var envelope struct {
+		Cube struct {
+			Days []struct {
+				Time  string `xml:"time,attr"`
+				Rates []struct {
+					Currency string  `xml:"currency,attr"`
+					Rate     float64 `xml:"rate,attr"`
+				} `xml:"Cube"`
+			} `xml:"Cube"`
+		} `xml:"Cube"`
+	}
+	if err := xml.Unmarshal(data, &envelope); err != nil {
+		return Table{}, err
+	}
+	if len(envelope.Cube.Days) == 0 {
+		return Table{}, fmt.Errorf("no rates in ECB file")
+	}
+
+	// Daily files hold one day; history files list the newest first
+	day := envelope.Cube.Days[0]
+	date, err := parseDate(day.Time)
+	if err != nil {
+		return Table{}, err
+	}
+	table := Table{Base: "EUR", Date: date, Rates: make(map[string]float64, len(day.Rates))}
+	for _, rate := range day.Rates {
+		table.Rates[strings.ToUpper(rate.Currency)] = rate.Rate
+	}
+	if len(table.Rates) == 0 {
+		return Table{}, fmt.Errorf("no rates in ECB file")
+	}
+	return table, nil
+}
+
+func parseDate(value string) (time.Time, error) {
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return date, nil
+	}
+	date, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unreadable rates date %q", value)
+	}
+	return date, nil
+}
+
+func upperKeys(rates map[string]float64) map[string]float64 {
+	upper := make(map[string]float64, len(rates))
+	for currency, rate := range rates {
+		upper[strings.ToUpper(currency)] = rate
+	}
+	return upper
+}
+
+// BuiltIn is the rates embedded in the binary
+func BuiltIn() Table {
+	table, err := Parse(builtInRates)
+	if err != nil {
+		log.Fatalf("Failed to parse built-in exchange rates: %v", err)
+	}
+	table.Source = SourceBuiltIn
+	return table
+}
+
+// Converter loads rates from a source and caches them for a TTL
+type Converter struct {
+	source string // File path or http(s) URL; "" uses the built-in rates
+	ttl    time.Duration
+	client *http.Client
+
+	mutex       sync.Mutex
+	table       Table
+	loaded      bool // The source has been loaded at least once
+	fetching    bool // A caller is reloading the source
+	lastAttempt time.Time
+}
+
+// NewConverter caches rates from source for ttl; an empty source uses the built-in rates
+func NewConverter(source string, ttl time.Duration) *Converter {
+	return &Converter{
+		source: source,
+		ttl:    ttl,
+		client: &http.Client{Timeout: 10 * time.Second},
+		table:  BuiltIn(),
+	}
+}
+
+// Table returns the current rates, reloading them from the source once they are older than the TTL. A source
+// that fails keeps the rates last loaded, or the built-in rates until it first succeeds. One caller reloads the
+// source, without holding the lock; the others are served the rates already loaded rather than wait for it.
+func (c *Converter) Table(ctx context.Context) Table {
+	if c.source == "" {
+		return c.table
+	}
+
+	c.mutex.Lock()
+	now := time.Now()
+	if c.fetching || (c.loaded && now.Sub(c.table.FetchedAt) < c.ttl) || now.Sub(c.lastAttempt) < retryInterval {
+		table := c.table
+		c.mutex.Unlock()
+		return table
+	}
+	c.fetching, c.lastAttempt = true, now
+	c.mutex.Unlock()
+
+	table, err := c.fetch(ctx)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.fetching = false
+	if err != nil {
+		log.Printf("⚠️ Failed to load exchange rates from %s, using %s rates of %s: %v",
+			c.source, c.table.Source, c.table.Date.Format("2006-01-02"), err)
+		return c.table
+	}
+	table.Source = c.source
+	table.FetchedAt = now
+	c.table, c.loaded = table, true
+	log.Printf("💱 Loaded %d exchange rates of %s from %s", len(table.Rates), table.Date.Format("2006-01-02"), c.source)
+	return c.table
+}
+
+func (c *Converter) fetch(ctx context.Context) (Table, error) {
+	if !strings.HasPrefix(c.source, "http://") && !strings.HasPrefix(c.source, "https://") {
+		data, err := os.ReadFile(c.source)
+		if err != nil {
+			return Table{}, err
+		}
+		return Parse(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.source, nil)
+	if err != nil {
+		return Table{}, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return Table{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Table{}, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return Table{}, err
+	}
+	return Parse(data)
+}
+
+// Round keeps a rate to six significant digits for display
+func Round(rate float64) float64 {
+	if rate == 0 {
+		return 0
+	}
+	scale := math.Pow10(5 - int(math.Floor(math.Log10(math.Abs(rate)))))
+	return math.Round(rate*scale) / scale
+}
@@ -0,0 +1,100 @@
+package rates
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"price-comparison-tool/internal/money"
+)
+
+const ecbDaily = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<Cube>
+		<Cube time="2024-05-17">
+			<Cube currency="USD" rate="1.0867"/>
+			<Cube currency="JPY" rate="169.21"/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>`
+
+func TestParse(t *testing.T) {
+	tests := map[string]string{
+		"ecb":  ecbDaily,
+		"json": `{"base": "eur", "date": "2024-05-17", "rates": {"usd": 1.0867, "jpy": 169.21}}`,
+	}
+	for name, data := range tests {
+		table, err := Parse([]byte(data))
+		if err != nil {
+			t.Fatalf("%s: Parse() error: %v", name, err)
+		}
+		if table.Base != "EUR" || table.Rates["USD"] != 1.0867 || table.Date.Format("2006-01-02") != "2024-05-17" {
+			t.Errorf("%s: Parse() = %+v, want EUR base, USD 1.0867 on 2024-05-17", name, table)
+		}
+	}
+
+	for _, data := range []string{`{"base": "EUR", "rates": {}}`, `<x/>`, `not rates`} {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", data)
+		}
+	}
+}
+
+func TestConvertCrossesThroughBase(t *testing.T) {
+	table := Table{Base: "EUR", Rates: map[string]float64{"USD": 1.25, "JPY": 160}}
+
+	converted, rate, err := table.Convert(money.FromFloat(10, "USD"), "JPY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rate != 128 || converted.Decimal() != "1280" || converted.Currency != "JPY" {
+		t.Errorf("Convert(10 USD, JPY) = %s at %v, want 1280 JPY at 128", converted, rate)
+	}
+	if _, _, err := table.Convert(money.FromFloat(10, "USD"), "XYZ"); err == nil {
+		t.Error("Convert to an unknown currency succeeded, want an error")
+	}
+}
+
+func TestTableServesCurrentRatesWhileReloading(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte(ecbDaily))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	converter := NewConverter(server.URL, time.Hour)
+	reloaded := make(chan Table)
+	go func() { reloaded <- converter.Table(context.Background()) }()
+
+	// Wait until the first caller has claimed the reload, then check a second caller is not blocked behind it
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		converter.mutex.Lock()
+		fetching := converter.fetching
+		converter.mutex.Unlock()
+		if fetching {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no reload started")
+		}
+	}
+	served := make(chan Table)
+	go func() { served <- converter.Table(context.Background()) }()
+	select {
+	case table := <-served:
+		if table.Source != SourceBuiltIn {
+			t.Errorf("Table() during the reload came from %q, want the built-in rates", table.Source)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Table() waited for another caller's reload")
+	}
+
+	release <- struct{}{}
+	if table := <-reloaded; table.Source != server.URL || table.Rates["JPY"] != 169.21 {
+		t.Errorf("reloaded Table() = %+v, want the source's rates", table)
+	}
+}
@@ -0,0 +1,204 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"price-comparison-tool/internal/models"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/gocolly/colly/v2"
+)
+
+const (
+	// chunkOverlap is carried between free-text chunks so a product split across a boundary is seen whole
+	chunkOverlap = 300
+
+	maxProductsPerSite = 25
+)
+
+// extractContentChunks splits a results page into product-card-aligned chunks sized for the LLM window
+func (s *Service) extractContentChunks(e *colly.HTMLElement, site models.SiteConfig) []string {
+	chunkSize := s.matcher.ExtractionChunkSize()
+
+	cards := s.collectProductCards(e, site.Selectors.Product)
+	if len(cards) > 0 {
+		log.Printf("Found %d product cards on %s", len(cards), site.Name)
+		return packChunks(cards, chunkSize)
+	}
+
+	// No card markup matched, fall back to overlapping windows over the main content
+	mainContent := s.extractMainContent(e)
+	if mainContent == "" {
+		return nil
+	}
+	return splitText(mainContent, chunkSize, chunkOverlap)
+}
+
+// collectProductCards returns the cleaned text of every element matching the product selector.
+// Nested matches (e.g. ".s-result-item" inside "[data-asin]") are skipped so cards are not repeated.
+func (s *Service) collectProductCards(e *colly.HTMLElement, selector string) []string {
+	if selector == "" {
+		return nil
+	}
+
+	var cards []string
+	previous := ""
+	e.ForEach(selector, func(_ int, card *colly.HTMLElement) {
+		text := s.cleanContent(card.Text)
+		if utf8.RuneCountInString(text) < 20 {
+			return
+		}
+		if previous != "" && strings.Contains(previous, text) {
+			return
+		}
+		cards = append(cards, text)
+		previous = text
+	})
+
+	return cards
+}
+
+// packChunks groups whole segments into chunks no larger than size, splitting only oversized segments
+func packChunks(segments []string, size int) []string {
+	var chunks []string
+	var current strings.Builder
+
+	flush := func() {
+		if current.Len() > 0 {
+			chunks = append(chunks, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, segment := range segments {
+		if len(segment) > size {
+			flush()
+			chunks = append(chunks, splitText(segment, size, 0)...)
+			continue
+		}
+		if current.Len()+len(segment)+1 > size {
+			flush()
+		}
+		if current.Len() > 0 {
+			current.WriteString("\n")
+		}
+		current.WriteString(segment)
+	}
+	flush()
+
+	return chunks
+}
+
+// splitText cuts text into pieces of at most size bytes on whitespace, never inside a UTF-8 sequence
+func splitText(text string, size, overlap int) []string {
+	if overlap >= size/2 {
+		overlap = size / 4
+	}
+
+	var chunks []string
+	for len(text) > size {
+		cut := size
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		if space := strings.LastIndexAny(text[:cut], " \n"); space > size/2 {
+			cut = space
+		}
+
+		chunks = append(chunks, strings.TrimSpace(text[:cut]))
+
+		next := cut - overlap
+		if next <= 0 {
+			next = cut
+		}
+		for next < cut && !utf8.RuneStart(text[next]) {
+			next++
+		}
+		if space := strings.IndexByte(text[next:cut], ' '); space >= 0 {
+			next += space + 1
+		}
+		text = text[next:]
+	}
+
+	if rest := strings.TrimSpace(text); rest != "" {
+		chunks = append(chunks, rest)
+	}
+	return chunks
+}
+
+// limitChunks keeps the first limit chunks of a page, reporting the cut so the response can say the site's
+// results are incomplete; a limit of 0 or less keeps every chunk
+func limitChunks(chunks []string, limit int, siteName string) ([]string, *models.Truncation) {
+	if limit <= 0 || len(chunks) <= limit {
+		return chunks, nil
+	}
+	log.Printf("Page for %s produced %d chunks, extracting the first %d", siteName, len(chunks), limit)
+	return chunks[:limit], &models.Truncation{Site: siteName, Chunks: len(chunks), Extracted: limit}
+}
+
+// extractProductsChunked runs LLM extraction over every chunk in parallel and merges the results.
+// Concurrency is bounded globally by the matcher's LLM scheduler rather than per page.
+func (s *Service) extractProductsChunked(ctx context.Context, chunks []string, query, country string, site models.SiteConfig, minConfidence float64) ([]models.ProductResult, error) {
+	batches := make([][]models.ProductResult, len(chunks))
+	errs := make([]error, len(chunks))
+
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func(i int, chunk string) {
+			defer wg.Done()
+			batches[i], errs[i] = s.extractProductsWithLLM(ctx, chunk, query, country, site.Name, site.BaseURL, site.Category, minConfidence)
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	failed := 0
+	for i, err := range errs {
+		if err != nil {
+			failed++
+			log.Printf("LLM extraction failed for %s chunk %d/%d: %v", site.Name, i+1, len(chunks), err)
+		}
+	}
+	if failed == len(chunks) {
+		return nil, fmt.Errorf("all %d chunks failed, last error: %v", len(chunks), errs[len(errs)-1])
+	}
+
+	products := mergeExtractedProducts(batches)
+	log.Printf("Merged %d products from %d/%d chunks for %s", len(products), len(chunks)-failed, len(chunks), site.Name)
+
+	return products, nil
+}
+
+// mergeExtractedProducts concatenates chunk results, keeping the most confident copy of each product
+func mergeExtractedProducts(batches [][]models.ProductResult) []models.ProductResult {
+	var merged []models.ProductResult
+	seen := make(map[string]int)
+
+	for _, batch := range batches {
+		for _, product := range batch {
+			key := productDedupKey(product)
+			if idx, exists := seen[key]; exists {
+				if product.Confidence > merged[idx].Confidence {
+					merged[idx] = product
+				}
+				continue
+			}
+			seen[key] = len(merged)
+			merged = append(merged, product)
+		}
+	}
+
+	if len(merged) > maxProductsPerSite {
+		merged = merged[:maxProductsPerSite]
+	}
+	return merged
+}
+
+// productDedupKey identifies a listing by its normalized title and price. Links are not used because
+// sponsored results share redirect paths and overlapping chunks can report the same card differently.
+func productDedupKey(product models.ProductResult) string {
+	return strings.ToLower(strings.Join(strings.Fields(product.ProductName), " ")) + "|" + product.Price
+}
@@ -0,0 +1,40 @@
+package scraper
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLimitChunksReportsTruncation(t *testing.T) {
+	chunks := []string{"a", "b", "c", "d"}
+
+	kept, truncation := limitChunks(chunks, 3, "Amazon")
+	if len(kept) != 3 || truncation == nil {
+		t.Fatalf("limitChunks(4 chunks, 3) kept %d, truncation %v; want 3 kept and a truncation", len(kept), truncation)
+	}
+	if truncation.Site != "Amazon" || truncation.Chunks != 4 || truncation.Extracted != 3 {
+		t.Errorf("truncation = %+v, want Amazon with 3 of 4 chunks extracted", *truncation)
+	}
+
+	for _, limit := range []int{0, 4, 10} {
+		if kept, truncation := limitChunks(chunks, limit, "Amazon"); len(kept) != 4 || truncation != nil {
+			t.Errorf("limitChunks(4 chunks, %d) kept %d, truncation %v; want every chunk and no truncation", limit, len(kept), truncation)
+		}
+	}
+}
+
+func TestPackChunksKeepsCardsWhole(t *testing.T) {
+	cards := []string{strings.Repeat("a", 40), strings.Repeat("b", 40), strings.Repeat("c", 40)}
+
+	chunks := packChunks(cards, 100)
+	if len(chunks) != 2 {
+		t.Fatalf("packChunks() = %d chunks, want 2", len(chunks))
+	}
+	for _, chunk := range chunks {
+		for _, card := range cards {
+			if strings.Contains(chunk, card[:1]) && !strings.Contains(chunk, card) {
+				t.Errorf("chunk %q splits card %q", chunk, card)
+			}
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package scraper
+
+import (
+	"context"
+	"price-comparison-tool/internal/feedback"
+	"price-comparison-tool/internal/models"
+	"strings"
+)
+
+// RecordFeedback stores a relevance judgement with the match features of each judged result. Picking the
+// correct match also records the other results shown as irrelevant.
+func (s *Service) RecordFeedback(ctx context.Context, req models.FeedbackRequest) ([]feedback.Entry, error) {
+	intent := s.matcher.ParseQuery(ctx, req.Query)
+	entries := []feedback.Entry{s.feedbackEntry(req, intent, req.Result, req.Label)}
+	for _, other := range req.Others {
+		if strings.TrimSpace(other.ProductName) != "" {
+			entries = append(entries, s.feedbackEntry(req, intent, other, feedback.LabelIrrelevant))
+		}
+	}
+	return s.feedback.Append(entries)
+}
+
+func (s *Service) feedbackEntry(req models.FeedbackRequest, intent models.QueryIntent, result models.ProductResult, label string) feedback.Entry {
+	entry := feedback.Entry{
+		Query:         req.Query,
+		Country:       req.Country,
+		ProductName:   result.ProductName,
+		Site:          result.Site,
+		Link:          result.Link,
+		Label:         label,
+		Confidence:    result.Confidence,
+		RawConfidence: result.RawConfidence,
+		Source:        result.Strategy,
+		Features:      s.matcher.MatchFeatures(intent, result.ProductName),
+	}
+	// Results from clients that do not send rawConfidence were shown their raw confidence unless calibrated
+	if entry.RawConfidence == 0 && result.Calibration == "" {
+		entry.RawConfidence = result.Confidence
+	}
+	if result.Explanation != nil {
+		entry.Scorer = result.Explanation.Scorer
+	}
+	return entry
+}
@@ -0,0 +1,173 @@
+package scraper
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"price-comparison-tool/internal/identifiers"
+	"price-comparison-tool/internal/models"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly/v2"
+)
+
+const (
+	// detailPageTimeout bounds each product detail page fetched to read identifiers or shipping
+	detailPageTimeout = 8 * time.Second
+	// minDetailPageTime is the least time before the search's deadline worth starting a detail page fetch with
+	minDetailPageTime = 2 * time.Second
+)
+
+// attachStructuredData records the identifiers in each offer's link and, when the results page carries JSON-LD,
+// the identifiers, condition and shipping of the structured-data product with the same URL or name
+func attachStructuredData(products []models.ProductResult, body []byte) {
+	var listings []identifiers.Listing
+	if len(body) > 0 {
+		if doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body)); err == nil {
+			listings = identifiers.FromStructuredData(doc)
+		}
+	}
+
+	for i := range products {
+		ids := identifiers.Merge(products[i].Identifiers, identifiers.FromLink(products[i].Link))
+		for _, listing := range listings {
+			if sameListing(products[i], listing) {
+				ids = identifiers.Merge(ids, listing.Identifiers)
+				if products[i].Condition == "" {
+					products[i].Condition = listing.Condition
+				}
+				if products[i].Shipping == nil {
+					setShipping(&products[i], listing.Shipping)
+				}
+				break
+			}
+		}
+		products[i].Identifiers = ids
+	}
+}
+
+// sameListing pairs an offer with a structured-data product by URL path, or by title when the URL is missing
+func sameListing(product models.ProductResult, listing identifiers.Listing) bool {
+	if path := linkPath(listing.URL); path != "" && path == linkPath(product.Link) {
+		return true
+	}
+	return listing.Name != "" && strings.EqualFold(strings.TrimSpace(listing.Name), strings.TrimSpace(product.ProductName))
+}
+
+func linkPath(link string) string {
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(parsed.Path, "/")
+}
+
+// lookupIdentifiers fetches the detail pages of offers still without a barcode, at most IDENTIFIER_LOOKUPS
+// per site, and reads identifiers from their structured data, microdata and specification tables. Lookups stop
+// once ctx's deadline is too near to fit another fetch.
+func (s *Service) lookupIdentifiers(ctx context.Context, site models.SiteConfig, products []models.ProductResult) {
+	limit := s.config.IdentifierLookups
+	var wg sync.WaitGroup
+	for i := range products {
+		if limit == 0 {
+			break
+		}
+		if _, ok := detailPageBudget(ctx); !ok {
+			log.Printf("Skipping identifier lookups for %s: search deadline too near", site.Name)
+			break
+		}
+		if products[i].GTIN != "" || !strings.HasPrefix(products[i].Link, "http") {
+			continue
+		}
+		limit--
+
+		wg.Add(1)
+		go func(product *models.ProductResult) {
+			defer wg.Done()
+			doc, err := fetchDetailPage(ctx, site, product.Link)
+			if err != nil {
+				log.Printf("Identifier lookup failed for %s: %v", product.Link, err)
+				return
+			}
+			product.Identifiers = identifiers.Merge(product.Identifiers, identifiers.FromDetailPage(doc))
+		}(&products[i])
+	}
+	wg.Wait()
+}
+
+// detailPageBudget is how long a detail page fetch may take without running past ctx's deadline, and false when
+// too little time is left to start one
+func detailPageBudget(ctx context.Context) (time.Duration, bool) {
+	if ctx.Err() != nil {
+		return 0, false
+	}
+	budget := detailPageTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		if left := time.Until(deadline); left < budget {
+			budget = left
+		}
+	}
+	return budget, budget >= minDetailPageTime
+}
+
+// fetchDetailPage fetches an offer's product detail page with the site's headers, timing out by ctx's deadline
+func fetchDetailPage(ctx context.Context, site models.SiteConfig, link string) (*goquery.Document, error) {
+	budget, ok := detailPageBudget(ctx)
+	if !ok {
+		return nil, fmt.Errorf("search deadline too near")
+	}
+	var body []byte
+	collector := colly.NewCollector()
+	collector.SetRequestTimeout(budget)
+	for key, value := range site.Headers {
+		key, value := key, value
+		collector.OnRequest(func(r *colly.Request) {
+			r.Headers.Set(key, value)
+		})
+	}
+	collector.OnResponse(func(r *colly.Response) {
+		body = r.Body
+	})
+	if err := collector.Visit(link); err != nil {
+		return nil, err
+	}
+	return goquery.NewDocumentFromReader(bytes.NewReader(body))
+}
+
+// matchRequestedIdentifier gives an offer carrying the requested barcode full confidence, skipping scoring
+func matchRequestedIdentifier(req models.PriceRequest, product *models.ProductResult) bool {
+	if req.GTIN == "" || identifiers.Match(models.Identifiers{GTIN: req.GTIN}, product.Identifiers) != identifiers.KindGTIN {
+		return false
+	}
+	product.Confidence = 1.0
+	product.RawConfidence = 1.0
+	product.Calibration = ""
+	product.MatchedBy = identifiers.KindGTIN
+	product.Explanation = nil
+	if req.Explain {
+		product.Explanation = &models.ScoreExplanation{
+			Scorer: identifiers.KindGTIN,
+			Score:  1.0,
+			Rules:  []string{"gtin match: " + req.GTIN},
+		}
+	}
+	return true
+}
+
+// penaliseIdentifierConflict halves the confidence of an offer whose barcode differs from the requested one,
+// which is a different product however alike the titles read
+func penaliseIdentifierConflict(req models.PriceRequest, product *models.ProductResult) {
+	if req.GTIN != "" && identifiers.Conflict(models.Identifiers{GTIN: req.GTIN}, product.Identifiers) {
+		product.Confidence *= 0.5
+		product.RawConfidence *= 0.5
+		if product.Explanation != nil {
+			product.Explanation.Score = product.Confidence
+			product.Explanation.Rules = append(product.Explanation.Rules, "gtin conflict: "+product.GTIN+" instead of "+req.GTIN+", score halved")
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package scraper
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDetailPageBudgetFollowsDeadline(t *testing.T) {
+	if budget, ok := detailPageBudget(context.Background()); !ok || budget != detailPageTimeout {
+		t.Errorf("without a deadline: %v, %v; want %v", budget, ok, detailPageTimeout)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 4*time.Second)
+	defer cancel()
+	if budget, ok := detailPageBudget(ctx); !ok || budget > 4*time.Second || budget < 3*time.Second {
+		t.Errorf("4s before the deadline: %v, %v; want about 4s", budget, ok)
+	}
+
+	near, cancelNear := context.WithTimeout(context.Background(), time.Second)
+	defer cancelNear()
+	if _, ok := detailPageBudget(near); ok {
+		t.Error("1s before the deadline: want the lookup skipped")
+	}
+
+	cancelled, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+	if _, ok := detailPageBudget(cancelled); ok {
+		t.Error("cancelled context: want the lookup skipped")
+	}
+}
@@ -0,0 +1,98 @@
+package scraper
+
+import (
+	"context"
+	"log"
+	"price-comparison-tool/internal/identifiers"
+	"price-comparison-tool/internal/landed"
+	"price-comparison-tool/internal/models"
+	"price-comparison-tool/internal/rates"
+	"strings"
+	"sync"
+)
+
+// setShipping reads a listing's delivery charge; free delivery is zero in the currency of the price
+func setShipping(product *models.ProductResult, shippingText string) {
+	shippingText = strings.TrimSpace(shippingText)
+	if amount, ok := landed.ParseShipping(shippingText, product.Country, product.Currency); ok {
+		product.ShippingText = shippingText
+		product.Shipping = &amount
+	}
+}
+
+// setTaxLabel records whether the price includes tax when text labels it, e.g. "incl. VAT" or "+ tax"
+func setTaxLabel(product *models.ProductResult, text string) {
+	if included, labelled := landed.TaxLabel(text); labelled {
+		product.TaxIncluded = &included
+	}
+}
+
+// lookupShipping fetches the detail pages of offers still without a delivery charge, at most SHIPPING_LOOKUPS
+// per site, and reads the shipping rate from their structured data. Lookups stop once ctx's deadline is too near
+// to fit another fetch.
+func (s *Service) lookupShipping(ctx context.Context, site models.SiteConfig, products []models.ProductResult) {
+	limit := s.config.ShippingLookups
+	var wg sync.WaitGroup
+	for i := range products {
+		if limit == 0 {
+			break
+		}
+		if _, ok := detailPageBudget(ctx); !ok {
+			log.Printf("Skipping shipping lookups for %s: search deadline too near", site.Name)
+			break
+		}
+		if products[i].Shipping != nil || !strings.HasPrefix(products[i].Link, "http") {
+			continue
+		}
+		limit--
+
+		wg.Add(1)
+		go func(product *models.ProductResult) {
+			defer wg.Done()
+			doc, err := fetchDetailPage(ctx, site, product.Link)
+			if err != nil {
+				log.Printf("Shipping lookup failed for %s: %v", product.Link, err)
+				return
+			}
+			for _, listing := range identifiers.FromStructuredData(doc) {
+				if listing.Shipping != "" {
+					setShipping(product, listing.Shipping)
+					return
+				}
+			}
+		}(&products[i])
+	}
+	wg.Wait()
+}
+
+// estimateLandedCosts fills in whether each price includes tax from the country's rule, unless the listing said,
+// and when the request asks for landed cost, estimates each total for the shopper, converted like the price
+func (s *Service) estimateLandedCosts(ctx context.Context, req models.PriceRequest, products []models.ProductResult) {
+	rule, known := s.taxRules.For(req.Country)
+	for i := range products {
+		if products[i].TaxIncluded == nil && known {
+			included := rule.Included
+			products[i].TaxIncluded = &included
+		}
+	}
+	if !req.LandedCost {
+		return
+	}
+
+	var table rates.Table
+	if req.DisplayCurrency != "" {
+		table = s.rates.Table(ctx)
+	}
+	for i := range products {
+		cost, err := s.taxRules.Estimate(products[i], req.Country, req.PostalCode)
+		if err != nil {
+			continue
+		}
+		if req.DisplayCurrency != "" {
+			if cost.Converted, err = convertAmount(table, cost.Total, req.DisplayCurrency); err != nil {
+				log.Printf("Cannot convert %s landed cost of %s: %v", products[i].Site, products[i].ProductName, err)
+			}
+		}
+		products[i].Landed = &cost
+	}
+}
@@ -0,0 +1,155 @@
+package scraper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"price-comparison-tool/internal/llm"
+	"price-comparison-tool/internal/matcher"
+	"price-comparison-tool/internal/models"
+	"price-comparison-tool/internal/prompts"
+	"price-comparison-tool/internal/siterepair"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const (
+	// selectorRepairCooldown limits selector induction to one attempt per site in this window
+	selectorRepairCooldown = time.Hour
+	selectorRepairTimeout  = 2 * time.Minute
+)
+
+// maybeRepairSelectors starts selector induction in the background when a site's CSS selectors matched
+// nothing on a page that evidently lists products. Proposals are stored for admin approval, never applied directly.
+func (s *Service) maybeRepairSelectors(site models.SiteConfig, pageURL string, body []byte) {
+	if len(body) == 0 {
+		return
+	}
+
+	s.repairMutex.Lock()
+	if last, exists := s.lastRepair[site.Name]; exists && time.Since(last) < selectorRepairCooldown {
+		s.repairMutex.Unlock()
+		return
+	}
+	s.lastRepair[site.Name] = time.Now()
+	s.repairMutex.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), selectorRepairTimeout)
+		defer cancel()
+
+		revision, err := s.induceSelectors(ctx, site, pageURL, body)
+		if err != nil {
+			log.Printf("🔧 Selector repair for %s skipped: %v", site.Name, err)
+			return
+		}
+		log.Printf("🔧 Proposed selectors for %s (revision %s, %d/%d cards valid) awaiting approval",
+			site.Name, revision.ID, revision.Validation.ValidCards, revision.Validation.Cards)
+	}()
+}
+
+func (s *Service) induceSelectors(ctx context.Context, site models.SiteConfig, pageURL string, body []byte) (*siterepair.Revision, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if !siterepair.LooksLikeProductPage(doc) {
+		return nil, fmt.Errorf("page does not look like a product listing")
+	}
+
+	outline := siterepair.Outline(doc)
+	if outline == "" {
+		return nil, fmt.Errorf("no repeated priced structures found")
+	}
+
+	tmpl, err := s.matcher.Prompts().Get(prompts.Selectors, prompts.Scope{Site: site.Name})
+	if err != nil {
+		return nil, err
+	}
+	prompt, err := tmpl.Render(prompts.SelectorsData{Site: site.Name, Current: site.Selectors, Outline: outline})
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := s.matcher.CallOllama(ctx, llm.PriorityBackground, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	var proposal struct {
+		Candidates []siterepair.Candidate `json:"candidates"`
+	}
+	if err := json.Unmarshal([]byte(matcher.ExtractJSONObject(response)), &proposal); err != nil {
+		return nil, fmt.Errorf("failed to parse LLM response: %v", err)
+	}
+
+	var best *siterepair.Candidate
+	var bestValidation siterepair.Validation
+	for i, candidate := range proposal.Candidates {
+		validation, err := siterepair.Validate(doc, candidate, site.BaseURL)
+		if err != nil {
+			log.Printf("🔧 Candidate %d for %s rejected: %v", i+1, site.Name, err)
+			continue
+		}
+		if best == nil || validation.Score > bestValidation.Score {
+			best = &proposal.Candidates[i]
+			bestValidation = validation
+		}
+	}
+	if best == nil || bestValidation.Score < siterepair.MinValidationScore {
+		return nil, fmt.Errorf("no candidate reached validation score %.2f", siterepair.MinValidationScore)
+	}
+
+	proposed := site.Selectors
+	proposed.Product = best.Product
+	proposed.Title = best.Title
+	proposed.Price = best.Price
+	proposed.Link = best.Link
+
+	return s.selectorRevisions.Add(siterepair.Revision{
+		Site:       site.Name,
+		PageURL:    pageURL,
+		Current:    site.Selectors,
+		Proposed:   proposed,
+		Validation: bestValidation,
+	})
+}
+
+// SelectorRevisions lists stored selector revisions, optionally filtered by status
+func (s *Service) SelectorRevisions(status string) []siterepair.Revision {
+	return s.selectorRevisions.List(status)
+}
+
+// ReviewSelectorRevision approves or rejects a pending revision; approved selectors take effect immediately
+func (s *Service) ReviewSelectorRevision(id string, approve bool) (*siterepair.Revision, error) {
+	status := siterepair.StatusRejected
+	if approve {
+		status = siterepair.StatusApproved
+	}
+
+	revision, err := s.selectorRevisions.Review(id, status)
+	if err != nil {
+		return nil, err
+	}
+	if approve {
+		s.applySelectors(revision.Site, revision.Proposed)
+	}
+	return revision, nil
+}
+
+// applySelectors replaces a site's selectors in the live configuration
+func (s *Service) applySelectors(siteName string, selectors models.SiteSelectors) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i := range s.sites {
+		if s.sites[i].Name == siteName {
+			s.sites[i].Selectors = selectors
+			log.Printf("🔧 Applied approved selectors for %s", siteName)
+			return
+		}
+	}
+}
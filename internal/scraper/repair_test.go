@@ -0,0 +1,77 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"price-comparison-tool/internal/config"
+	"price-comparison-tool/internal/siterepair"
+)
+
+// listingPage is a results page whose cards no longer match a site's configured selectors
+func listingPage() []byte {
+	var b strings.Builder
+	b.WriteString(`<html><body><div class="grid">`)
+	for i := 1; i <= 6; i++ {
+		fmt.Fprintf(&b, `<div class="tile"><a class="tile-link" href="/item/%d"><span class="tile-name">Phone %c</span></a>`+
+			`<span class="tile-price">$%d.00</span></div>`, i, 'A'+i-1, 300+i)
+	}
+	b.WriteString(`</div></body></html>`)
+	return []byte(b.String())
+}
+
+func TestInduceSelectorsProposesValidatedCandidateForApproval(t *testing.T) {
+	// The model proposes one candidate that reads no prices and one that reads every card
+	proposal := `Here are the selectors: {"candidates": [` +
+		`{"product": "div.tile", "title": ".tile-name", "price": ".tile-name", "link": "a"},` +
+		`{"product": "div.tile", "title": ".tile-name", "price": ".tile-price", "link": "a.tile-link"}]}`
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"response": proposal})
+	}))
+	defer ollama.Close()
+
+	s := NewService(&config.Config{
+		OllamaHost:            ollama.URL,
+		LLMConcurrency:        1,
+		SelectorRevisionsFile: filepath.Join(t.TempDir(), "revisions.json"),
+	})
+	site := s.sites[0]
+
+	revision, err := s.induceSelectors(context.Background(), site, site.BaseURL+"/search", listingPage())
+	if err != nil {
+		t.Fatalf("induceSelectors() error: %v", err)
+	}
+	if revision.Status != siterepair.StatusPending || revision.Proposed.Price != ".tile-price" || revision.Validation.ValidCards != 6 {
+		t.Errorf("induceSelectors() = %+v, want the pending candidate that read all 6 cards", revision)
+	}
+	if revision.Proposed.ListPrice != site.Selectors.ListPrice || revision.Current != site.Selectors {
+		t.Errorf("induceSelectors() proposed %+v, want the selectors it did not induce kept from %+v", revision.Proposed, site.Selectors)
+	}
+	if s.sites[0].Selectors.Product == "div.tile" {
+		t.Fatal("induceSelectors() applied the proposal before review")
+	}
+
+	if _, err := s.ReviewSelectorRevision(revision.ID, true); err != nil {
+		t.Fatalf("ReviewSelectorRevision() error: %v", err)
+	}
+	if s.sites[0].Selectors != revision.Proposed {
+		t.Errorf("selectors after approval %+v, want %+v", s.sites[0].Selectors, revision.Proposed)
+	}
+	if _, err := s.ReviewSelectorRevision(revision.ID, false); err == nil {
+		t.Error("ReviewSelectorRevision() re-reviewed an approved revision")
+	}
+}
+
+func TestInduceSelectorsRejectsPagesWithoutListings(t *testing.T) {
+	s := NewService(&config.Config{LLMConcurrency: 1})
+	page := []byte(`<html><body><h1>Robot check</h1><p>Type the characters you see.</p></body></html>`)
+	if _, err := s.induceSelectors(context.Background(), s.sites[0], "", page); err == nil {
+		t.Error("induceSelectors() proposed selectors for a page without priced listings")
+	}
+}
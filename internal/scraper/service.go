@@ -6,14 +6,25 @@ import (
 	"fmt"
 	"log"
 	"net/url"
+	"price-comparison-tool/internal/brands"
 	"price-comparison-tool/internal/config"
+	"price-comparison-tool/internal/feedback"
+	"price-comparison-tool/internal/landed"
+	"price-comparison-tool/internal/lang"
+	"price-comparison-tool/internal/llm"
 	"price-comparison-tool/internal/matcher"
 	"price-comparison-tool/internal/models"
+	"price-comparison-tool/internal/money"
+	"price-comparison-tool/internal/prompts"
+	"price-comparison-tool/internal/rates"
+	"price-comparison-tool/internal/siterepair"
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/PuerkitoBio/goquery"
 	"github.com/gocolly/colly/v2"
 	"github.com/gocolly/colly/v2/debug"
 )
@@ -24,6 +35,15 @@ type Service struct {
 	collectors map[string]*colly.Collector
 	matcher   *matcher.Service
 	mutex     sync.RWMutex
+
+	selectorRevisions *siterepair.Store
+	repairMutex       sync.Mutex
+	lastRepair        map[string]time.Time
+
+	feedback *feedback.Store
+
+	rates    *rates.Converter
+	taxRules landed.Rules
 }
 
 func NewService(cfg *config.Config) *Service {
@@ -31,10 +51,20 @@ func NewService(cfg *config.Config) *Service {
 		config:     cfg,
 		collectors: make(map[string]*colly.Collector),
 		matcher:    matcher.NewService(cfg),
+		lastRepair: make(map[string]time.Time),
+		feedback:   feedback.NewStore(cfg.FeedbackFile),
+		rates:      rates.NewConverter(cfg.RatesSource, time.Duration(cfg.RatesTTL)*time.Minute),
 	}
 	
 	s.loadSiteConfigs()
 	s.initializeCollectors()
+	s.loadSelectorRevisions()
+
+	taxRules, err := landed.Load(cfg.TaxRulesFile)
+	if err != nil {
+		log.Printf("⚠️ Failed to load tax rules from %s: %v", cfg.TaxRulesFile, err)
+	}
+	s.taxRules = taxRules
 	
 	return s
 }
@@ -47,11 +77,13 @@ func (s *Service) loadSiteConfigs() {
 			SearchPath: "/s?k=",
 			Countries:  []string{"US"},
 			Selectors: models.SiteSelectors{
-				Product:  "[data-component-type='s-search-result']",
-				Price:    ".a-price-whole, .a-offscreen",
-				Title:    "[data-cy='title-recipe-title'] span, h2 a span",
-				Link:     "h2 a",
-				Currency: ".a-price-symbol",
+				Product:   "[data-component-type='s-search-result']",
+				Price:     ".a-price-whole, .a-offscreen",
+				ListPrice: ".a-price.a-text-price .a-offscreen",
+				Shipping:  "[data-cy='delivery-recipe']",
+				Title:     "[data-cy='title-recipe-title'] span, h2 a span",
+				Link:      "h2 a",
+				Currency:  ".a-price-symbol",
 			},
 			Headers: map[string]string{
 				"User-Agent": "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36",
@@ -70,11 +102,13 @@ func (s *Service) loadSiteConfigs() {
 			SearchPath: "/s?k=",
 			Countries:  []string{"CA"},
 			Selectors: models.SiteSelectors{
-				Product:  "[data-component-type='s-search-result']",
-				Price:    ".a-price-whole, .a-offscreen",
-				Title:    "[data-cy='title-recipe-title'] span, h2 a span",
-				Link:     "h2 a",
-				Currency: ".a-price-symbol",
+				Product:   "[data-component-type='s-search-result']",
+				Price:     ".a-price-whole, .a-offscreen",
+				ListPrice: ".a-price.a-text-price .a-offscreen",
+				Shipping:  "[data-cy='delivery-recipe']",
+				Title:     "[data-cy='title-recipe-title'] span, h2 a span",
+				Link:      "h2 a",
+				Currency:  ".a-price-symbol",
 			},
 			Headers: map[string]string{
 				"User-Agent": "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36",
@@ -87,11 +121,13 @@ func (s *Service) loadSiteConfigs() {
 			SearchPath: "/s?k=",
 			Countries:  []string{"UK"},
 			Selectors: models.SiteSelectors{
-				Product:  "[data-component-type='s-search-result']",
-				Price:    ".a-price-whole, .a-offscreen",
-				Title:    "[data-cy='title-recipe-title'] span, h2 a span",
-				Link:     "h2 a",
-				Currency: ".a-price-symbol",
+				Product:   "[data-component-type='s-search-result']",
+				Price:     ".a-price-whole, .a-offscreen",
+				ListPrice: ".a-price.a-text-price .a-offscreen",
+				Shipping:  "[data-cy='delivery-recipe']",
+				Title:     "[data-cy='title-recipe-title'] span, h2 a span",
+				Link:      "h2 a",
+				Currency:  ".a-price-symbol",
 			},
 			Headers: map[string]string{
 				"User-Agent": "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36",
@@ -104,11 +140,13 @@ func (s *Service) loadSiteConfigs() {
 			SearchPath: "/s?k=",
 			Countries:  []string{"IN"},
 			Selectors: models.SiteSelectors{
-				Product:  "[data-component-type='s-search-result'], .s-result-item, [data-asin], .sg-col-inner",
-				Price:    ".a-price-whole, .a-offscreen, .a-price .a-offscreen, .a-price-range, .a-price",
-				Title:    "[data-cy='title-recipe-title'] span, h2 a span, .a-size-medium, .a-size-base-plus, [data-cy='title-recipe-title']",
-				Link:     "h2 a, .a-link-normal",
-				Currency: ".a-price-symbol",
+				Product:   "[data-component-type='s-search-result'], .s-result-item, [data-asin], .sg-col-inner",
+				Price:     ".a-price-whole, .a-offscreen, .a-price .a-offscreen, .a-price-range, .a-price",
+				ListPrice: ".a-price.a-text-price .a-offscreen",
+				Shipping:  "[data-cy='delivery-recipe']",
+				Title:     "[data-cy='title-recipe-title'] span, h2 a span, .a-size-medium, .a-size-base-plus, [data-cy='title-recipe-title']",
+				Link:      "h2 a, .a-link-normal",
+				Currency:  ".a-price-symbol",
 			},
 			Headers: map[string]string{
 				"User-Agent": "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
@@ -122,10 +160,13 @@ func (s *Service) loadSiteConfigs() {
 			SearchPath: "/sch/i.html?_nkw=",
 			Countries:  []string{"US"},
 			Selectors: models.SiteSelectors{
-				Product:  ".s-item",
-				Price:    ".s-item__price",
-				Title:    ".s-item__title",
-				Link:     ".s-item__link",
+				Product:   ".s-item",
+				Price:     ".s-item__price",
+				ListPrice: ".STRIKETHROUGH",
+				Title:     ".s-item__title",
+				Link:      ".s-item__link",
+				Condition: ".SECONDARY_INFO",
+				Shipping:  ".s-item__shipping, .s-item__logisticsCost",
 			},
 			Headers: map[string]string{
 				"User-Agent": "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36",
@@ -143,10 +184,13 @@ func (s *Service) loadSiteConfigs() {
 			SearchPath: "/sch/i.html?_nkw=",
 			Countries:  []string{"CA"},
 			Selectors: models.SiteSelectors{
-				Product:  ".s-item",
-				Price:    ".s-item__price",
-				Title:    ".s-item__title",
-				Link:     ".s-item__link",
+				Product:   ".s-item",
+				Price:     ".s-item__price",
+				ListPrice: ".STRIKETHROUGH",
+				Title:     ".s-item__title",
+				Link:      ".s-item__link",
+				Condition: ".SECONDARY_INFO",
+				Shipping:  ".s-item__shipping, .s-item__logisticsCost",
 			},
 			Headers: map[string]string{
 				"User-Agent": "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36",
@@ -159,10 +203,13 @@ func (s *Service) loadSiteConfigs() {
 			SearchPath: "/sch/i.html?_nkw=",
 			Countries:  []string{"UK"},
 			Selectors: models.SiteSelectors{
-				Product:  ".s-item",
-				Price:    ".s-item__price",
-				Title:    ".s-item__title",
-				Link:     ".s-item__link",
+				Product:   ".s-item",
+				Price:     ".s-item__price",
+				ListPrice: ".STRIKETHROUGH",
+				Title:     ".s-item__title",
+				Link:      ".s-item__link",
+				Condition: ".SECONDARY_INFO",
+				Shipping:  ".s-item__shipping, .s-item__logisticsCost",
 			},
 			Headers: map[string]string{
 				"User-Agent": "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36",
@@ -175,10 +222,11 @@ func (s *Service) loadSiteConfigs() {
 			SearchPath: "/search?q=",
 			Countries:  []string{"IN"},
 			Selectors: models.SiteSelectors{
-				Product:  "._1AtVbE, ._13oc-S, [data-id], ._1fQZEK, ._75nlfW, [data-testid='product-base'], .cPHDOP, ._2kHMtA, ._3pLy-c, .col-12-12",
-				Price:    "._30jeq3, ._1_WHN1, .Nx9bqj, ._25b18c, ._3I9_wc, ._2rQ-NK, .Nx9bqj, ._30jeq3, ._1_WHN1, ._25b18c",
-				Title:    "._4rR01T, .s1Q9rs, .IRpwTa, ._2WkVRV, ._3pLy-c, .col-7-12, .KzDlHZ, ._2WkVRV, ._4rR01T, .s1Q9rs",
-				Link:     "._1fQZEK, ._2rpwqI, .IRpwTa, ._2WkVRV a, ._3pLy-c a, .col-7-12 a, .KzDlHZ, ._2WkVRV a",
+				Product:   "._1AtVbE, ._13oc-S, [data-id], ._1fQZEK, ._75nlfW, [data-testid='product-base'], .cPHDOP, ._2kHMtA, ._3pLy-c, .col-12-12",
+				Price:     "._30jeq3, ._1_WHN1, .Nx9bqj, ._25b18c, ._3I9_wc, ._2rQ-NK, .Nx9bqj, ._30jeq3, ._1_WHN1, ._25b18c",
+				ListPrice: "._3I9_wc, .yRaY8j",
+				Title:     "._4rR01T, .s1Q9rs, .IRpwTa, ._2WkVRV, ._3pLy-c, .col-7-12, .KzDlHZ, ._2WkVRV, ._4rR01T, .s1Q9rs",
+				Link:      "._1fQZEK, ._2rpwqI, .IRpwTa, ._2WkVRV a, ._3pLy-c a, .col-7-12 a, .KzDlHZ, ._2WkVRV a",
 			},
 			Headers: map[string]string{
 				"User-Agent": "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36",
@@ -202,10 +250,11 @@ func (s *Service) loadSiteConfigs() {
 			SearchPath: "/search?keyword=",
 			Countries:  []string{"IN"},
 			Selectors: models.SiteSelectors{
-				Product:  ".product-tuple-listing",
-				Price:    ".lfloat.product-price",
-				Title:    ".product-title",
-				Link:     ".dp-widget-link",
+				Product:   ".product-tuple-listing",
+				Price:     ".lfloat.product-price",
+				ListPrice: ".product-desc-price.strike",
+				Title:     ".product-title",
+				Link:      ".dp-widget-link",
 			},
 			Headers: map[string]string{
 				"User-Agent": "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36",
@@ -229,10 +278,11 @@ func (s *Service) loadSiteConfigs() {
 			SearchPath: "/search?q=",
 			Countries:  []string{"US"},
 			Selectors: models.SiteSelectors{
-				Product:  "[data-testid='item-stack']",
-				Price:    "[data-automation-id='product-price']",
-				Title:    "[data-automation-id='product-title']",
-				Link:     "[data-automation-id='product-title'] a",
+				Product:   "[data-testid='item-stack']",
+				Price:     "[data-automation-id='product-price']",
+				ListPrice: "[data-automation-id='product-price'] .strike",
+				Title:     "[data-automation-id='product-title']",
+				Link:      "[data-automation-id='product-title'] a",
 			},
 			Headers: map[string]string{
 				"User-Agent": "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36",
@@ -245,10 +295,11 @@ func (s *Service) loadSiteConfigs() {
 			SearchPath: "/search?q=",
 			Countries:  []string{"CA"},
 			Selectors: models.SiteSelectors{
-				Product:  "[data-testid='product-tile']",
-				Price:    "[data-testid='price-current']",
-				Title:    "[data-testid='product-title']",
-				Link:     "[data-testid='product-title'] a",
+				Product:   "[data-testid='product-tile']",
+				Price:     "[data-testid='price-current']",
+				ListPrice: "[data-testid='price-was']",
+				Title:     "[data-testid='product-title']",
+				Link:      "[data-testid='product-title'] a",
 			},
 			Headers: map[string]string{
 				"User-Agent": "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36",
@@ -262,11 +313,13 @@ func (s *Service) loadSiteConfigs() {
 			SearchPath: "/s?k=",
 			Countries:  []string{"DE"},
 			Selectors: models.SiteSelectors{
-				Product:  "[data-component-type='s-search-result']",
-				Price:    ".a-price-whole, .a-offscreen",
-				Title:    "[data-cy='title-recipe-title'] span, h2 a span",
-				Link:     "h2 a",
-				Currency: ".a-price-symbol",
+				Product:   "[data-component-type='s-search-result']",
+				Price:     ".a-price-whole, .a-offscreen",
+				ListPrice: ".a-price.a-text-price .a-offscreen",
+				Shipping:  "[data-cy='delivery-recipe']",
+				Title:     "[data-cy='title-recipe-title'] span, h2 a span",
+				Link:      "h2 a",
+				Currency:  ".a-price-symbol",
 			},
 			Headers: map[string]string{
 				"User-Agent": "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36",
@@ -279,11 +332,13 @@ func (s *Service) loadSiteConfigs() {
 			SearchPath: "/s?k=",
 			Countries:  []string{"FR"},
 			Selectors: models.SiteSelectors{
-				Product:  "[data-component-type='s-search-result']",
-				Price:    ".a-price-whole, .a-offscreen",
-				Title:    "[data-cy='title-recipe-title'] span, h2 a span",
-				Link:     "h2 a",
-				Currency: ".a-price-symbol",
+				Product:   "[data-component-type='s-search-result']",
+				Price:     ".a-price-whole, .a-offscreen",
+				ListPrice: ".a-price.a-text-price .a-offscreen",
+				Shipping:  "[data-cy='delivery-recipe']",
+				Title:     "[data-cy='title-recipe-title'] span, h2 a span",
+				Link:      "h2 a",
+				Currency:  ".a-price-symbol",
 			},
 			Headers: map[string]string{
 				"User-Agent": "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36",
@@ -296,11 +351,13 @@ func (s *Service) loadSiteConfigs() {
 			SearchPath: "/s?k=",
 			Countries:  []string{"JP"},
 			Selectors: models.SiteSelectors{
-				Product:  "[data-component-type='s-search-result']",
-				Price:    ".a-price-whole, .a-offscreen",
-				Title:    "[data-cy='title-recipe-title'] span, h2 a span",
-				Link:     "h2 a",
-				Currency: ".a-price-symbol",
+				Product:   "[data-component-type='s-search-result']",
+				Price:     ".a-price-whole, .a-offscreen",
+				ListPrice: ".a-price.a-text-price .a-offscreen",
+				Shipping:  "[data-cy='delivery-recipe']",
+				Title:     "[data-cy='title-recipe-title'] span, h2 a span",
+				Link:      "h2 a",
+				Currency:  ".a-price-symbol",
 			},
 			Headers: map[string]string{
 				"User-Agent": "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36",
@@ -313,11 +370,13 @@ func (s *Service) loadSiteConfigs() {
 			SearchPath: "/s?k=",
 			Countries:  []string{"AU"},
 			Selectors: models.SiteSelectors{
-				Product:  "[data-component-type='s-search-result']",
-				Price:    ".a-price-whole, .a-offscreen",
-				Title:    "[data-cy='title-recipe-title'] span, h2 a span",
-				Link:     "h2 a",
-				Currency: ".a-price-symbol",
+				Product:   "[data-component-type='s-search-result']",
+				Price:     ".a-price-whole, .a-offscreen",
+				ListPrice: ".a-price.a-text-price .a-offscreen",
+				Shipping:  "[data-cy='delivery-recipe']",
+				Title:     "[data-cy='title-recipe-title'] span, h2 a span",
+				Link:      "h2 a",
+				Currency:  ".a-price-symbol",
 			},
 			Headers: map[string]string{
 				"User-Agent": "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36",
@@ -330,11 +389,13 @@ func (s *Service) loadSiteConfigs() {
 			BaseURL:    "https://www.myntra.com",
 			SearchPath: "/",
 			Countries:  []string{"IN"},
+			Category:   "fashion",
 			Selectors: models.SiteSelectors{
-				Product:  ".product-base",
-				Price:    ".product-discountedPrice",
-				Title:    ".product-product",
-				Link:     ".product-base a",
+				Product:   ".product-base",
+				Price:     ".product-discountedPrice",
+				ListPrice: ".product-strike",
+				Title:     ".product-product",
+				Link:      ".product-base a",
 			},
 			Headers: map[string]string{
 				"User-Agent": "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36",
@@ -364,10 +425,11 @@ func (s *Service) loadSiteConfigs() {
 			SearchPath: "/site/searchpage.jsp?st=",
 			Countries:  []string{"US"},
 			Selectors: models.SiteSelectors{
-				Product:  ".sku-item",
-				Price:    ".sr-price",
-				Title:    ".sku-header a",
-				Link:     ".sku-header a",
+				Product:   ".sku-item",
+				Price:     ".sr-price",
+				ListPrice: ".pricing-price__regular-price",
+				Title:     ".sku-header a",
+				Link:      ".sku-header a",
 			},
 			Headers: map[string]string{
 				"User-Agent": "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36",
@@ -377,6 +439,19 @@ func (s *Service) loadSiteConfigs() {
 	}
 }
 
+// loadSelectorRevisions opens the selector revision store and applies previously approved repairs
+func (s *Service) loadSelectorRevisions() {
+	store, err := siterepair.NewStore(s.config.SelectorRevisionsFile)
+	if err != nil {
+		log.Printf("⚠️ Failed to load selector revisions from %s: %v", s.config.SelectorRevisionsFile, err)
+	}
+	s.selectorRevisions = store
+
+	for siteName, selectors := range store.Approved() {
+		s.applySelectors(siteName, selectors)
+	}
+}
+
 func (s *Service) initializeCollectors() {
 	for _, site := range s.sites {
 		collector := colly.NewCollector(
@@ -403,10 +478,15 @@ func (s *Service) initializeCollectors() {
 	}
 }
 
-func (s *Service) FetchPrices(ctx context.Context, country, query string) ([]models.ProductResult, error) {
+// FetchPrices scrapes every site for the country and scores the results against the query. The response holds
+// the results with the intent they were scored against, the sites whose pages were only partly extracted and, for
+// the cascade strategy, the search's stage counts.
+func (s *Service) FetchPrices(ctx context.Context, req models.PriceRequest) (models.PriceResponse, error) {
+	req = searchByIdentifier(req)
+	country, query := req.Country, req.Query
 	relevantSites := s.getSitesForCountry(country)
 	if len(relevantSites) == 0 {
-		return nil, fmt.Errorf("no supported sites for country: %s", country)
+		return models.PriceResponse{}, fmt.Errorf("no supported sites for country: %s", country)
 	}
 	
 	// Create timeout context for scraping
@@ -421,7 +501,13 @@ func (s *Service) FetchPrices(ctx context.Context, country, query string) ([]mod
 		wg.Add(1)
 		go func(site models.SiteConfig) {
 			defer wg.Done()
-			results := s.scrapeWebsiteParallel(scrapingCtx, site, query, country)
+			results := s.scrapeWebsiteParallel(scrapingCtx, site, query, country, s.minConfidence(req))
+			if req.GTIN != "" {
+				s.lookupIdentifiers(scrapingCtx, site, results.Products)
+			}
+			if req.LandedCost {
+				s.lookupShipping(scrapingCtx, site, results.Products)
+			}
 			resultsChan <- results
 		}(site)
 	}
@@ -432,32 +518,54 @@ func (s *Service) FetchPrices(ctx context.Context, country, query string) ([]mod
 	}()
 	
 	var allResults []models.ProductResult
+	var truncated []models.Truncation
 	for result := range resultsChan {
 		if result.Error != nil {
 			log.Printf("Error scraping %s: %v", result.Site, result.Error)
 			continue
 		}
 		allResults = append(allResults, result.Products...)
+		if result.Truncated != nil {
+			truncated = append(truncated, *result.Truncated)
+		}
 	}
 	
 	log.Printf("Found %d raw results from %d sites before filtering", len(allResults), len(relevantSites))
 	
-	// Use parallel LLM processing with worker pool
-	filteredResults, err := s.processResultsParallel(ctx, query, allResults)
+	intent := s.matcher.ParseQuery(ctx, query)
+	s.classifyProducts(intent, allResults)
+	
+	// Score in parallel with the request's strategy
+	filteredResults, stages, err := s.processResultsParallel(ctx, req, intent, allResults)
 	if err != nil {
 		log.Printf("Parallel processing failed, using fallback: %v", err)
-		// Fallback to fuzzy matching if LLM processing fails
+		// Fallback to fuzzy matching if parallel scoring fails
+		fuzzy := s.matcher.Strategy(matcher.StrategyFuzzy)
 		for i := range allResults {
-			allResults[i].Confidence = s.matcher.FuzzyProductMatch(query, allResults[i].ProductName)
+			if !matchRequestedIdentifier(req, &allResults[i]) {
+				s.scoreProduct(ctx, req, fuzzy, intent, &allResults[i])
+				penaliseIdentifierConflict(req, &allResults[i])
+			}
 		}
-		return allResults, nil
+		filteredResults = allResults
 	}
 	
-	return filteredResults, nil
+	filteredResults = filterByCondition(filteredResults, matcher.WantedCondition(req, intent))
+	s.convertPrices(ctx, req.DisplayCurrency, filteredResults)
+	s.estimateLandedCosts(ctx, req, filteredResults)
+	return models.PriceResponse{
+		Results:   filteredResults,
+		Count:     len(filteredResults),
+		Intent:    &intent,
+		Stages:    stages,
+		Truncated: truncated,
+	}, nil
 }
 
 // FetchPricesStreaming provides real-time streaming of results as they become available
-func (s *Service) FetchPricesStreaming(ctx context.Context, country, query string, resultsChan chan<- models.StreamingResult) {
+func (s *Service) FetchPricesStreaming(ctx context.Context, req models.PriceRequest, resultsChan chan<- models.StreamingResult) {
+	req = searchByIdentifier(req)
+	country, query := req.Country, req.Query
 	relevantSites := s.getSitesForCountry(country)
 	if len(relevantSites) == 0 {
 		resultsChan <- models.StreamingResult{
@@ -467,11 +575,19 @@ func (s *Service) FetchPricesStreaming(ctx context.Context, country, query strin
 		return
 	}
 	
-	// Send initial status
+	// Send initial status along with how the query was understood
+	intent := s.matcher.ParseQuery(ctx, query)
 	resultsChan <- models.StreamingResult{
 		Status:   "processing",
 		Progress: 0,
 		Message:  fmt.Sprintf("Starting to scrape %d websites for %s in %s", len(relevantSites), query, country),
+		Intent:   &intent,
+	}
+	
+	// Streaming keeps the confidence extraction reported unless the request names a strategy
+	strategy := s.matcher.Strategy(matcher.StrategyFuzzy)
+	if req.Strategy != "" {
+		strategy = s.matcher.Strategy(req.Strategy)
 	}
 	
 	// Create timeout context for scraping
@@ -480,7 +596,7 @@ func (s *Service) FetchPricesStreaming(ctx context.Context, country, query strin
 	
 	var wg sync.WaitGroup
 	siteResultsChan := make(chan models.ScrapingResult, len(relevantSites))
-	completedSites := 0
+	var completedSites int32
 	
 	// Launch parallel goroutines for each website
 	for _, site := range relevantSites {
@@ -492,15 +608,21 @@ func (s *Service) FetchPricesStreaming(ctx context.Context, country, query strin
 			resultsChan <- models.StreamingResult{
 				Site:     site.Name,
 				Status:   "processing",
-				Progress: (completedSites * 100) / len(relevantSites),
+				Progress: int(atomic.LoadInt32(&completedSites)) * 100 / len(relevantSites),
 				Message:  fmt.Sprintf("Scraping %s...", site.Name),
 			}
 			
-			results := s.scrapeWebsiteParallel(scrapingCtx, site, query, country)
-			siteResultsChan <- results
+			results := s.scrapeWebsiteParallel(scrapingCtx, site, query, country, s.minConfidence(req))
+			if req.GTIN != "" {
+				s.lookupIdentifiers(scrapingCtx, site, results.Products)
+			}
+			if req.LandedCost {
+				s.lookupShipping(scrapingCtx, site, results.Products)
+			}
 			
 			// Send immediate results as they become available
 			if results.Error != nil {
+				siteResultsChan <- results
 				resultsChan <- models.StreamingResult{
 					Site:   site.Name,
 					Status: "error",
@@ -509,22 +631,59 @@ func (s *Service) FetchPricesStreaming(ctx context.Context, country, query strin
 			} else {
 				// Process results through LLM if needed
 				processedProducts := results.Products
+				var stages []models.StageStats
 				if len(processedProducts) > 0 {
 					// Apply confidence scoring in smaller batches for streaming
+					s.classifyProducts(intent, processedProducts)
+					processedProducts = filterByCondition(processedProducts, matcher.WantedCondition(req, intent))
+					siteStrategy := matcher.ForResults(strategy, processedProducts)
 					for i := range processedProducts {
-						if processedProducts[i].Confidence == 0 {
-							processedProducts[i].Confidence = s.matcher.FuzzyProductMatch(query, processedProducts[i].ProductName)
+						if matchRequestedIdentifier(req, &processedProducts[i]) {
+							continue
 						}
+						if processedProducts[i].Confidence == 0 || req.Strategy != "" {
+							s.scoreProduct(ctx, req, siteStrategy, intent, &processedProducts[i])
+						} else {
+							// Keep the confidence the extraction stage, or the CSS fallback, reported for the listing
+							if processedProducts[i].Strategy == "" {
+								processedProducts[i].Strategy = prompts.Extraction
+							}
+							if req.Explain {
+								processedProducts[i].Explanation = &models.ScoreExplanation{
+									Scorer: processedProducts[i].Strategy,
+									Score:  processedProducts[i].Confidence,
+									Rules:  []string{"confidence kept from " + processedProducts[i].Strategy},
+								}
+							}
+							s.calibrate(&processedProducts[i])
+						}
+						penaliseIdentifierConflict(req, &processedProducts[i])
+					}
+					if reporter, ok := siteStrategy.(matcher.StageReporter); ok {
+						stages = reporter.StageStats()
 					}
+					processedProducts = s.filterByConfidence(req, intent, processedProducts)
+					s.convertPrices(ctx, req.DisplayCurrency, processedProducts)
+					s.estimateLandedCosts(ctx, req, processedProducts)
 				}
 				
-				completedSites++
+				var otherVariants []models.ProductResult
+				if req.GroupVariants {
+					processedProducts, otherVariants = models.SplitVariants(processedProducts)
+				}
+				
+				// The summary clusters the offers this site reported, not the raw listings
+				siteResultsChan <- models.ScrapingResult{Products: processedProducts, Site: site.Name}
+				completed := atomic.AddInt32(&completedSites, 1)
 				resultsChan <- models.StreamingResult{
-					Site:     site.Name,
-					Products: processedProducts,
-					Status:   "completed",
-					Progress: (completedSites * 100) / len(relevantSites),
-					Message:  fmt.Sprintf("Found %d products from %s", len(processedProducts), site.Name),
+					Site:          site.Name,
+					Products:      processedProducts,
+					OtherVariants: otherVariants,
+					Stages:        stages,
+					Truncated:     results.Truncated,
+					Status:        "completed",
+					Progress:      int(completed) * 100 / len(relevantSites),
+					Message:       fmt.Sprintf("Found %d products from %s", len(processedProducts), site.Name),
 				}
 			}
 		}(site)
@@ -544,12 +703,16 @@ func (s *Service) FetchPricesStreaming(ctx context.Context, country, query strin
 		}
 	}
 	
-	// Send final completion status
-	resultsChan <- models.StreamingResult{
+	// Send final completion status, with every site's offers clustered when grouping was requested
+	final := models.StreamingResult{
 		Status:   "completed",
 		Progress: 100,
 		Message:  fmt.Sprintf("Completed scraping. Found %d total products from %d sites", len(allResults), len(relevantSites)),
 	}
+	if req.Grouped {
+		final.Groups = s.matcher.ClusterProducts(allResults)
+	}
+	resultsChan <- final
 }
 
 func (s *Service) scrapeWebsite(ctx context.Context, site models.SiteConfig, query, country string) models.ScrapingResult {
@@ -585,7 +748,7 @@ func (s *Service) scrapeWebsite(ctx context.Context, site models.SiteConfig, que
 		}
 		
 		title := strings.TrimSpace(e.ChildText(site.Selectors.Title))
-		priceText := strings.TrimSpace(e.ChildText(site.Selectors.Price))
+		priceText, listText := priceTexts(e, site)
 		linkHref := e.ChildAttr(site.Selectors.Link, "href")
 		
 		if title == "" || priceText == "" {
@@ -597,19 +760,16 @@ func (s *Service) scrapeWebsite(ctx context.Context, site models.SiteConfig, que
 			fullLink = site.BaseURL + linkHref
 		}
 		
-		currency := extractCurrency(priceText, country)
-		cleanPrice := cleanPriceText(priceText)
-		
-		if cleanPrice != "" && title != "" {
-			product := models.ProductResult{
-				Link:        fullLink,
-				Price:       cleanPrice,
-				Currency:    currency,
-				ProductName: title,
-				Site:        site.Name,
-				Country:     country,
-				FetchedAt:   time.Now(),
-			}
+		product := models.ProductResult{
+			Link:        fullLink,
+			ProductName: title,
+			Site:        site.Name,
+			Country:     country,
+			Condition:   conditionLabel(e, site),
+			FetchedAt:   time.Now(),
+		}
+		if setPrice(&product, priceText, listText, "") {
+			setShipping(&product, shippingLabel(e, site))
 			products = append(products, product)
 		}
 	})
@@ -630,6 +790,9 @@ func (s *Service) scrapeWebsite(ctx context.Context, site models.SiteConfig, que
 }
 
 func (s *Service) getSitesForCountry(country string) []models.SiteConfig {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
 	var relevantSites []models.SiteConfig
 	
 	for _, site := range s.sites {
@@ -645,6 +808,9 @@ func (s *Service) getSitesForCountry(country string) []models.SiteConfig {
 }
 
 func (s *Service) GetSupportedSites() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
 	var siteNames []string
 	for _, site := range s.sites {
 		siteNames = append(siteNames, site.Name)
@@ -652,49 +818,112 @@ func (s *Service) GetSupportedSites() []string {
 	return siteNames
 }
 
-func extractCurrency(priceText, country string) string {
-	currencyMap := map[string]string{
-		"US": "USD",
-		"IN": "INR",
-		"UK": "GBP",
-		"CA": "CAD",
-		"AU": "AUD",
+// Brands lists the brand knowledge base, optionally only brands with the given category hint
+func (s *Service) Brands(category string) []brands.Brand {
+	return s.matcher.Brands().List(category)
+}
+
+// Brand looks up a brand by its canonical name or an alias
+func (s *Service) Brand(name string) (brands.Brand, bool) {
+	return s.matcher.Brands().Get(name)
+}
+
+// AddBrand extends the brand knowledge base used for matching
+func (s *Service) AddBrand(brand brands.Brand) (brands.Brand, error) {
+	return s.matcher.AddBrand(brand)
+}
+
+// LLMStats reports the load on the shared LLM scheduler
+func (s *Service) LLMStats() llm.Stats {
+	return s.matcher.Scheduler().Stats()
+}
+
+// convertPrices adds each result's price in the display currency; "" converts nothing, and results in a
+// currency without an exchange rate are left unconverted
+func (s *Service) convertPrices(ctx context.Context, currency string, products []models.ProductResult) {
+	if currency == "" {
+		return
 	}
-	
-	if currency, exists := currencyMap[country]; exists {
-		return currency
+	table := s.rates.Table(ctx)
+	for i := range products {
+		amount, err := products[i].Amount()
+		if err != nil {
+			continue
+		}
+		converted, err := convertAmount(table, amount, currency)
+		if err != nil {
+			log.Printf("Cannot convert %s price of %s: %v", products[i].Site, products[i].ProductName, err)
+			continue
+		}
+		products[i].Converted = converted
 	}
-	
-	if strings.Contains(priceText, "$") {
-		return "USD"
-	} else if strings.Contains(priceText, "₹") {
-		return "INR"
-	} else if strings.Contains(priceText, "£") {
-		return "GBP"
+}
+
+// convertAmount converts amount into currency with the rates in table
+func convertAmount(table rates.Table, amount money.Money, currency string) (*models.ConvertedPrice, error) {
+	converted, rate, err := table.Convert(amount, currency)
+	if err != nil {
+		return nil, err
 	}
-	
-	return "USD"
+	return &models.ConvertedPrice{
+		Price:       converted.Decimal(),
+		Value:       converted,
+		Rate:        rates.Round(rate),
+		RatesDate:   table.Date,
+		RatesSource: table.Source,
+	}, nil
 }
 
-func cleanPriceText(priceText string) string {
-	re := regexp.MustCompile(`[\d,]+\.?\d*`)
-	matches := re.FindAllString(priceText, -1)
-	
-	if len(matches) > 0 {
-		return strings.ReplaceAll(matches[0], ",", "")
+// ExchangeRates returns the exchange rates conversions currently use
+func (s *Service) ExchangeRates(ctx context.Context) rates.Table {
+	return s.rates.Table(ctx)
+}
+
+// CascadeStats reports how far results have travelled through the cascade strategy since startup
+func (s *Service) CascadeStats() []models.StageStats {
+	return s.matcher.CascadeStats()
+}
+
+// setPrice reads a displayed price by the conventions of the result's country, keeping the display text. A range
+// sets its lowest amount as the price. A list price is kept, with the discount, only when it is above the price.
+// currency overrides the one read from the text; it returns false when the text holds no amount.
+func setPrice(product *models.ProductResult, priceText, listText, currency string) bool {
+	low, high, err := money.ParseRange(priceText, product.Country, currency)
+	if err != nil {
+		return false
 	}
-	
-	return ""
+	product.PriceText = strings.TrimSpace(priceText)
+	product.Price = low.Decimal()
+	product.Currency = low.Currency
+	product.PriceValue = &low
+	product.PriceRange = nil
+	if high.Minor > low.Minor {
+		product.PriceRange = &models.PriceRange{Min: low, Max: high}
+	}
+	setTaxLabel(product, priceText)
+
+	product.ListPrice, product.DiscountPercent = nil, 0
+	if strings.TrimSpace(listText) == "" {
+		return true
+	}
+	if list, err := money.ParseIn(listText, product.Country, low.Currency); err == nil && list.Minor > low.Minor {
+		product.ListPrice = &list
+		product.DiscountPercent = money.DiscountPercent(list, low)
+	}
+	return true
 }
 
-// scrapeWebsiteParallel uses LLM-first approach for intelligent content extraction
-func (s *Service) scrapeWebsiteParallel(ctx context.Context, site models.SiteConfig, query, country string) models.ScrapingResult {
+// scrapeWebsiteParallel uses LLM-first approach for intelligent content extraction, keeping extracted listings
+// whose calibrated confidence reaches minConfidence
+func (s *Service) scrapeWebsiteParallel(ctx context.Context, site models.SiteConfig, query, country string, minConfidence float64) models.ScrapingResult {
 	encodedQuery := url.QueryEscape(query)
 	searchURL := site.BaseURL + site.SearchPath + encodedQuery
 	
 	var products []models.ProductResult
 	var scrapeError error
-	var pageContent string
+	var pageChunks []string
+	var truncation *models.Truncation
+	var pageBody []byte
 	
 	// Create a fresh collector for each request
 	collector := colly.NewCollector()
@@ -714,11 +943,14 @@ func (s *Service) scrapeWebsiteParallel(ctx context.Context, site models.SiteCon
 		})
 	}
 	
+	collector.OnResponse(func(r *colly.Response) {
+		pageBody = r.Body
+	})
+	
 	// Extract the full page content instead of using CSS selectors
 	collector.OnHTML("body", func(e *colly.HTMLElement) {
-		// Get the main content area, skip navigation and footer
-		mainContent := s.extractMainContent(e)
-		pageContent = mainContent
+		// Split the page into product-card-aligned chunks that fit the LLM window
+		pageChunks, truncation = limitChunks(s.extractContentChunks(e, site), s.config.MaxChunksPerPage, site.Name)
 	})
 	
 	collector.OnError(func(r *colly.Response, err error) {
@@ -738,22 +970,24 @@ func (s *Service) scrapeWebsiteParallel(ctx context.Context, site models.SiteCon
 	}
 	
 	// Use LLM to intelligently extract products from page content
-	if pageContent != "" {
-		extractedProducts, err := s.extractProductsWithLLM(ctx, pageContent, query, country, site.Name, site.BaseURL)
+	if len(pageChunks) > 0 {
+		extractedProducts, err := s.extractProductsChunked(ctx, pageChunks, query, country, site, minConfidence)
 		if err != nil {
 			log.Printf("LLM extraction failed for %s: %v", site.Name, err)
 			// Fallback to CSS selector approach if LLM fails
 			return s.fallbackCSSExtraction(ctx, site, query, country, searchURL)
 		}
 		products = extractedProducts
+		attachStructuredData(products, pageBody)
 	}
 	
 	log.Printf("Site %s returned %d products via LLM extraction", site.Name, len(products))
 	
 	return models.ScrapingResult{
-		Products: products,
-		Site:     site.Name,
-		Error:    scrapeError,
+		Products:  products,
+		Site:      site.Name,
+		Error:     scrapeError,
+		Truncated: truncation,
 	}
 }
 
@@ -772,26 +1006,31 @@ func (s *Service) isGenericResult(title string) bool {
 		"search results",
 	}
 	
-	titleLower := strings.ToLower(title)
+	genericTerms = append(genericTerms, lang.GenericPhrases()...)
+	
+	titleLower := lang.Fold(title)
 	for _, term := range genericTerms {
 		if strings.Contains(titleLower, term) || titleLower == term {
 			return true
 		}
 	}
 	
-	// Filter out titles that are too short or generic
-	if len(strings.TrimSpace(title)) < 10 {
+	// Filter out titles that are too short or generic; CJK characters count double, so "中古スマホ" is kept
+	if lang.Width(title) < 10 {
 		return true
 	}
 	
 	return false
 }
 
-// processResultsParallel handles LLM processing with worker pool pattern
-func (s *Service) processResultsParallel(ctx context.Context, query string, allResults []models.ProductResult) ([]models.ProductResult, error) {
+// processResultsParallel scores results with the request's strategy using a worker pool, returning the kept
+// results and, when the strategy reports them, its stage counts for this search
+func (s *Service) processResultsParallel(ctx context.Context, req models.PriceRequest, intent models.QueryIntent, allResults []models.ProductResult) ([]models.ProductResult, []models.StageStats, error) {
 	if len(allResults) == 0 {
-		return allResults, nil
+		return allResults, nil, nil
 	}
+	// Strategies weighing titles against each other, such as BM25, see every result of the search
+	strategy := matcher.ForResults(s.matcher.Strategy(req.Strategy), allResults)
 	
 	// Create worker pool for parallel LLM processing
 	numWorkers := 5 // Concurrent LLM evaluations
@@ -805,14 +1044,14 @@ func (s *Service) processResultsParallel(ctx context.Context, query string, allR
 		go func() {
 			defer wg.Done()
 			for product := range jobs {
-				// Try LLM scoring first
-				score, err := s.matcher.FilterAndScoreProducts(ctx, query, []models.ProductResult{product})
-				if err != nil || len(score) == 0 {
-					// Fallback to fuzzy matching
-					product.Confidence = s.matcher.FuzzyProductMatch(query, product.ProductName)
-				} else {
-					product.Confidence = score[0].Confidence
+				// An offer carrying the requested barcode is the product; no scoring needed
+				if matchRequestedIdentifier(req, &product) {
+					results <- product
+					continue
 				}
+
+				s.scoreProduct(ctx, req, strategy, intent, &product)
+				penaliseIdentifierConflict(req, &product)
 				results <- product
 			}
 		}()
@@ -835,17 +1074,168 @@ func (s *Service) processResultsParallel(ctx context.Context, query string, allR
 	// Collect results
 	var processedResults []models.ProductResult
 	for result := range results {
-		// Apply confidence threshold - only include relevant results
-		if result.Confidence >= 0.3 {
-			processedResults = append(processedResults, result)
-		}
+		processedResults = append(processedResults, result)
 	}
 	
-	log.Printf("Filtered %d results from %d total (%.1f%% relevant)", 
-		len(processedResults), len(allResults), 
+	// Apply confidence threshold - only include relevant results
+	processedResults = s.filterByConfidence(req, intent, processedResults)
+	log.Printf("Filtered %d results from %d total with %s scoring (%.1f%% relevant)", 
+		len(processedResults), len(allResults), strategy.Name(),
 		float64(len(processedResults))/float64(len(allResults))*100)
+	var stages []models.StageStats
+	if reporter, ok := strategy.(matcher.StageReporter); ok {
+		stages = reporter.StageStats()
+		for _, stage := range stages {
+			log.Printf("Cascade stage %s: %d reached, %d resolved, %d escalated, %d failed, %.0fms avg",
+				stage.Stage, stage.Reached, stage.Resolved, stage.Escalated, stage.Failed, stage.AvgLatencyMs)
+		}
+	}
 	
-	return processedResults, nil
+	return processedResults, stages, nil
+}
+
+// scoreProduct scores a result with strategy, falling back to fuzzy matching when it fails, and records the
+// strategy that produced the score, or for the cascade the stage that did
+func (s *Service) scoreProduct(ctx context.Context, req models.PriceRequest, strategy matcher.Matcher, intent models.QueryIntent, product *models.ProductResult) {
+	explanation, used := s.matcher.Score(ctx, strategy, intent, *product)
+	if explanation.PromptVersion != "" {
+		product.SetPromptVersion(prompts.Scoring, explanation.PromptVersion)
+	}
+	setScore(req, product, explanation)
+	product.Strategy = matcher.CalibrationSource(used, explanation)
+	s.calibrate(product)
+}
+
+// calibrate maps a result's confidence from the scale of its source to the probability of relevance fitted by
+// cmd/calibrate, so one threshold means the same for every strategy, extraction and the CSS fallback. The
+// source's own value is kept as RawConfidence.
+func (s *Service) calibrate(product *models.ProductResult) {
+	product.RawConfidence = product.Confidence
+	product.Confidence, product.Calibration = s.matcher.Calibrate(product.Strategy, product.RawConfidence)
+	if product.Explanation != nil && product.Calibration != "" {
+		product.Explanation.Rules = append(product.Explanation.Rules, fmt.Sprintf("%s calibration: %.3f -> %.3f",
+			product.Calibration, product.RawConfidence, product.Confidence))
+	}
+}
+
+// minConfidence is the request's confidence threshold, or the configured one when it sets none
+func (s *Service) minConfidence(req models.PriceRequest) float64 {
+	if req.MinConfidence != nil {
+		return *req.MinConfidence
+	}
+	return s.config.MinConfidence
+}
+
+// setScore records a result's confidence and, when the request asks for it, the explanation behind it
+func setScore(req models.PriceRequest, product *models.ProductResult, explanation models.ScoreExplanation) {
+	product.Confidence = explanation.Score
+	product.Explanation = nil
+	if req.Explain {
+		product.Explanation = &explanation
+	}
+}
+
+// classifyProducts records the detected category, accessory flag, condition and any variant mismatch on each result
+func (s *Service) classifyProducts(intent models.QueryIntent, products []models.ProductResult) {
+	for i := range products {
+		classification := s.matcher.ClassifyProduct(intent, products[i].ProductName)
+		if classification.Category != "" {
+			products[i].Category = classification.Category
+		}
+		products[i].Kind = classification.Kind
+		products[i].Accessory = classification.IsAddOn()
+		products[i].Condition = matcher.ProductCondition(products[i])
+
+		products[i].VariantMismatch = nil
+		for _, conflict := range s.matcher.VariantConflicts(intent, products[i].ProductName) {
+			products[i].VariantMismatch = append(products[i].VariantMismatch, conflict.String())
+		}
+	}
+}
+
+// filterByCondition keeps the results in the wanted condition; "" keeps every result
+func filterByCondition(products []models.ProductResult, condition string) []models.ProductResult {
+	if condition == "" {
+		return products
+	}
+	var kept []models.ProductResult
+	for _, product := range products {
+		if product.Condition == condition {
+			kept = append(kept, product)
+		}
+	}
+	return kept
+}
+
+// filterByConfidence keeps the results scoring at least the request's minimum confidence. Variant conflicts are
+// penalised below that threshold, so when the request groups variants a result that would have passed without its
+// variant penalties is kept too, for OtherVariants; accessories for another variant are still dropped.
+func (s *Service) filterByConfidence(req models.PriceRequest, intent models.QueryIntent, products []models.ProductResult) []models.ProductResult {
+	minConfidence := s.minConfidence(req)
+	var kept []models.ProductResult
+	for _, product := range products {
+		confidence := product.Confidence
+		if req.GroupVariants && !product.Accessory && len(product.VariantMismatch) > 0 {
+			for _, conflict := range s.matcher.VariantConflicts(intent, product.ProductName) {
+				confidence += conflict.Penalty
+			}
+		}
+		if confidence >= minConfidence {
+			kept = append(kept, product)
+		}
+	}
+	return kept
+}
+
+// conditionLabel reads a card's condition label with the site's condition selector, if it has one
+func conditionLabel(e *colly.HTMLElement, site models.SiteConfig) string {
+	if site.Selectors.Condition == "" {
+		return ""
+	}
+	return strings.TrimSpace(e.ChildText(site.Selectors.Condition))
+}
+
+// shippingLabel reads a card's delivery charge with the site's shipping selector, if it has one
+func shippingLabel(e *colly.HTMLElement, site models.SiteConfig) string {
+	if site.Selectors.Shipping == "" {
+		return ""
+	}
+	return strings.TrimSpace(e.DOM.Find(site.Selectors.Shipping).First().Text())
+}
+
+// priceTexts reads a card's selling price and, when the site has a list price selector, its struck-through list
+// price. Price elements that are or hold the list price are passed over, so "₹1,499 ₹999" gives ₹999 rather than
+// the MRP; when every price element holds it, the list price is cut out of the first.
+func priceTexts(e *colly.HTMLElement, site models.SiteConfig) (price, list string) {
+	prices := e.DOM.Find(site.Selectors.Price)
+	if site.Selectors.ListPrice == "" {
+		return strings.TrimSpace(prices.First().Text()), ""
+	}
+	listSelector := site.Selectors.ListPrice
+	list = strings.TrimSpace(e.DOM.Find(listSelector).First().Text())
+
+	selling := prices.FilterFunction(func(_ int, sel *goquery.Selection) bool {
+		return !sel.Is(listSelector) && sel.Closest(listSelector).Length() == 0 && sel.Find(listSelector).Length() == 0
+	})
+	if selling.Length() > 0 {
+		return strings.TrimSpace(selling.First().Text()), list
+	}
+	first := prices.First().Clone()
+	first.Find(listSelector).Remove()
+	return strings.TrimSpace(first.Text()), list
+}
+
+// searchByIdentifier searches the sites for the barcode itself when a request carries only a GTIN
+func searchByIdentifier(req models.PriceRequest) models.PriceRequest {
+	if strings.TrimSpace(req.Query) == "" {
+		req.Query = req.GTIN
+	}
+	return req
+}
+
+// ClusterProducts groups offers of the same product from different sites into canonical products
+func (s *Service) ClusterProducts(products []models.ProductResult) []models.ProductGroup {
+	return s.matcher.ClusterProducts(products)
 }
 
 // extractMainContent intelligently extracts the main product content area from a page
@@ -869,7 +1259,7 @@ func (s *Service) extractMainContent(e *colly.HTMLElement) string {
 	// Try to find main content area first
 	for _, selector := range mainSelectors {
 		mainArea := e.ChildText(selector)
-		if len(mainArea) > 1000 { // Has substantial content
+		if lang.Width(mainArea) > 1000 { // Has substantial content
 			return s.cleanContent(mainArea)
 		}
 	}
@@ -886,12 +1276,7 @@ func (s *Service) extractMainContent(e *colly.HTMLElement) string {
 		
 		// Add text content if it looks like product information
 		text := strings.TrimSpace(child.Text)
-		if len(text) > 20 && len(text) < 500 &&
-		   (strings.Contains(strings.ToLower(text), "price") ||
-		    strings.Contains(strings.ToLower(text), "$") ||
-		    strings.Contains(strings.ToLower(text), "₹") ||
-		    strings.Contains(strings.ToLower(text), "buy") ||
-		    strings.Contains(strings.ToLower(text), "add to cart")) {
+		if textWidth := lang.Width(text); textWidth > 20 && textWidth < 500 && hasPriceCue(text) {
 			content.WriteString(text + "\n")
 		}
 	})
@@ -899,57 +1284,42 @@ func (s *Service) extractMainContent(e *colly.HTMLElement) string {
 	return s.cleanContent(content.String())
 }
 
+// priceCues mark text as an offer; lang.PriceCues adds the words of the other site languages
+var priceCues = append([]string{"price", "$", "₹", "£", "buy", "add to cart"}, lang.PriceCues()...)
+
+// hasPriceCue reports whether text mentions a price or a way to buy
+func hasPriceCue(text string) bool {
+	folded := lang.Fold(text)
+	for _, cue := range priceCues {
+		if strings.Contains(folded, cue) {
+			return true
+		}
+	}
+	return false
+}
+
 // cleanContent removes excessive whitespace and irrelevant content
 func (s *Service) cleanContent(content string) string {
-	// Remove excessive newlines and spaces
+	// Remove excessive newlines and spaces; sizing for the LLM happens in extractContentChunks
 	re := regexp.MustCompile(`\s+`)
 	cleaned := re.ReplaceAllString(content, " ")
 	
-	// Limit content size for LLM processing (around 8000 characters)
-	if len(cleaned) > 8000 {
-		cleaned = cleaned[:8000] + "..."
-	}
-	
 	return strings.TrimSpace(cleaned)
 }
 
-// extractProductsWithLLM uses LLM to intelligently extract product information
-func (s *Service) extractProductsWithLLM(ctx context.Context, content, query, country, siteName, baseURL string) ([]models.ProductResult, error) {
-	prompt := fmt.Sprintf(`You are an expert e-commerce product extractor. Extract up to 25 relevant products from this webpage content that match the search query.
-
-Search Query: "%s"
-Country: %s
-Website: %s
-
-Webpage Content:
-%s
-
-Extract products in this exact JSON format:
-{
-  "products": [
-    {
-      "title": "Product name",
-      "price": "numeric price only (no currency symbols)",
-      "currency": "USD/INR/GBP/EUR/etc",
-      "link": "relative or absolute URL",
-      "confidence": 0.95
-    }
-  ]
-}
-
-Rules:
-1. Only include products that actually match the search query
-2. Extract exact product names from the content
-3. Clean price to numbers only (remove currency symbols, commas)
-4. Include relative URLs starting with / or absolute URLs
-5. Confidence 0.9-1.0 for exact matches, 0.7-0.8 for good matches, 0.5-0.6 for related
-6. Skip ads, navigation links, and irrelevant content
-7. Focus on actual product listings with prices
-8. Maximum 25 products
-
-Respond only with valid JSON, no explanation.`, query, country, siteName, content)
+// extractProductsWithLLM uses LLM to intelligently extract product information, dropping listings whose
+// calibrated confidence is below minConfidence
+func (s *Service) extractProductsWithLLM(ctx context.Context, content, query, country, siteName, baseURL, category string, minConfidence float64) ([]models.ProductResult, error) {
+	tmpl, err := s.matcher.Prompts().Get(prompts.Extraction, prompts.Scope{Site: siteName, Category: category})
+	if err != nil {
+		return nil, err
+	}
+	prompt, err := tmpl.Render(prompts.ExtractionData{Query: query, Country: country, Site: siteName, Content: content})
+	if err != nil {
+		return nil, err
+	}
 
-	response, err := s.matcher.CallOllama(ctx, prompt)
+	response, err := s.matcher.CallOllama(ctx, llm.PriorityInteractive, prompt)
 	if err != nil {
 		return nil, fmt.Errorf("LLM call failed: %v", err)
 	}
@@ -959,8 +1329,12 @@ Respond only with valid JSON, no explanation.`, query, country, siteName, conten
 		Products []struct {
 			Title      string  `json:"title"`
 			Price      string  `json:"price"`
+			ListPrice  string  `json:"listPrice"`
 			Currency   string  `json:"currency"`
+			Shipping   string  `json:"shipping"`
+			TaxNote    string  `json:"taxNote"`
 			Link       string  `json:"link"`
+			Condition  string  `json:"condition"`
 			Confidence float64 `json:"confidence"`
 		} `json:"products"`
 	}
@@ -985,25 +1359,31 @@ Respond only with valid JSON, no explanation.`, query, country, siteName, conten
 			fullLink = baseURL + "/" + strings.TrimPrefix(p.Link, "/")
 		}
 
-		// Set default currency if missing
-		currency := p.Currency
-		if currency == "" {
-			currency = extractCurrency(p.Price, country)
-		}
-
 		product := models.ProductResult{
 			Link:        fullLink,
-			Price:       cleanPriceText(p.Price),
-			Currency:    currency,
 			ProductName: strings.TrimSpace(p.Title),
 			Site:        siteName,
 			Country:     country,
+			Category:    category,
+			Condition:   p.Condition,
 			Confidence:  p.Confidence,
 			FetchedAt:   time.Now(),
 		}
+		product.SetPromptVersion(prompts.Extraction, tmpl.ID())
+		// A currency symbol in the displayed price outranks the currency the model named
+		currency := p.Currency
+		if _, shown := money.SymbolCurrency(p.Price); shown {
+			currency = ""
+		}
+		if !setPrice(&product, p.Price, p.ListPrice, currency) {
+			continue
+		}
+		setShipping(&product, p.Shipping)
+		setTaxLabel(&product, p.TaxNote)
 
-		// Apply basic validation
-		if !s.isGenericResult(product.ProductName) && product.Confidence >= 0.3 {
+		// Apply basic validation, judging the reported confidence as calibrated for extraction
+		calibrated, _ := s.matcher.Calibrate(prompts.Extraction, product.Confidence)
+		if !s.isGenericResult(product.ProductName) && calibrated >= minConfidence {
 			products = append(products, product)
 			log.Printf("LLM extracted from %s: %s - %s (confidence: %.2f)", 
 				siteName, product.ProductName, product.Price, product.Confidence)
@@ -1019,6 +1399,7 @@ func (s *Service) fallbackCSSExtraction(ctx context.Context, site models.SiteCon
 	
 	var products []models.ProductResult
 	var scrapeError error
+	var pageBody []byte
 	
 	collector := colly.NewCollector()
 	collector.SetRequestTimeout(10 * time.Second)
@@ -1029,13 +1410,17 @@ func (s *Service) fallbackCSSExtraction(ctx context.Context, site models.SiteCon
 		})
 	}
 	
+	collector.OnResponse(func(r *colly.Response) {
+		pageBody = r.Body
+	})
+	
 	collector.OnHTML(site.Selectors.Product, func(e *colly.HTMLElement) {
 		if len(products) >= 10 { // Reduced limit for fallback
 			return
 		}
 		
 		title := strings.TrimSpace(e.ChildText(site.Selectors.Title))
-		priceText := strings.TrimSpace(e.ChildText(site.Selectors.Price))
+		priceText, listText := priceTexts(e, site)
 		linkHref := e.ChildAttr(site.Selectors.Link, "href")
 		
 		if title != "" && priceText != "" && !s.isGenericResult(title) {
@@ -1046,20 +1431,30 @@ func (s *Service) fallbackCSSExtraction(ctx context.Context, site models.SiteCon
 			
 			product := models.ProductResult{
 				Link:        fullLink,
-				Price:       cleanPriceText(priceText),
-				Currency:    extractCurrency(priceText, country),
 				ProductName: title,
 				Site:        site.Name,
 				Country:     country,
+				Category:    site.Category,
+				Condition:   conditionLabel(e, site),
 				Confidence:  0.5, // Lower confidence for fallback
+				Strategy:    matcher.SourceCSSFallback,
 				FetchedAt:   time.Now(),
 			}
-			products = append(products, product)
+			if setPrice(&product, priceText, listText, "") {
+				setShipping(&product, shippingLabel(e, site))
+				products = append(products, product)
+			}
 		}
 	})
 	
 	collector.Visit(searchURL)
 	
+	// Selectors matched nothing; if the page still lists products they have likely gone stale
+	if len(products) == 0 {
+		s.maybeRepairSelectors(site, searchURL, pageBody)
+	}
+	attachStructuredData(products, pageBody)
+	
 	return models.ScrapingResult{
 		Products: products,
 		Site:     site.Name,
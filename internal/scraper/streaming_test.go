@@ -0,0 +1,104 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"price-comparison-tool/internal/config"
+	"price-comparison-tool/internal/models"
+)
+
+// shopPage lists the product asked for, another variant of it and an accessory, as a search results page
+const shopPage = `<html><body>
+<div class="item"><a href="/p/1"><h2>Apple iPhone 15 128GB Black</h2></a><span class="price">$799.00</span></div>
+<div class="item"><a href="/p/2"><h2>Apple iPhone 15 Pro Max 256GB</h2></a><span class="price">$1,199.00</span></div>
+<div class="item"><a href="/p/3"><h2>Silicone Case for iPhone 15</h2></a><span class="price">$19.00</span></div>
+</body></html>`
+
+func TestFetchPricesStreamingGroupsProcessedOffers(t *testing.T) {
+	shop := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, shopPage)
+	}))
+	defer shop.Close()
+	// Extraction fails, so every site falls back to its CSS selectors
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "model not loaded", http.StatusInternalServerError)
+	}))
+	defer ollama.Close()
+
+	s := NewService(&config.Config{OllamaHost: ollama.URL, LLMConcurrency: 2})
+	s.sites = nil
+	for _, name := range []string{"Shop A", "Shop B", "Shop C"} {
+		s.sites = append(s.sites, models.SiteConfig{
+			Name:       name,
+			BaseURL:    shop.URL,
+			SearchPath: "/search?q=",
+			Countries:  []string{"US"},
+			Selectors:  models.SiteSelectors{Product: "div.item", Title: "h2", Price: ".price", Link: "a"},
+		})
+	}
+
+	resultsChan := make(chan models.StreamingResult)
+	go func() {
+		defer close(resultsChan)
+		s.FetchPricesStreaming(context.Background(), models.PriceRequest{
+			Query:         "iPhone 15",
+			Country:       "US",
+			Strategy:      "fuzzy",
+			GroupVariants: true,
+			Grouped:       true,
+		}, resultsChan)
+	}()
+
+	reported := map[string]bool{}
+	var final *models.StreamingResult
+	lastProgress := 0
+	for result := range resultsChan {
+		if result.Error != "" {
+			t.Fatalf("site %s failed: %s", result.Site, result.Error)
+		}
+		if result.Status != "completed" {
+			continue
+		}
+		if result.Site == "" {
+			final = &result
+			continue
+		}
+		if result.Progress <= lastProgress {
+			t.Errorf("progress %d after %d, want it to grow with each completed site", result.Progress, lastProgress)
+		}
+		lastProgress = result.Progress
+		for _, product := range result.Products {
+			reported[product.Site+" "+product.ProductName] = true
+		}
+		for _, product := range result.OtherVariants {
+			if product.ProductName != "Apple iPhone 15 Pro Max 256GB" {
+				t.Errorf("%s reported %q as another variant", result.Site, product.ProductName)
+			}
+		}
+	}
+
+	if final == nil || final.Progress != 100 || lastProgress != 100 {
+		t.Fatalf("final event %+v after progress %d, want completion at 100", final, lastProgress)
+	}
+	for _, site := range []string{"Shop A", "Shop B", "Shop C"} {
+		if !reported[site+" Apple iPhone 15 128GB Black"] || reported[site+" Apple iPhone 15 Pro Max 256GB"] {
+			t.Fatalf("sites reported %v, want the iPhone 15 from %s with the Pro Max split off", reported, site)
+		}
+	}
+	offers := 0
+	for _, group := range final.Groups {
+		for _, offer := range group.Offers {
+			offers++
+			if !reported[offer.Site+" "+offer.ProductName] {
+				t.Errorf("group %q holds %s from %s, which no site reported", group.Title, offer.ProductName, offer.Site)
+			}
+		}
+	}
+	if offers != len(reported) {
+		t.Errorf("groups hold %d offers, want the %d the sites reported", offers, len(reported))
+	}
+}
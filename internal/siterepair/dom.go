@@ -0,0 +1,184 @@
+package siterepair
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+var pricePattern = regexp.MustCompile(`(?:[$£€¥₹]|Rs\.?|USD|INR|GBP|EUR|CAD|AUD|JPY)\s?\d[\d,.]*|\d[\d,.]*\s?(?:€|円|zł)`)
+
+const (
+	// minPriceMentions is how many prices a page must show before we assume it lists products
+	minPriceMentions = 5
+
+	// minRepeats is how often a tag/class signature must occur to be treated as a product card candidate
+	minRepeats = 3
+
+	maxOutlineLines = 120
+	maxOutlineDepth = 6
+	sampleTextRunes = 60
+
+	// MinValidationScore is the share of matched cards that must yield title, price and link
+	MinValidationScore = 0.6
+)
+
+// LooksLikeProductPage reports whether the page text mentions enough prices to be a results page
+func LooksLikeProductPage(doc *goquery.Document) bool {
+	return len(pricePattern.FindAllStringIndex(doc.Find("body").Text(), minPriceMentions)) >= minPriceMentions
+}
+
+// Outline summarises the repeated structures of a page that contain prices, for the LLM to pick selectors from.
+// Each line is an indented "tag.class[attr]" signature with its repeat count and a short text sample.
+func Outline(doc *goquery.Document) string {
+	counts := make(map[string]int)
+	examples := make(map[string]*goquery.Selection)
+	var order []string
+	doc.Find("body *").Each(func(_ int, sel *goquery.Selection) {
+		sig := signature(sel)
+		counts[sig]++
+		// Remember the first element per signature that looks like a card: it has structure and a price
+		if examples[sig] == nil && sel.Children().Length() >= 2 {
+			if text := sel.Text(); len(text) < 3000 && pricePattern.MatchString(text) {
+				examples[sig] = sel
+				order = append(order, sig)
+			}
+		}
+	})
+
+	var cards []string
+	for _, sig := range order {
+		if counts[sig] >= minRepeats {
+			cards = append(cards, sig)
+		}
+	}
+	sort.SliceStable(cards, func(i, j int) bool { return counts[cards[i]] > counts[cards[j]] })
+
+	var lines []string
+	for _, card := range cards {
+		if len(lines) >= maxOutlineLines {
+			break
+		}
+		lines = append(lines, fmt.Sprintf("%s (x%d)", card, counts[card]))
+		outlineChildren(examples[card], 1, &lines)
+		lines = append(lines, "")
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func outlineChildren(sel *goquery.Selection, depth int, lines *[]string) {
+	if depth > maxOutlineDepth {
+		return
+	}
+	sel.Children().Each(func(_ int, child *goquery.Selection) {
+		if len(*lines) >= maxOutlineLines || strings.TrimSpace(child.Text()) == "" {
+			return
+		}
+		line := strings.Repeat("  ", depth) + signature(child)
+		if href, exists := child.Attr("href"); exists && href != "" {
+			line += " href"
+		}
+		if child.Children().Length() == 0 {
+			line += fmt.Sprintf(" %q", truncateRunes(strings.Join(strings.Fields(child.Text()), " "), sampleTextRunes))
+		}
+		*lines = append(*lines, line)
+		outlineChildren(child, depth+1, lines)
+	})
+}
+
+// signature describes an element by tag, classes and the data-* attributes retailers key cards on
+func signature(sel *goquery.Selection) string {
+	node := sel.Get(0)
+	if node == nil || node.Type != html.ElementNode {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(node.Data)
+	if class, exists := sel.Attr("class"); exists {
+		for _, c := range strings.Fields(class) {
+			b.WriteString("." + c)
+		}
+	}
+	for _, attr := range node.Attr {
+		switch {
+		case !strings.HasPrefix(attr.Key, "data-"):
+		case strings.Contains(attr.Key, "component") || strings.Contains(attr.Key, "test") || strings.Contains(attr.Key, "automation"):
+			// Role markers share a value across cards, e.g. data-component-type='s-search-result'
+			b.WriteString(fmt.Sprintf("[%s='%s']", attr.Key, attr.Val))
+		case attr.Key == "data-asin" || attr.Key == "data-id" || attr.Key == "data-pid":
+			// Per-product IDs differ on every card, so only their presence is part of the signature
+			b.WriteString("[" + attr.Key + "]")
+		}
+	}
+	return b.String()
+}
+
+func truncateRunes(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit]) + "…"
+}
+
+// Candidate is a set of selectors proposed by the LLM
+type Candidate struct {
+	Product string `json:"product"`
+	Title   string `json:"title"`
+	Price   string `json:"price"`
+	Link    string `json:"link"`
+}
+
+// Validate applies candidate selectors to the page and measures how many cards yield a title, price and link
+func Validate(doc *goquery.Document, candidate Candidate, baseURL string) (validation Validation, err error) {
+	// goquery panics on selectors cascadia cannot compile, which LLM output can contain
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("invalid selector: %v", r)
+		}
+	}()
+
+	if candidate.Product == "" || candidate.Title == "" || candidate.Price == "" || candidate.Link == "" {
+		return validation, fmt.Errorf("candidate is missing selectors")
+	}
+
+	cards := doc.Find(candidate.Product)
+	validation.Cards = cards.Length()
+	if validation.Cards < minRepeats {
+		return validation, fmt.Errorf("product selector matched %d elements", validation.Cards)
+	}
+
+	cards.Each(func(_ int, card *goquery.Selection) {
+		title := strings.TrimSpace(card.Find(candidate.Title).First().Text())
+		price := strings.TrimSpace(card.Find(candidate.Price).First().Text())
+		link, _ := card.Find(candidate.Link).First().Attr("href")
+		if link == "" {
+			link, _ = card.Filter(candidate.Link).Attr("href")
+		}
+
+		if title == "" || !strings.ContainsAny(price, "0123456789") || link == "" {
+			return
+		}
+		validation.ValidCards++
+
+		if len(validation.Samples) < 3 {
+			if !strings.HasPrefix(link, "http") {
+				link = baseURL + link
+			}
+			validation.Samples = append(validation.Samples, Sample{
+				Title: truncateRunes(strings.Join(strings.Fields(title), " "), 120),
+				Price: strings.Join(strings.Fields(price), " "),
+				Link:  link,
+			})
+		}
+	})
+
+	validation.Score = float64(validation.ValidCards) / float64(validation.Cards)
+	return validation, nil
+}
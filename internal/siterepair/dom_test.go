@@ -0,0 +1,94 @@
+package siterepair
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// resultsPage renders a search results page with n product cards, the markup selector repair is induced from
+func resultsPage(n int) string {
+	var b strings.Builder
+	b.WriteString(`<html><body><header><a href="/">Home</a></header><div class="results">`)
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, `<div class="card" data-pid="p%d">
+  <a class="card-link" href="/p/%d"><h3 class="card-title">Product %c</h3></a>
+  <div class="card-price"><span class="amount">$%d.99</span></div>
+</div>`, i, i, 'A'+i-1, 100+i)
+	}
+	b.WriteString(`</div><footer>Prices include VAT</footer></body></html>`)
+	return b.String()
+}
+
+func parse(t *testing.T, page string) *goquery.Document {
+	t.Helper()
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestLooksLikeProductPage(t *testing.T) {
+	if !LooksLikeProductPage(parse(t, resultsPage(6))) {
+		t.Error("LooksLikeProductPage() = false for a page of six priced cards")
+	}
+	if LooksLikeProductPage(parse(t, `<html><body><p>Sorry, we could not find that page. Only $5 shipping!</p></body></html>`)) {
+		t.Error("LooksLikeProductPage() = true for an error page with one price")
+	}
+}
+
+func TestOutlineListsRepeatedPricedCards(t *testing.T) {
+	outline := Outline(parse(t, resultsPage(6)))
+	lines := strings.Split(outline, "\n")
+	if len(lines) == 0 || lines[0] != "div.card[data-pid] (x6)" {
+		t.Fatalf("Outline() starts %q, want the card signature with its count:\n%s", lines[0], outline)
+	}
+	for _, want := range []string{`  a.card-link href`, `    h3.card-title "Product A"`, `    span.amount "$101.99"`} {
+		if !strings.Contains(outline, want+"\n") {
+			t.Errorf("Outline() is missing %q:\n%s", want, outline)
+		}
+	}
+	if strings.Contains(outline, "footer") || strings.Contains(outline, "header") {
+		t.Errorf("Outline() lists unrepeated page chrome:\n%s", outline)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	doc := parse(t, resultsPage(5))
+	good := Candidate{Product: "div.card", Title: ".card-title", Price: ".amount", Link: "a.card-link"}
+
+	validation, err := Validate(doc, good, "https://shop.example")
+	if err != nil {
+		t.Fatalf("Validate(good) error: %v", err)
+	}
+	if validation.Cards != 5 || validation.ValidCards != 5 || validation.Score < MinValidationScore {
+		t.Errorf("Validate(good) = %+v, want 5 of 5 cards valid", validation)
+	}
+	if len(validation.Samples) != 3 || validation.Samples[0] != (Sample{Title: "Product A", Price: "$101.99", Link: "https://shop.example/p/1"}) {
+		t.Errorf("Validate(good) samples %+v, want the first three cards with absolute links", validation.Samples)
+	}
+
+	// Titles are found but no card has a price under the selector
+	unpriced := good
+	unpriced.Price = ".card-title"
+	if validation, err := Validate(doc, unpriced, ""); err != nil || validation.ValidCards != 0 || validation.Score >= MinValidationScore {
+		t.Errorf("Validate(unpriced) = %+v, %v; want no valid cards", validation, err)
+	}
+
+	rejected := []struct {
+		name      string
+		candidate Candidate
+	}{
+		{"too few cards", Candidate{Product: "div.results", Title: ".card-title", Price: ".amount", Link: "a"}},
+		{"missing selector", Candidate{Product: "div.card", Title: ".card-title", Price: ".amount"}},
+		{"invalid selector", Candidate{Product: "div.card[", Title: ".card-title", Price: ".amount", Link: "a"}},
+	}
+	for _, test := range rejected {
+		if _, err := Validate(doc, test.candidate, ""); err == nil {
+			t.Errorf("Validate(%s) accepted %+v", test.name, test.candidate)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package siterepair
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"price-comparison-tool/internal/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Revision statuses
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusRejected = "rejected"
+)
+
+// Sample is a product read from the page with proposed selectors, shown to the reviewer
+type Sample struct {
+	Title string `json:"title"`
+	Price string `json:"price"`
+	Link  string `json:"link"`
+}
+
+// Validation summarises how well proposed selectors worked on the page they were induced from
+type Validation struct {
+	Cards      int      `json:"cards"`
+	ValidCards int      `json:"validCards"`
+	Score      float64  `json:"score"`
+	Samples    []Sample `json:"samples,omitempty"`
+}
+
+// Revision is a proposed change to a site's selectors awaiting admin review
+type Revision struct {
+	ID         string               `json:"id"`
+	Site       string               `json:"site"`
+	Status     string               `json:"status"`
+	PageURL    string               `json:"pageUrl"`
+	Current    models.SiteSelectors `json:"current"`
+	Proposed   models.SiteSelectors `json:"proposed"`
+	Validation Validation           `json:"validation"`
+	CreatedAt  time.Time            `json:"createdAt"`
+	ReviewedAt *time.Time           `json:"reviewedAt,omitempty"`
+}
+
+// Store keeps selector revisions in memory and persists them to a JSON file
+type Store struct {
+	mutex     sync.RWMutex
+	path      string
+	revisions map[string]*Revision
+}
+
+// NewStore loads revisions from path; a missing file starts an empty store
+func NewStore(path string) (*Store, error) {
+	s := &Store{
+		path:      path,
+		revisions: make(map[string]*Revision),
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return s, err
+	}
+
+	var revisions []*Revision
+	if err := json.Unmarshal(data, &revisions); err != nil {
+		return s, fmt.Errorf("parse %s: %v", path, err)
+	}
+	for _, revision := range revisions {
+		s.revisions[revision.ID] = revision
+	}
+	return s, nil
+}
+
+// Add records a new pending revision unless the site already has a pending one with the same selectors
+func (s *Store) Add(revision Revision) (*Revision, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, existing := range s.revisions {
+		if existing.Site == revision.Site && existing.Status == StatusPending && existing.Proposed == revision.Proposed {
+			result := *existing
+			return &result, nil
+		}
+	}
+
+	revision.ID = newID()
+	revision.Status = StatusPending
+	revision.CreatedAt = time.Now()
+	stored := revision
+	s.revisions[revision.ID] = &stored
+
+	// A revision that was not persisted would be lost on restart, so it is not kept at all
+	if err := s.save(); err != nil {
+		delete(s.revisions, revision.ID)
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// List returns revisions newest first, optionally filtered by status
+func (s *Store) List(status string) []Revision {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var result []Revision
+	for _, revision := range s.revisions {
+		if status == "" || revision.Status == status {
+			result = append(result, *revision)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.After(result[j].CreatedAt) })
+	return result
+}
+
+// Approved returns the latest approved selectors for each site
+func (s *Store) Approved() map[string]models.SiteSelectors {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	latest := make(map[string]*Revision)
+	for _, revision := range s.revisions {
+		if revision.Status != StatusApproved || revision.ReviewedAt == nil {
+			continue
+		}
+		if current, exists := latest[revision.Site]; !exists || revision.ReviewedAt.After(*current.ReviewedAt) {
+			latest[revision.Site] = revision
+		}
+	}
+
+	result := make(map[string]models.SiteSelectors, len(latest))
+	for site, revision := range latest {
+		result[site] = revision.Proposed
+	}
+	return result
+}
+
+// Review moves a pending revision to approved or rejected. The revision stays pending when the store cannot be
+// saved, so the review can be retried.
+func (s *Store) Review(id, status string) (*Revision, error) {
+	if status != StatusApproved && status != StatusRejected {
+		return nil, fmt.Errorf("invalid review status %q", status)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	revision, exists := s.revisions[id]
+	if !exists {
+		return nil, fmt.Errorf("revision %s not found", id)
+	}
+	if revision.Status != StatusPending {
+		return nil, fmt.Errorf("revision %s is already %s", id, revision.Status)
+	}
+
+	now := time.Now()
+	revision.Status = status
+	revision.ReviewedAt = &now
+	if err := s.save(); err != nil {
+		revision.Status = StatusPending
+		revision.ReviewedAt = nil
+		return nil, err
+	}
+
+	result := *revision
+	return &result, nil
+}
+
+// save writes all revisions to disk. Must be called with the mutex held.
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	revisions := make([]*Revision, 0, len(s.revisions))
+	for _, revision := range s.revisions {
+		revisions = append(revisions, revision)
+	}
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i].CreatedAt.Before(revisions[j].CreatedAt) })
+
+	data, err := json.MarshalIndent(revisions, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
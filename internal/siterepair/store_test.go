@@ -0,0 +1,128 @@
+package siterepair
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"price-comparison-tool/internal/models"
+)
+
+func proposal(site, product string) Revision {
+	return Revision{
+		Site:     site,
+		Current:  models.SiteSelectors{Product: ".old-card", Title: "h2", Price: ".price", Link: "a"},
+		Proposed: models.SiteSelectors{Product: product, Title: "h3", Price: ".amount", Link: "a"},
+	}
+}
+
+func TestStoreAddDeduplicatesPendingProposals(t *testing.T) {
+	store, err := NewStore(filepath.Join(t.TempDir(), "revisions.json"))
+	if err != nil {
+		t.Fatalf("NewStore() error: %v", err)
+	}
+
+	first, err := store.Add(proposal("Shop", "div.card"))
+	if err != nil || first.Status != StatusPending || first.ID == "" {
+		t.Fatalf("Add() = %+v, %v; want a pending revision with an ID", first, err)
+	}
+	again, err := store.Add(proposal("Shop", "div.card"))
+	if err != nil || again.ID != first.ID {
+		t.Errorf("Add() of the same proposal = %+v, %v; want the pending revision %s", again, err, first.ID)
+	}
+	other, _ := store.Add(proposal("Shop", "li.card"))
+	elsewhere, _ := store.Add(proposal("Other Shop", "div.card"))
+	if other.ID == first.ID || elsewhere.ID == first.ID {
+		t.Error("Add() merged a different proposal or another site's proposal into the pending one")
+	}
+	if pending := store.List(StatusPending); len(pending) != 3 {
+		t.Errorf("List(pending) = %d revisions, want 3", len(pending))
+	}
+
+	// Once reviewed, the same proposal can be made again
+	if _, err := store.Review(first.ID, StatusRejected); err != nil {
+		t.Fatalf("Review() error: %v", err)
+	}
+	if proposed, _ := store.Add(proposal("Shop", "div.card")); proposed.ID == first.ID {
+		t.Error("Add() returned a reviewed revision for a new proposal")
+	}
+}
+
+func TestStoreReview(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "revisions.json")
+	store, _ := NewStore(path)
+	revision, _ := store.Add(proposal("Shop", "div.card"))
+
+	if _, err := store.Review(revision.ID, StatusPending); err == nil {
+		t.Error("Review() accepted pending as a review status")
+	}
+	if _, err := store.Review("missing", StatusApproved); err == nil {
+		t.Error("Review() accepted an unknown revision")
+	}
+
+	approved, err := store.Review(revision.ID, StatusApproved)
+	if err != nil || approved.Status != StatusApproved || approved.ReviewedAt == nil {
+		t.Fatalf("Review() = %+v, %v; want approved with a review time", approved, err)
+	}
+	if _, err := store.Review(revision.ID, StatusRejected); err == nil {
+		t.Error("Review() re-reviewed an approved revision")
+	}
+
+	reloaded, err := NewStore(path)
+	if err != nil {
+		t.Fatalf("NewStore() reload error: %v", err)
+	}
+	if approved := reloaded.List(StatusApproved); len(approved) != 1 || approved[0].ID != revision.ID {
+		t.Errorf("reloaded approved revisions %+v, want %s", approved, revision.ID)
+	}
+}
+
+func TestStoreReviewStaysPendingWhenSaveFails(t *testing.T) {
+	dir := t.TempDir()
+	store, _ := NewStore(filepath.Join(dir, "revisions.json"))
+	revision, _ := store.Add(proposal("Shop", "div.card"))
+
+	// A directory where the temporary file goes makes the save fail
+	if err := os.Mkdir(filepath.Join(dir, "revisions.json.tmp"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Review(revision.ID, StatusApproved); err == nil {
+		t.Fatal("Review() succeeded without saving")
+	}
+	if approved := store.Approved(); len(approved) != 0 {
+		t.Errorf("Approved() = %v after a failed save, want none", approved)
+	}
+	if _, err := store.Add(proposal("Shop", "li.card")); err == nil {
+		t.Error("Add() succeeded without saving")
+	}
+	if pending := store.List(StatusPending); len(pending) != 1 || pending[0].ID != revision.ID {
+		t.Errorf("List(pending) = %+v, want only %s with the unsaved proposal dropped", pending, revision.ID)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "revisions.json.tmp")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Review(revision.ID, StatusApproved); err != nil {
+		t.Errorf("Review() retry error: %v", err)
+	}
+}
+
+func TestStoreApprovedReturnsLatestPerSite(t *testing.T) {
+	store, _ := NewStore("")
+	for _, product := range []string{"div.card", "li.card"} {
+		revision, _ := store.Add(proposal("Shop", product))
+		if _, err := store.Review(revision.ID, StatusApproved); err != nil {
+			t.Fatalf("Review() error: %v", err)
+		}
+	}
+	rejected, _ := store.Add(proposal("Shop", "article.card"))
+	store.Review(rejected.ID, StatusRejected)
+	store.Add(proposal("Shop", "section.card"))
+	other, _ := store.Add(proposal("Other Shop", "div.tile"))
+	store.Review(other.ID, StatusApproved)
+
+	approved := store.Approved()
+	if len(approved) != 2 || approved["Shop"].Product != "li.card" || approved["Other Shop"].Product != "div.tile" {
+		t.Errorf("Approved() = %+v, want li.card for Shop and div.tile for Other Shop", approved)
+	}
+}
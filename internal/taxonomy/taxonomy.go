@@ -0,0 +1,316 @@
+// Package taxonomy classifies product titles and queries into a category and tells primary products apart
+// from accessories, consumables and parts. Each category lists its terms per kind; the embedded list can be
+// extended or overridden with a JSON file of the same shape.
+package taxonomy
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"price-comparison-tool/internal/lang"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+//go:embed taxonomy.json
+var embeddedTaxonomy []byte
+
+// Kinds of product a title can describe
+const (
+	KindPrimary    = "primary"
+	KindAccessory  = "accessory"
+	KindConsumable = "consumable"
+	KindPart       = "part"
+
+	// KindPackaging marks pack words such as "jar" or "pouch" that name a container, not the product kind
+	KindPackaging = "packaging"
+)
+
+// generalCategory holds add-on terms shared by every category; it is never reported as a category itself
+const generalCategory = "general"
+
+// compatibilityMarkers introduce the product an add-on is meant for, as in "Case for iPhone 16"
+var compatibilityMarkers = []string{" compatible with ", " designed for ", " made for ", " for ", " fits "}
+
+// bundleMarkers introduce items shipped alongside the product, as in "AirPods Pro with MagSafe Case"
+var bundleMarkers = []string{" with ", " includes ", " including ", " bundle "}
+
+// Category lists the terms that identify primary products and add-ons in one product category
+type Category struct {
+	Name       string   `json:"name"`
+	Primary    []string `json:"primary,omitempty"`
+	Accessory  []string `json:"accessory,omitempty"`
+	Consumable []string `json:"consumable,omitempty"`
+	Part       []string `json:"part,omitempty"`
+	Packaging  []string `json:"packaging,omitempty"`
+}
+
+// Classification is the category and kind detected for a piece of text
+type Classification struct {
+	Category string `json:"category,omitempty"`
+	Kind     string `json:"kind,omitempty"` // "" when no term was recognised
+	Term     string `json:"term,omitempty"` // the term that decided the kind
+}
+
+// IsAddOn reports whether the text describes an accessory, consumable or part rather than a product itself
+func (c Classification) IsAddOn() bool {
+	return c.Kind == KindAccessory || c.Kind == KindConsumable || c.Kind == KindPart
+}
+
+// sense is one meaning of a term: the same word can be an accessory in one category and primary in another
+type sense struct {
+	category string
+	kind     string
+	order    int // category position in the file, for deterministic tie-breaking
+}
+
+// Taxonomy is an immutable term index built from the category lists
+type Taxonomy struct {
+	categories []Category
+	senses     map[string][]sense
+	terms      []string // longest first so "stand mixer" is matched before "stand"
+}
+
+// Load builds the taxonomy from the embedded categories, then applies the categories in path (if set),
+// which replace embedded categories of the same name or add new ones
+func Load(path string) (*Taxonomy, error) {
+	var categories []Category
+	if err := json.Unmarshal(embeddedTaxonomy, &categories); err != nil {
+		return nil, fmt.Errorf("embedded taxonomy: %v", err)
+	}
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return New(categories), err
+		}
+		var overrides []Category
+		if err := json.Unmarshal(data, &overrides); err != nil {
+			return New(categories), fmt.Errorf("parse %s: %v", path, err)
+		}
+		for _, override := range overrides {
+			replaced := false
+			for i := range categories {
+				if categories[i].Name == override.Name {
+					categories[i] = override
+					replaced = true
+					break
+				}
+			}
+			if !replaced {
+				categories = append(categories, override)
+			}
+		}
+	}
+
+	return New(categories), nil
+}
+
+// New indexes the given categories
+func New(categories []Category) *Taxonomy {
+	t := &Taxonomy{
+		categories: categories,
+		senses:     make(map[string][]sense),
+	}
+
+	for order, category := range categories {
+		lists := []struct {
+			kind  string
+			terms []string
+		}{
+			{KindPrimary, category.Primary},
+			{KindAccessory, category.Accessory},
+			{KindConsumable, category.Consumable},
+			{KindPart, category.Part},
+			{KindPackaging, category.Packaging},
+		}
+		for _, list := range lists {
+			for _, term := range list.terms {
+				if term = normalize(term); term != "" {
+					t.senses[term] = append(t.senses[term], sense{category: category.Name, kind: list.kind, order: order})
+				}
+			}
+		}
+	}
+
+	for term := range t.senses {
+		t.terms = append(t.terms, term)
+	}
+	sort.Slice(t.terms, func(i, j int) bool {
+		if len(t.terms[i]) != len(t.terms[j]) {
+			return len(t.terms[i]) > len(t.terms[j])
+		}
+		return t.terms[i] < t.terms[j]
+	})
+
+	return t
+}
+
+// Categories returns the category definitions in file order
+func (t *Taxonomy) Categories() []Category {
+	return t.categories
+}
+
+// Classify detects the category and kind of a title or query. hint is the category expected from context,
+// such as the query's category when classifying a result; it breaks ties between categories sharing a term.
+//
+// The kind comes from the last recognised term of the title's subject: the text before any "for ..." or
+// "compatible with ..." clause and before any "with ..." bundle clause. "Laptop Sleeve for MacBook Air" is an
+// accessory, "Interchangeable Lens Camera Body" a primary product, "AirPods Pro with Charging Case" too.
+// A subject without known terms that is "for" a primary product is an accessory, e.g. "Spigen Ultra Hybrid for iPhone 16".
+func (t *Taxonomy) Classify(text, hint string) Classification {
+	hint = normalize(hint)
+	lower := strings.ReplaceAll(" "+strings.ToLower(text)+" ", " w/ ", " with ")
+	padded := " " + normalize(lower) + " "
+
+	subject, target := padded, ""
+	if i, marker := firstMarker(padded, compatibilityMarkers); i >= 0 {
+		subject, target = padded[:i+1], padded[i+len(marker)-1:]
+	}
+	if i, _ := firstMarker(subject, bundleMarkers); i >= 0 {
+		subject = subject[:i+1]
+	}
+
+	primaryCategory := t.primaryCategory(padded, hint)
+	context := orHint(hint, primaryCategory)
+
+	// Walk the subject's terms from the end, skipping words that only describe packaging in this context
+	terms := t.findTerms(subject)
+	for i := len(terms) - 1; i >= 0; i-- {
+		chosen := t.choose(terms[i], context, "")
+		if chosen.kind == KindPackaging {
+			continue
+		}
+
+		result := Classification{Kind: chosen.kind, Term: terms[i]}
+		switch {
+		case chosen.kind == KindPrimary:
+			result.Category = primaryCategory
+		case primaryCategory != "":
+			result.Category = primaryCategory
+		case chosen.category != generalCategory:
+			result.Category = chosen.category
+		default:
+			result.Category = hint
+		}
+		return result
+	}
+
+	for _, term := range t.findTerms(target) {
+		if t.choose(term, context, KindPrimary).kind == KindPrimary {
+			return Classification{Category: primaryCategory, Kind: KindAccessory}
+		}
+	}
+	return Classification{Category: orHint(primaryCategory, hint)}
+}
+
+// findTerms returns the known terms in segment in the order they appear. Longer terms claim their span
+// first, so "stand" inside "stand mixer" is not reported separately.
+func (t *Taxonomy) findTerms(segment string) []string {
+	if strings.TrimSpace(segment) == "" {
+		return nil
+	}
+
+	type occurrence struct {
+		term  string
+		start int
+	}
+	var found []occurrence
+	masked := []byte(segment)
+	for _, term := range t.terms {
+		needle := " " + term + " "
+		for offset := 0; ; {
+			i := strings.Index(string(masked[offset:]), needle)
+			if i < 0 {
+				break
+			}
+			start := offset + i
+			found = append(found, occurrence{term: term, start: start})
+			for j := start + 1; j < start+len(needle)-1; j++ {
+				masked[j] = 0
+			}
+			offset = start + len(needle) - 1
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool { return found[i].start < found[j].start })
+	terms := make([]string, len(found))
+	for i, o := range found {
+		terms[i] = o.term
+	}
+	return terms
+}
+
+// primaryCategory returns the category of a primary-product term anywhere in padded text, preferring hint
+func (t *Taxonomy) primaryCategory(padded, hint string) string {
+	found := ""
+	for _, term := range t.terms {
+		if !strings.Contains(padded, " "+term+" ") {
+			continue
+		}
+		for _, s := range t.senses[term] {
+			if s.kind != KindPrimary {
+				continue
+			}
+			if s.category == hint {
+				return hint
+			}
+			if found == "" {
+				found = s.category
+			}
+		}
+	}
+	return found
+}
+
+// choose picks the sense of term to use: the hinted category first, then the preferred kind, then file order
+func (t *Taxonomy) choose(term, hint, preferKind string) sense {
+	senses := t.senses[term]
+	best := senses[0]
+	score := func(s sense) int {
+		value := 0
+		if hint != "" && s.category == hint {
+			value += 4
+		}
+		if preferKind != "" && s.kind == preferKind {
+			value += 2
+		}
+		if s.category != generalCategory {
+			value++
+		}
+		return value
+	}
+	for _, s := range senses[1:] {
+		if score(s) > score(best) || (score(s) == score(best) && s.order < best.order) {
+			best = s
+		}
+	}
+	return best
+}
+
+func firstMarker(text string, markers []string) (int, string) {
+	index, found := -1, ""
+	for _, marker := range markers {
+		if i := strings.Index(text, marker); i >= 0 && (index < 0 || i < index) {
+			index, found = i, marker
+		}
+	}
+	return index, found
+}
+
+func orHint(category, hint string) string {
+	if category != "" {
+		return category
+	}
+	return hint
+}
+
+// normalize lowercases text and reduces punctuation, including hyphens, to single spaces
+func normalize(text string) string {
+	text = strings.NewReplacer("'", "").Replace(lang.Canonical(text))
+	return strings.Join(strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && !unicode.IsMark(r)
+	}), " ")
+}